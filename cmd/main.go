@@ -2,49 +2,123 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"robohub-inventory/internal/buildinfo"
 	"robohub-inventory/internal/config"
 	"robohub-inventory/internal/database"
+	"robohub-inventory/internal/fixtures"
+	"robohub-inventory/internal/health"
 	"robohub-inventory/internal/http"
+	"robohub-inventory/internal/jwtauth"
 	"robohub-inventory/internal/logger"
 	"robohub-inventory/internal/metrics"
+	"robohub-inventory/internal/tracing"
+	"robohub-inventory/internal/trash"
+	"robohub-inventory/pkg/apikey"
+	"robohub-inventory/pkg/bulk"
+	"robohub-inventory/pkg/counter"
 	"robohub-inventory/pkg/dataset"
+	"robohub-inventory/pkg/event"
+	"robohub-inventory/pkg/identity"
 	pkg "robohub-inventory/pkg/package"
 	"robohub-inventory/pkg/repository"
 	"robohub-inventory/pkg/scenario"
+	"robohub-inventory/pkg/search"
 	"robohub-inventory/pkg/simulator"
+	"robohub-inventory/pkg/store"
+	"robohub-inventory/pkg/webhook"
 )
 
 func main() {
-	// Initialize logger
-	log := logger.New()
-	log.Info("Starting RoboHub Inventory Service...")
+	// Load configuration
+	cfg, args, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		logger.Fatal("Failed to load configuration", "error", err)
+	}
 
-	// Initialize metrics
-	metrics.Init()
+	// Initialize logger
+	log, err := newLogger(cfg.Log)
+	if err != nil {
+		logger.Fatal("Failed to initialize logger", "error", err)
+	}
+	slog.SetDefault(log)
 
-	// Load configuration
-	cfg, err := config.Load()
+	// Initialize tracing; spans still buffered are flushed on exit
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
 	if err != nil {
-		log.Fatal("Failed to load configuration: %v", err)
+		logger.Fatal("Failed to initialize tracing", "error", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			log.Warn("Failed to flush traces", "error", err)
+		}
+	}()
+
+	if len(args) > 0 {
+		switch args[0] {
+		case "migrate":
+			runMigrate(cfg, args[1:])
+			return
+		case "seed":
+			runSeed(cfg, args[1:])
+			return
+		case "config":
+			if err := cfg.Print(os.Stdout); err != nil {
+				logger.Fatal("Failed to print configuration", "error", err)
+			}
+			return
+		default:
+			logger.Fatal("Unknown command", "command", args[0])
+		}
 	}
+	build := buildinfo.Get()
+	log.Info("Starting RoboHub Inventory Service", "version", build.Version, "commit", build.Commit)
 
 	// Connect to database
 	db, err := database.Connect(&cfg.Database)
 	if err != nil {
-		log.Fatal("Failed to connect to database: %v", err)
+		logger.Fatal("Failed to connect to database", "error", err)
 	}
 	defer database.Close()
 
-	// Run migrations
-	if err := database.Migrate(db); err != nil {
-		log.Fatal("Failed to run migrations: %v", err)
+	// Instrument queries and export pool statistics and catalog gauges
+	if err := metrics.Instrument(db); err != nil {
+		logger.Fatal("Failed to initialize metrics", "error", err)
+	}
+
+	// Apply pending migrations; replicas starting together wait on the migration lock
+	if cfg.Database.AutoMigrate {
+		migrator, err := database.NewMigrator(db)
+		if err != nil {
+			logger.Fatal("Failed to load migrations", "error", err)
+		}
+		applied, err := migrator.Up(context.Background())
+		if err != nil {
+			logger.Fatal("Failed to run migrations", "error", err)
+		}
+		log.Info("Database migrations completed", "applied", len(applied))
+	}
+
+	// Load fixtures when asked to; entities that already exist are kept
+	if cfg.Seed.Load {
+		result, err := fixtures.Load(context.Background(), db, cfg.Seed.Dir)
+		if err != nil {
+			logger.Fatal("Failed to load fixtures", "dir", cfg.Seed.Dir, "error", err)
+		}
+		log.Info("Fixtures loaded", "dir", cfg.Seed.Dir, "result", result.String())
 	}
-	log.Info("Database migrations completed")
 
 	// Initialize repositories
 	pkgRepo := pkg.NewRepository(db)
@@ -52,13 +126,71 @@ func main() {
 	scenarioRepo := scenario.NewRepository(db)
 	datasetRepo := dataset.NewRepository(db)
 	simulatorRepo := simulator.NewRepository(db)
+	searchRepo := search.NewRepository(db)
+	apiKeyRepo := apikey.NewRepository(db)
+	identityRepo := identity.NewRepository(db)
+	webhookRepo := webhook.NewRepository(db)
+	eventRepo := event.NewRepository(db)
 
 	// Initialize services
-	pkgService := pkg.NewService(pkgRepo)
-	repoService := repository.NewService(repoRepo)
-	scenarioService := scenario.NewService(scenarioRepo)
-	datasetService := dataset.NewService(datasetRepo)
-	simulatorService := simulator.NewService(simulatorRepo)
+	transactor := store.NewTransactor(db)
+	bus := event.NewBus(eventRepo, transactor, event.Options{
+		PollInterval: cfg.Events.PollInterval,
+		BatchSize:    cfg.Events.BatchSize,
+		Retention:    cfg.Events.Retention,
+	}, log)
+	identityService := identity.NewService(identityRepo, cfg.Auth.AdminSubjects)
+	webhookService := webhook.NewService(webhookRepo, webhook.Options{
+		MaxRetries:   cfg.Webhook.MaxRetries,
+		BackoffBase:  cfg.Webhook.BackoffBase,
+		BackoffMax:   cfg.Webhook.BackoffMax,
+		Timeout:      cfg.Webhook.Timeout,
+		DisableAfter: cfg.Webhook.DisableAfter,
+	})
+	pkgService := pkg.NewService(pkgRepo, identityService, transactor, bus)
+	repoService := repository.NewService(repoRepo, identityService, pkgService, transactor, bus)
+	scenarioService := scenario.NewService(scenarioRepo, identityService, transactor, bus)
+	datasetService := dataset.NewService(datasetRepo, identityService, transactor, bus)
+	simulatorService := simulator.NewService(simulatorRepo, transactor, bus)
+	searchService := search.NewService(searchRepo, identityService)
+	apiKeyService := apikey.NewService(apiKeyRepo)
+	counterService := counter.NewService(db)
+	bulkService := bulk.NewService(transactor, pkgService, repoService, scenarioService, datasetService, simulatorService)
+
+	feed := event.NewFeed(eventRepo, cfg.Events.PollInterval, log)
+
+	// Resolve client addresses, trusting the forwarding headers of the configured proxies only
+	clientIP, err := http.NewClientIP(cfg.Server.TrustedProxies)
+	if err != nil {
+		logger.Fatal("Failed to initialize client address resolution", "error", err)
+	}
+
+	// Initialize rate limiting
+	var rateLimiter *http.RateLimiter
+	if cfg.RateLimit.Enabled {
+		var store http.RateLimitStore = http.NewMemoryRateLimitStore()
+		if cfg.RateLimit.Store == "postgres" {
+			store = http.NewPostgresRateLimitStore(db)
+		}
+		rateLimiter = http.NewRateLimiter(store, http.RateLimits{
+			Public:        cfg.RateLimit.Public,
+			Authenticated: cfg.RateLimit.Authenticated,
+			Webhook:       cfg.RateLimit.Webhook,
+			Window:        cfg.RateLimit.Window,
+		})
+	}
+
+	// Initialize authentication
+	verifier, err := jwtauth.NewVerifier(&cfg.Auth)
+	if err != nil {
+		logger.Fatal("Failed to initialize authentication", "error", err)
+	}
+	authenticator := http.NewAuthenticator(verifier, apiKeyService, identityService, cfg.Auth.AgentIDs, rateLimiter)
+
+	// Initialize health checks; the database gates readiness, a long run queue only degrades health
+	checks := health.New(cfg.Health.CheckTimeout)
+	checks.Register("database", health.Database(db), true)
+	checks.Register("queue", health.QueueDepth(pkgService.CountQueuedRuns, int64(cfg.Health.QueueDepthLimit)), false)
 
 	// Initialize router
 	router := http.NewRouter(
@@ -67,16 +199,49 @@ func main() {
 		scenarioService,
 		datasetService,
 		simulatorService,
+		searchService,
+		bulkService,
+		counterService,
+		apiKeyService,
+		identityService,
+		webhookService,
+		feed,
+		clientIP,
+		authenticator,
+		rateLimiter,
+		checks,
 	)
 
+	// Purge the trash in the background
+	purgeCtx, stopPurger := context.WithCancel(context.Background())
+	defer stopPurger()
+	purger := trash.NewPurger(cfg.Trash.Retention, cfg.Trash.PurgeInterval, log,
+		trash.Target{Name: "packages", Purge: pkgService.PurgeDeletedPackages},
+		trash.Target{Name: "repositories", Purge: repoService.PurgeDeletedRepositories},
+		trash.Target{Name: "scenarios", Purge: scenarioService.PurgeDeletedScenarios},
+		trash.Target{Name: "datasets", Purge: datasetService.PurgeDeletedDatasets},
+		trash.Target{Name: "simulators", Purge: simulatorService.PurgeDeletedSimulators},
+	)
+	go purger.Run(purgeCtx)
+
+	// Dispatch domain events to their subscribers, follow the log for change
+	// streams and deliver webhooks in the background; stopping them ends the streams
+	dispatchCtx, stopDispatchers := context.WithCancel(context.Background())
+	defer stopDispatchers()
+	bus.Subscribe("webhooks", webhookService.HandleEvent)
+	go bus.Run(dispatchCtx)
+	go feed.Run(dispatchCtx)
+	dispatcher := webhook.NewDispatcher(webhookService, cfg.Webhook.Workers, cfg.Webhook.PollInterval, log)
+	go dispatcher.Run(dispatchCtx)
+
 	// Initialize HTTP server
 	server := http.NewServer(&cfg.Server, router)
 
 	// Start server in a goroutine
 	go func() {
-		log.Info("Server starting on %s:%s", cfg.Server.Host, cfg.Server.Port)
+		log.Info("Server starting", "host", cfg.Server.Host, "port", cfg.Server.Port)
 		if err := server.Start(); err != nil && err != context.Canceled {
-			log.Fatal("Server failed to start: %v", err)
+			logger.Fatal("Server failed to start", "error", err)
 		}
 	}()
 
@@ -85,15 +250,26 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	log.Info("Shutting down server...")
+	log.Info("Shutting down server")
+	stopPurger()
+	stopDispatchers()
 
 	// Graceful shutdown with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer cancel()
 
 	if err := server.Shutdown(ctx); err != nil {
-		log.Fatal("Server forced to shutdown: %v", err)
+		logger.Fatal("Server forced to shutdown", "error", err)
 	}
 
 	log.Info("Server exited")
 }
+
+// newLogger creates the JSON or text logger of the service on stdout
+func newLogger(cfg config.LogConfig) (*slog.Logger, error) {
+	level, err := logger.ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+	return logger.New(os.Stdout, level, cfg.Format)
+}
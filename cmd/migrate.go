@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"robohub-inventory/internal/config"
+	"robohub-inventory/internal/database"
+	"robohub-inventory/internal/logger"
+)
+
+const migrateUsage = "usage: migrate status | up | down [N]"
+
+// runMigrate implements the migrate subcommand: status lists the migrations,
+// up applies the pending ones and down reverts the latest N (default 1)
+func runMigrate(cfg *config.Config, args []string) {
+	if len(args) == 0 || (args[0] != "status" && args[0] != "up" && args[0] != "down") {
+		logger.Fatal(migrateUsage)
+	}
+	steps := 1
+	switch {
+	case args[0] == "down" && len(args) == 2:
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 {
+			logger.Fatal("migrate down expects a positive number of steps", "steps", args[1])
+		}
+		steps = n
+	case len(args) != 1:
+		logger.Fatal(migrateUsage)
+	}
+
+	db, err := database.Connect(&cfg.Database)
+	if err != nil {
+		logger.Fatal("Failed to connect to database", "error", err)
+	}
+	defer database.Close()
+
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		logger.Fatal("Failed to load migrations", "error", err)
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "status":
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			logger.Fatal("Failed to read migration status", "error", err)
+		}
+		printMigrationStatus(status)
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			logger.Fatal("Failed to run migrations", "error", err)
+		}
+		slog.Info("Migrations applied", "count", len(applied))
+	case "down":
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			logger.Fatal("Failed to revert migrations", "error", err)
+		}
+		slog.Info("Migrations reverted", "count", len(reverted))
+	}
+}
+
+func printMigrationStatus(status []database.MigrationStatus) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT\tNOTE")
+	for _, s := range status {
+		appliedAt, note := "pending", ""
+		if s.AppliedAt != nil {
+			appliedAt = s.AppliedAt.UTC().Format(time.RFC3339)
+		}
+		switch {
+		case s.Unknown:
+			note = "unknown to this build"
+		case s.Modified:
+			note = "modified after it was applied"
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, appliedAt, note)
+	}
+	w.Flush()
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"robohub-inventory/internal/config"
+	"robohub-inventory/internal/database"
+	"robohub-inventory/internal/fixtures"
+	"robohub-inventory/internal/logger"
+)
+
+// runSeed implements the seed subcommand: it loads the fixture directories
+// given as arguments, or SEED_DIR, in order. Migrations must be applied first.
+func runSeed(cfg *config.Config, dirs []string) {
+	if len(dirs) == 0 {
+		dirs = []string{cfg.Seed.Dir}
+	}
+
+	db, err := database.Connect(&cfg.Database)
+	if err != nil {
+		logger.Fatal("Failed to connect to database", "error", err)
+	}
+	defer database.Close()
+
+	for _, dir := range dirs {
+		result, err := fixtures.Load(context.Background(), db, dir)
+		if err != nil {
+			logger.Fatal("Failed to load fixtures", "dir", dir, "error", err)
+		}
+		slog.Info("Fixtures loaded", "dir", dir, "result", result.String())
+	}
+}
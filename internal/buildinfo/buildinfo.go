@@ -0,0 +1,60 @@
+// Package buildinfo describes the running build. Version, Commit and Date are
+// set at link time:
+//
+//	go build -ldflags "-X robohub-inventory/internal/buildinfo.Version=1.2.0 -X robohub-inventory/internal/buildinfo.Commit=$(git rev-parse HEAD)" ./cmd
+//
+// Otherwise the commit and date are taken from the VCS information embedded
+// by the Go toolchain, when available.
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+var (
+	Version = "dev"
+	Commit  = ""
+	Date    = ""
+)
+
+// Info is the build information returned by GET /version
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Date      string `json:"date,omitempty"`
+	Modified  bool   `json:"modified,omitempty"` // Built from a working tree with uncommitted changes
+	GoVersion string `json:"goVersion"`
+}
+
+var (
+	once sync.Once
+	info Info
+)
+
+// Get returns the build information of the running binary
+func Get() Info {
+	once.Do(func() {
+		info = Info{Version: Version, Commit: Commit, Date: Date, GoVersion: runtime.Version()}
+		bi, ok := debug.ReadBuildInfo()
+		if !ok {
+			return
+		}
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = s.Value
+				}
+			case "vcs.time":
+				if info.Date == "" {
+					info.Date = s.Value
+				}
+			case "vcs.modified":
+				info.Modified = s.Value == "true"
+			}
+		}
+	})
+	return info
+}
@@ -1,56 +1,505 @@
+// Package config loads the service configuration. Settings are layered, each
+// source overriding the previous one:
+//
+//  1. the defaults of Default
+//  2. a YAML (.yaml, .yml) or TOML (.toml) file given by -config or CONFIG_FILE
+//  3. environment variables
+//  4. command-line flags
+//
+// Every setting has an environment variable and a flag named after it, e.g.
+// DB_MAX_OPEN_CONNS and -db-max-open-conns (see settings.go). Secrets can be
+// read from files with the <NAME>_FILE variables, such as DB_PASSWORD_FILE.
+// The merged configuration is validated as a whole.
 package config
 
 import (
+	"bytes"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"log/slog"
+	"net"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
 )
 
+// Redacted replaces secrets in the printed configuration
+const Redacted = "[REDACTED]"
+
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
+	Server    ServerConfig    `yaml:"server" toml:"server"`
+	Database  DatabaseConfig  `yaml:"database" toml:"database"`
+	Auth      AuthConfig      `yaml:"auth" toml:"auth"`
+	RateLimit RateLimitConfig `yaml:"rateLimit" toml:"rateLimit"`
+	Trash     TrashConfig     `yaml:"trash" toml:"trash"`
+	Seed      SeedConfig      `yaml:"seed" toml:"seed"`
+	Log       LogConfig       `yaml:"log" toml:"log"`
+	Tracing   TracingConfig   `yaml:"tracing" toml:"tracing"`
+	Health    HealthConfig    `yaml:"health" toml:"health"`
+	Events    EventsConfig    `yaml:"events" toml:"events"`
+	Webhook   WebhookConfig   `yaml:"webhook" toml:"webhook"`
 }
 
 type ServerConfig struct {
-	Port string
-	Host string
+	Port              string        `yaml:"port" toml:"port"`
+	Host              string        `yaml:"host" toml:"host"`
+	ReadTimeout       time.Duration `yaml:"readTimeout" toml:"readTimeout"`             // Whole request, body included
+	ReadHeaderTimeout time.Duration `yaml:"readHeaderTimeout" toml:"readHeaderTimeout"` // Request headers
+	WriteTimeout      time.Duration `yaml:"writeTimeout" toml:"writeTimeout"`
+	IdleTimeout       time.Duration `yaml:"idleTimeout" toml:"idleTimeout"`         // Keep-alive connections
+	ShutdownTimeout   time.Duration `yaml:"shutdownTimeout" toml:"shutdownTimeout"` // Grace period for in-flight requests
+	TrustedProxies    []string      `yaml:"trustedProxies" toml:"trustedProxies"`   // IPs or CIDRs whose X-Forwarded-For and X-Real-IP are honored
+	TLS               TLSConfig     `yaml:"tls" toml:"tls"`
+}
+
+// TLSConfig serves HTTPS when a certificate and key are set
+type TLSConfig struct {
+	CertFile   string `yaml:"certFile" toml:"certFile"` // PEM certificate chain
+	KeyFile    string `yaml:"keyFile" toml:"keyFile"`   // PEM private key
+	MinVersion string `yaml:"minVersion" toml:"minVersion"`
+}
+
+// Enabled reports whether the server serves HTTPS
+func (t TLSConfig) Enabled() bool {
+	return t.CertFile != "" || t.KeyFile != ""
 }
 
 type DatabaseConfig struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
-	DBName   string
-	SSLMode  string
+	Host         string `yaml:"host" toml:"host"`
+	Port         string `yaml:"port" toml:"port"`
+	User         string `yaml:"user" toml:"user"`
+	Password     string `yaml:"password" toml:"password"`
+	PasswordFile string `yaml:"passwordFile" toml:"passwordFile"` // Overrides Password with the file contents
+	DBName       string `yaml:"name" toml:"name"`
+	SSLMode      string `yaml:"sslMode" toml:"sslMode"`
+	AutoMigrate  bool   `yaml:"autoMigrate" toml:"autoMigrate"` // Apply pending migrations at startup instead of a separate "migrate up"
+
+	SlowQueryThreshold time.Duration `yaml:"slowQueryThreshold" toml:"slowQueryThreshold"` // Queries slower than this are logged as warnings; 0 disables
+
+	MaxOpenConns    int           `yaml:"maxOpenConns" toml:"maxOpenConns"`
+	MaxIdleConns    int           `yaml:"maxIdleConns" toml:"maxIdleConns"`
+	ConnMaxLifetime time.Duration `yaml:"connMaxLifetime" toml:"connMaxLifetime"` // 0 is unlimited
+	ConnMaxIdleTime time.Duration `yaml:"connMaxIdleTime" toml:"connMaxIdleTime"` // 0 is unlimited
+}
+
+// LogValue logs the connection settings without the password
+func (d DatabaseConfig) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("host", d.Host),
+		slog.String("port", d.Port),
+		slog.String("user", d.User),
+		slog.String("dbname", d.DBName),
+		slog.String("sslmode", d.SSLMode),
+	)
+}
+
+// AuthConfig configures request authentication (API_CONTRACT.md §7)
+type AuthConfig struct {
+	JWTSecret        string   `yaml:"jwtSecret" toml:"jwtSecret"`               // HMAC secret for HS256/384/512 bearer tokens
+	JWTSecretFile    string   `yaml:"jwtSecretFile" toml:"jwtSecretFile"`       // Overrides JWTSecret with the file contents
+	JWTPublicKeyFile string   `yaml:"jwtPublicKeyFile" toml:"jwtPublicKeyFile"` // PEM RSA or EC public key for bearer tokens
+	JWKSURL          string   `yaml:"jwksURL" toml:"jwksURL"`                   // JWKS endpoint; takes precedence over static keys
+	JWTIssuer        string   `yaml:"jwtIssuer" toml:"jwtIssuer"`               // Required "iss" claim, if set
+	JWTAudience      string   `yaml:"jwtAudience" toml:"jwtAudience"`           // Required "aud" claim, if set
+	AgentIDs         []string `yaml:"agentIDs" toml:"agentIDs"`                 // Internal agents accepted via X-Agent-ID
+	AdminSubjects    []string `yaml:"adminSubjects" toml:"adminSubjects"`       // Subjects granted platform admin rights
 }
 
-func Load() (*Config, error) {
-	cfg := &Config{
+// RateLimitConfig configures per-client request limits (API_CONTRACT.md §10)
+type RateLimitConfig struct {
+	Enabled       bool          `yaml:"enabled" toml:"enabled"`
+	Store         string        `yaml:"store" toml:"store"`                 // "memory" or "postgres" for limits shared across replicas
+	Public        int           `yaml:"public" toml:"public"`               // Requests per window for anonymous callers
+	Authenticated int           `yaml:"authenticated" toml:"authenticated"` // Requests per window for users and API keys
+	Webhook       int           `yaml:"webhook" toml:"webhook"`             // Requests per window for internal agents
+	Window        time.Duration `yaml:"window" toml:"window"`
+}
+
+// TrashConfig configures how long deleted entities can be restored
+type TrashConfig struct {
+	Retention     time.Duration `yaml:"retention" toml:"retention"` // Deleted entities older than this are purged
+	PurgeInterval time.Duration `yaml:"purgeInterval" toml:"purgeInterval"`
+}
+
+// SeedConfig configures fixture loading (see internal/fixtures)
+type SeedConfig struct {
+	Load bool   `yaml:"load" toml:"load"` // Load the fixtures at startup
+	Dir  string `yaml:"dir" toml:"dir"`   // Directory of the fixture files
+}
+
+// LogConfig configures structured logging (see internal/logger)
+type LogConfig struct {
+	Level  string `yaml:"level" toml:"level"`   // "debug" | "info" | "warn" | "error"
+	Format string `yaml:"format" toml:"format"` // "json" | "text"
+}
+
+// TracingConfig configures OpenTelemetry tracing (see internal/tracing). The
+// OTLP exporter reads its endpoint and headers from the standard
+// OTEL_EXPORTER_OTLP_* variables.
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter" toml:"exporter"`       // "none" | "otlp" | "stdout"
+	ServiceName string  `yaml:"serviceName" toml:"serviceName"` // service.name resource attribute
+	SampleRatio float64 `yaml:"sampleRatio" toml:"sampleRatio"` // Fraction of new traces sampled; sampled parents are always followed
+}
+
+// HealthConfig configures the health and readiness checks (see internal/health)
+type HealthConfig struct {
+	CheckTimeout    time.Duration `yaml:"checkTimeout" toml:"checkTimeout"`       // A check still running after this is reported down
+	QueueDepthLimit int           `yaml:"queueDepthLimit" toml:"queueDepthLimit"` // The run queue is reported degraded above this many queued runs
+}
+
+// EventsConfig configures the domain event log and its dispatch (see pkg/event)
+type EventsConfig struct {
+	PollInterval time.Duration `yaml:"pollInterval" toml:"pollInterval"` // How often the log is read for new events
+	BatchSize    int           `yaml:"batchSize" toml:"batchSize"`       // Events handled per subscriber and transaction
+	Retention    time.Duration `yaml:"retention" toml:"retention"`       // Processed events older than this are deleted
+}
+
+// WebhookConfig configures the delivery of webhook events (see pkg/webhook)
+type WebhookConfig struct {
+	Workers      int           `yaml:"workers" toml:"workers"`           // Concurrent deliveries per replica
+	PollInterval time.Duration `yaml:"pollInterval" toml:"pollInterval"` // How often due deliveries are looked for
+	Timeout      time.Duration `yaml:"timeout" toml:"timeout"`           // Timeout of each attempt
+	MaxRetries   int           `yaml:"maxRetries" toml:"maxRetries"`     // Retries after the first attempt before a delivery fails
+	BackoffBase  time.Duration `yaml:"backoffBase" toml:"backoffBase"`   // Delay before the first retry, doubled for each further retry
+	BackoffMax   time.Duration `yaml:"backoffMax" toml:"backoffMax"`     // Upper bound of the retry delay
+	DisableAfter time.Duration `yaml:"disableAfter" toml:"disableAfter"` // A subscription failing for this long is disabled
+}
+
+// Default returns the configuration used for settings that no source sets
+func Default() *Config {
+	return &Config{
 		Server: ServerConfig{
-			Port: getEnv("PORT", "8180"),
-			Host: getEnv("HOST", "0.0.0.0"),
+			Port:              "8180",
+			Host:              "0.0.0.0",
+			ReadTimeout:       15 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      15 * time.Second,
+			IdleTimeout:       60 * time.Second,
+			ShutdownTimeout:   30 * time.Second,
+			TLS:               TLSConfig{MinVersion: "1.2"},
 		},
 		Database: DatabaseConfig{
-			Host:     getEnv("DB_HOST", "localhost"),
-			Port:     getEnv("DB_PORT", "5435"),
-			User:     getEnv("DB_USER", "postgres"),
-			Password: getEnv("DB_PASSWORD", "postgres"),
-			DBName:   getEnv("DB_NAME", "robohub"),
-			SSLMode:  getEnv("DB_SSLMODE", "disable"),
+			Host:               "localhost",
+			Port:               "5435",
+			User:               "postgres",
+			Password:           "postgres",
+			DBName:             "robohub",
+			SSLMode:            "disable",
+			AutoMigrate:        true,
+			SlowQueryThreshold: 200 * time.Millisecond,
+			MaxOpenConns:       25,
+			MaxIdleConns:       10,
+			ConnMaxLifetime:    30 * time.Minute,
+			ConnMaxIdleTime:    5 * time.Minute,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:       true,
+			Store:         "memory",
+			Public:        100,
+			Authenticated: 1000,
+			Webhook:       10000,
+			Window:        time.Minute,
+		},
+		Trash: TrashConfig{
+			Retention:     30 * 24 * time.Hour,
+			PurgeInterval: time.Hour,
+		},
+		Seed: SeedConfig{
+			Dir: "fixtures",
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			ServiceName: "robohub-inventory",
+			SampleRatio: 1,
+		},
+		Health: HealthConfig{
+			CheckTimeout:    2 * time.Second,
+			QueueDepthLimit: 1000,
 		},
+		Events: EventsConfig{
+			PollInterval: time.Second,
+			BatchSize:    100,
+			Retention:    7 * 24 * time.Hour,
+		},
+		Webhook: WebhookConfig{
+			Workers:      4,
+			PollInterval: 2 * time.Second,
+			Timeout:      10 * time.Second,
+			MaxRetries:   8,
+			BackoffBase:  30 * time.Second,
+			BackoffMax:   6 * time.Hour,
+			DisableAfter: 72 * time.Hour,
+		},
+	}
+}
+
+// Load merges the defaults, the config file, the environment and the flags
+// in args, and validates the result. It returns the arguments left after the
+// flags, such as a subcommand.
+func Load(args []string) (*Config, []string, error) {
+	cfg := Default()
+	settings := cfg.settings()
+
+	fs := flag.NewFlagSet("robohub-inventory", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: robohub-inventory [flags] [migrate|seed|config] [args]\n\nFlags:\n")
+		fs.PrintDefaults()
 	}
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "YAML or TOML config `file` (env CONFIG_FILE)")
 
-	return cfg, nil
+	// Flags are recorded while parsing and applied last, so that they override the file and environment
+	type override struct {
+		s     setting
+		value string
+	}
+	var overrides []override
+	for _, s := range settings {
+		s := s
+		usage := fmt.Sprintf("%s (env %s)", s.usage, s.env)
+		record := func(v string) error {
+			overrides = append(overrides, override{s, v})
+			return nil
+		}
+		if _, ok := s.value.(*boolValue); ok {
+			fs.BoolFunc(s.flag(), usage, record)
+		} else {
+			fs.Func(s.flag(), usage, record)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	if *configFile != "" {
+		if err := cfg.loadFile(*configFile); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var errs []error
+	for _, s := range settings {
+		if v := os.Getenv(s.env); v != "" {
+			if err := s.value.Set(v); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", s.env, err))
+			}
+		}
+	}
+	for _, o := range overrides {
+		if err := o.s.value.Set(o.value); err != nil {
+			errs = append(errs, fmt.Errorf("-%s: %w", o.s.flag(), err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, nil, err
+	}
+
+	if err := cfg.readSecrets(); err != nil {
+		return nil, nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return cfg, fs.Args(), nil
+}
+
+// loadFile decodes a YAML or TOML file over c. Unknown keys are rejected.
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	case ".toml":
+		md, err := toml.Decode(string(data), c)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("%s: unknown keys %v", path, undecoded)
+		}
+	default:
+		return fmt.Errorf("%s: config file must be .yaml, .yml or .toml, got %q", path, ext)
+	}
+	return nil
+}
+
+// readSecrets replaces the secrets that have a file set with the file contents
+func (c *Config) readSecrets() error {
+	secrets := []struct {
+		file   string
+		secret *string
+	}{
+		{c.Database.PasswordFile, &c.Database.Password},
+		{c.Auth.JWTSecretFile, &c.Auth.JWTSecret},
+	}
+	for _, s := range secrets {
+		if s.file == "" {
+			continue
+		}
+		data, err := os.ReadFile(s.file)
+		if err != nil {
+			return fmt.Errorf("failed to read secret: %w", err)
+		}
+		*s.secret = strings.TrimRight(string(data), "\r\n")
+	}
+	return nil
+}
+
+// Validate checks the whole configuration, reporting every invalid setting
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	oneOf := func(name, value string, allowed ...string) {
+		for _, a := range allowed {
+			if value == a {
+				return
+			}
+		}
+		errs = append(errs, fmt.Errorf("%s must be one of %s, got %q", name, strings.Join(allowed, ", "), value))
+	}
+	port := func(name, value string) {
+		n, err := strconv.Atoi(value)
+		check(err == nil && n > 0 && n < 65536, "%s must be a port number, got %q", name, value)
+	}
+	positive := func(name string, d time.Duration) {
+		check(d > 0, "%s must be a positive duration, got %s", name, d)
+	}
+	file := func(name, path string) {
+		if path == "" {
+			return
+		}
+		_, err := os.Stat(path)
+		check(err == nil, "%s: %v", name, err)
+	}
+
+	s := c.Server
+	port("server.port", s.Port)
+	positive("server.readTimeout", s.ReadTimeout)
+	positive("server.readHeaderTimeout", s.ReadHeaderTimeout)
+	positive("server.writeTimeout", s.WriteTimeout)
+	positive("server.idleTimeout", s.IdleTimeout)
+	positive("server.shutdownTimeout", s.ShutdownTimeout)
+	for _, proxy := range s.TrustedProxies {
+		_, _, err := net.ParseCIDR(proxy)
+		check(err == nil || net.ParseIP(proxy) != nil, "server.trustedProxies must hold IP addresses or CIDR ranges, got %q", proxy)
+	}
+	if s.TLS.Enabled() {
+		check(s.TLS.CertFile != "" && s.TLS.KeyFile != "", "server.tls.certFile and server.tls.keyFile must be set together")
+		file("server.tls.certFile", s.TLS.CertFile)
+		file("server.tls.keyFile", s.TLS.KeyFile)
+		oneOf("server.tls.minVersion", s.TLS.MinVersion, "1.2", "1.3")
+	}
+
+	d := c.Database
+	check(d.Host != "", "database.host is required")
+	port("database.port", d.Port)
+	check(d.User != "", "database.user is required")
+	check(d.DBName != "", "database.name is required")
+	oneOf("database.sslMode", d.SSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full")
+	check(d.SlowQueryThreshold >= 0, "database.slowQueryThreshold must not be negative")
+	check(d.MaxOpenConns > 0, "database.maxOpenConns must be positive, got %d", d.MaxOpenConns)
+	check(d.MaxIdleConns >= 0 && d.MaxIdleConns <= d.MaxOpenConns,
+		"database.maxIdleConns must be between 0 and database.maxOpenConns, got %d", d.MaxIdleConns)
+	check(d.ConnMaxLifetime >= 0, "database.connMaxLifetime must not be negative")
+	check(d.ConnMaxIdleTime >= 0, "database.connMaxIdleTime must not be negative")
+
+	a := c.Auth
+	file("auth.jwtPublicKeyFile", a.JWTPublicKeyFile)
+	if a.JWKSURL != "" {
+		check(strings.HasPrefix(a.JWKSURL, "https://") || strings.HasPrefix(a.JWKSURL, "http://"),
+			"auth.jwksURL must be an http(s) URL, got %q", a.JWKSURL)
+	}
+
+	r := c.RateLimit
+	oneOf("rateLimit.store", r.Store, "memory", "postgres")
+	check(r.Public > 0, "rateLimit.public must be positive, got %d", r.Public)
+	check(r.Authenticated > 0, "rateLimit.authenticated must be positive, got %d", r.Authenticated)
+	check(r.Webhook > 0, "rateLimit.webhook must be positive, got %d", r.Webhook)
+	positive("rateLimit.window", r.Window)
+
+	positive("trash.retention", c.Trash.Retention)
+	positive("trash.purgeInterval", c.Trash.PurgeInterval)
+	if c.Seed.Load {
+		check(c.Seed.Dir != "", "seed.dir is required to load fixtures")
+	}
+
+	oneOf("log.level", strings.ToLower(c.Log.Level), "debug", "info", "warn", "error")
+	oneOf("log.format", c.Log.Format, "json", "text")
+
+	oneOf("tracing.exporter", c.Tracing.Exporter, "none", "otlp", "stdout")
+	check(c.Tracing.ServiceName != "", "tracing.serviceName is required")
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1,
+		"tracing.sampleRatio must be between 0 and 1, got %v", c.Tracing.SampleRatio)
+
+	positive("health.checkTimeout", c.Health.CheckTimeout)
+	check(c.Health.QueueDepthLimit > 0, "health.queueDepthLimit must be positive, got %d", c.Health.QueueDepthLimit)
+
+	positive("events.pollInterval", c.Events.PollInterval)
+	check(c.Events.BatchSize > 0, "events.batchSize must be positive, got %d", c.Events.BatchSize)
+	positive("events.retention", c.Events.Retention)
+
+	wh := c.Webhook
+	check(wh.Workers > 0, "webhook.workers must be positive, got %d", wh.Workers)
+	positive("webhook.pollInterval", wh.PollInterval)
+	positive("webhook.timeout", wh.Timeout)
+	check(wh.MaxRetries >= 0, "webhook.maxRetries must not be negative, got %d", wh.MaxRetries)
+	positive("webhook.backoffBase", wh.BackoffBase)
+	check(wh.BackoffMax >= wh.BackoffBase, "webhook.backoffMax must be at least webhook.backoffBase, got %s", wh.BackoffMax)
+	positive("webhook.disableAfter", wh.DisableAfter)
+
+	return errors.Join(errs...)
 }
 
+// Print writes the effective configuration as YAML, with secrets redacted
+func (c *Config) Print(w io.Writer) error {
+	redacted := *c
+	if redacted.Database.Password != "" {
+		redacted.Database.Password = Redacted
+	}
+	if redacted.Auth.JWTSecret != "" {
+		redacted.Auth.JWTSecret = Redacted
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&redacted); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// DSN returns the connection string in keyword/value form. Every value is
+// quoted, so passwords containing spaces, quotes or backslashes survive.
 func (d DatabaseConfig) DSN() string {
 	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		d.Host, d.Port, d.User, d.Password, d.DBName, d.SSLMode)
+		dsnValue(d.Host), dsnValue(d.Port), dsnValue(d.User), dsnValue(d.Password), dsnValue(d.DBName), dsnValue(d.SSLMode))
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
+// dsnQuoter escapes the characters libpq treats specially in a quoted value
+var dsnQuoter = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// dsnValue quotes a connection string value
+func dsnValue(v string) string {
+	return "'" + dsnQuoter.Replace(v) + "'"
 }
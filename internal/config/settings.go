@@ -0,0 +1,166 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setting binds an environment variable, and the flag named after it, to a
+// configuration field
+type setting struct {
+	env   string
+	usage string
+	value valueSetter
+}
+
+// flag returns the flag name of the setting, e.g. -db-max-open-conns for DB_MAX_OPEN_CONNS
+func (s setting) flag() string {
+	return strings.ReplaceAll(strings.ToLower(s.env), "_", "-")
+}
+
+// valueSetter parses a setting into its field
+type valueSetter interface {
+	Set(string) error
+}
+
+func (c *Config) settings() []setting {
+	return []setting{
+		{"HOST", "listen address", (*stringValue)(&c.Server.Host)},
+		{"PORT", "listen port", (*stringValue)(&c.Server.Port)},
+		{"SERVER_READ_TIMEOUT", "maximum duration of reading a request", (*durationValue)(&c.Server.ReadTimeout)},
+		{"SERVER_READ_HEADER_TIMEOUT", "maximum duration of reading request headers", (*durationValue)(&c.Server.ReadHeaderTimeout)},
+		{"SERVER_WRITE_TIMEOUT", "maximum duration of writing a response", (*durationValue)(&c.Server.WriteTimeout)},
+		{"SERVER_IDLE_TIMEOUT", "keep-alive timeout", (*durationValue)(&c.Server.IdleTimeout)},
+		{"SERVER_SHUTDOWN_TIMEOUT", "grace period of in-flight requests on shutdown", (*durationValue)(&c.Server.ShutdownTimeout)},
+		{"SERVER_TRUSTED_PROXIES", "comma-separated IPs or CIDRs of proxies whose forwarding headers are honored", (*listValue)(&c.Server.TrustedProxies)},
+		{"TLS_CERT_FILE", "PEM certificate chain; serves HTTPS with TLS_KEY_FILE", (*stringValue)(&c.Server.TLS.CertFile)},
+		{"TLS_KEY_FILE", "PEM private key", (*stringValue)(&c.Server.TLS.KeyFile)},
+		{"TLS_MIN_VERSION", "minimum TLS version, 1.2 or 1.3", (*stringValue)(&c.Server.TLS.MinVersion)},
+
+		{"DB_HOST", "database host", (*stringValue)(&c.Database.Host)},
+		{"DB_PORT", "database port", (*stringValue)(&c.Database.Port)},
+		{"DB_USER", "database user", (*stringValue)(&c.Database.User)},
+		{"DB_PASSWORD", "database password", (*stringValue)(&c.Database.Password)},
+		{"DB_PASSWORD_FILE", "file holding the database password", (*stringValue)(&c.Database.PasswordFile)},
+		{"DB_NAME", "database name", (*stringValue)(&c.Database.DBName)},
+		{"DB_SSLMODE", "PostgreSQL sslmode", (*stringValue)(&c.Database.SSLMode)},
+		{"DB_AUTO_MIGRATE", "apply pending migrations at startup", (*boolValue)(&c.Database.AutoMigrate)},
+		{"DB_SLOW_QUERY_THRESHOLD", "log queries slower than this as warnings; 0 disables", (*durationValue)(&c.Database.SlowQueryThreshold)},
+		{"DB_MAX_OPEN_CONNS", "maximum open connections", (*intValue)(&c.Database.MaxOpenConns)},
+		{"DB_MAX_IDLE_CONNS", "maximum idle connections", (*intValue)(&c.Database.MaxIdleConns)},
+		{"DB_CONN_MAX_LIFETIME", "maximum lifetime of a connection; 0 is unlimited", (*durationValue)(&c.Database.ConnMaxLifetime)},
+		{"DB_CONN_MAX_IDLE_TIME", "maximum idle time of a connection; 0 is unlimited", (*durationValue)(&c.Database.ConnMaxIdleTime)},
+
+		{"AUTH_JWT_SECRET", "HMAC secret of bearer tokens", (*stringValue)(&c.Auth.JWTSecret)},
+		{"AUTH_JWT_SECRET_FILE", "file holding the HMAC secret of bearer tokens", (*stringValue)(&c.Auth.JWTSecretFile)},
+		{"AUTH_JWT_PUBLIC_KEY_FILE", "PEM RSA or EC public key of bearer tokens", (*stringValue)(&c.Auth.JWTPublicKeyFile)},
+		{"AUTH_JWKS_URL", "JWKS endpoint of bearer tokens", (*stringValue)(&c.Auth.JWKSURL)},
+		{"AUTH_JWT_ISSUER", "required iss claim", (*stringValue)(&c.Auth.JWTIssuer)},
+		{"AUTH_JWT_AUDIENCE", "required aud claim", (*stringValue)(&c.Auth.JWTAudience)},
+		{"AUTH_AGENT_IDS", "comma-separated agent IDs accepted via X-Agent-ID", (*listValue)(&c.Auth.AgentIDs)},
+		{"AUTH_ADMIN_SUBJECTS", "comma-separated subjects granted platform admin rights", (*listValue)(&c.Auth.AdminSubjects)},
+
+		{"RATE_LIMIT_ENABLED", "limit requests per client", (*boolValue)(&c.RateLimit.Enabled)},
+		{"RATE_LIMIT_STORE", "rate limit store, memory or postgres", (*stringValue)(&c.RateLimit.Store)},
+		{"RATE_LIMIT_PUBLIC", "requests per window of anonymous callers", (*intValue)(&c.RateLimit.Public)},
+		{"RATE_LIMIT_AUTHENTICATED", "requests per window of users and API keys", (*intValue)(&c.RateLimit.Authenticated)},
+		{"RATE_LIMIT_WEBHOOK", "requests per window of internal agents", (*intValue)(&c.RateLimit.Webhook)},
+		{"RATE_LIMIT_WINDOW", "rate limit window", (*durationValue)(&c.RateLimit.Window)},
+
+		{"TRASH_RETENTION", "how long deleted entities stay restorable", (*durationValue)(&c.Trash.Retention)},
+		{"TRASH_PURGE_INTERVAL", "how often the trash is purged", (*durationValue)(&c.Trash.PurgeInterval)},
+
+		{"LOAD_SEED_DATA", "load the fixtures at startup", (*boolValue)(&c.Seed.Load)},
+		{"SEED_DIR", "fixture directory", (*stringValue)(&c.Seed.Dir)},
+
+		{"LOG_LEVEL", "debug, info, warn or error", (*stringValue)(&c.Log.Level)},
+		{"LOG_FORMAT", "json or text", (*stringValue)(&c.Log.Format)},
+
+		{"TRACING_EXPORTER", "none, otlp or stdout", (*stringValue)(&c.Tracing.Exporter)},
+		{"OTEL_SERVICE_NAME", "service name of exported spans", (*stringValue)(&c.Tracing.ServiceName)},
+		{"TRACING_SAMPLE_RATIO", "fraction of new traces recorded", (*floatValue)(&c.Tracing.SampleRatio)},
+
+		{"HEALTH_CHECK_TIMEOUT", "timeout of each health check", (*durationValue)(&c.Health.CheckTimeout)},
+		{"HEALTH_QUEUE_DEPTH_LIMIT", "queued runs above which health is degraded", (*intValue)(&c.Health.QueueDepthLimit)},
+
+		{"EVENTS_POLL_INTERVAL", "how often the event log is read for new events", (*durationValue)(&c.Events.PollInterval)},
+		{"EVENTS_BATCH_SIZE", "events handled per subscriber and transaction", (*intValue)(&c.Events.BatchSize)},
+		{"EVENTS_RETENTION", "how long processed events are kept", (*durationValue)(&c.Events.Retention)},
+
+		{"WEBHOOK_WORKERS", "concurrent webhook deliveries per replica", (*intValue)(&c.Webhook.Workers)},
+		{"WEBHOOK_POLL_INTERVAL", "how often due webhook deliveries are looked for", (*durationValue)(&c.Webhook.PollInterval)},
+		{"WEBHOOK_TIMEOUT", "timeout of each webhook delivery attempt", (*durationValue)(&c.Webhook.Timeout)},
+		{"WEBHOOK_MAX_RETRIES", "retries of a failed webhook delivery", (*intValue)(&c.Webhook.MaxRetries)},
+		{"WEBHOOK_BACKOFF_BASE", "delay before the first retry, doubled for each further retry", (*durationValue)(&c.Webhook.BackoffBase)},
+		{"WEBHOOK_BACKOFF_MAX", "upper bound of the retry delay", (*durationValue)(&c.Webhook.BackoffMax)},
+		{"WEBHOOK_DISABLE_AFTER", "failure streak after which a subscription is disabled", (*durationValue)(&c.Webhook.DisableAfter)},
+	}
+}
+
+type stringValue string
+
+func (v *stringValue) Set(s string) error {
+	*v = stringValue(s)
+	return nil
+}
+
+type boolValue bool
+
+func (v *boolValue) Set(s string) error {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return fmt.Errorf("must be true or false, got %q", s)
+	}
+	*v = boolValue(b)
+	return nil
+}
+
+type intValue int
+
+func (v *intValue) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("must be an integer, got %q", s)
+	}
+	*v = intValue(n)
+	return nil
+}
+
+type floatValue float64
+
+func (v *floatValue) Set(s string) error {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("must be a number, got %q", s)
+	}
+	*v = floatValue(f)
+	return nil
+}
+
+// durationValue accepts durations such as "720h" or "1m30s"
+type durationValue time.Duration
+
+func (v *durationValue) Set(s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("must be a duration, got %q", s)
+	}
+	*v = durationValue(d)
+	return nil
+}
+
+// listValue reads a comma-separated list, skipping empty entries
+type listValue []string
+
+func (v *listValue) Set(s string) error {
+	var values []string
+	for _, e := range strings.Split(s, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			values = append(values, e)
+		}
+	}
+	*v = values
+	return nil
+}
@@ -0,0 +1,306 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"gorm.io/gorm"
+
+	"robohub-inventory/internal/tracing"
+)
+
+var tracer = otel.Tracer("robohub-inventory/internal/database")
+
+// migrationFiles holds the schema scripts, named <version>_<name>.up.sql and
+// <version>_<name>.down.sql. A script runs in one transaction and may contain
+// several statements.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// migrationLockKey identifies the advisory lock held while migrating, so that
+// replicas starting together apply each migration once
+const migrationLockKey = 7_406_151_462_021_364_992
+
+// ErrChecksumMismatch is returned when an applied migration no longer matches its script
+var ErrChecksumMismatch = errors.New("applied migration was modified")
+
+// Migration is a versioned schema change
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // SHA-256 of the up script
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// MigrationStatus describes a migration known to this build or recorded in the database
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+	Modified  bool // The up script changed after it was applied
+	Unknown   bool // Applied by a newer build; there is no script for it
+}
+
+// appliedMigration is a row of the applied-migrations table
+type appliedMigration struct {
+	Version   int64 `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+func (appliedMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Migrator applies and reverts the embedded migrations
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// NewMigrator loads the embedded migrations in version order
+func NewMigrator(db *gorm.DB) (*Migrator, error) {
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+func loadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file %s", entry.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", entry.Name(), err)
+		}
+		script, err := fs.ReadFile(fsys, "migrations/"+entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", m, entry.Name(), version)
+		}
+		if match[3] == "up" {
+			m.Up = string(script)
+		} else {
+			m.Down = string(script)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %s needs both an up and a down script", m)
+		}
+		sum := sha256.Sum256([]byte(m.Up))
+		m.Checksum = hex.EncodeToString(sum[:])
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Status lists the known migrations and any applied migration this build
+// does not know, in version order
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var status []MigrationStatus
+	err := m.locked(ctx, func(db *gorm.DB) error {
+		applied, err := appliedMigrations(db)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			s := MigrationStatus{Migration: migration}
+			if a, ok := applied[migration.Version]; ok {
+				s.AppliedAt = &a.AppliedAt
+				s.Modified = a.Checksum != migration.Checksum
+				delete(applied, migration.Version)
+			}
+			status = append(status, s)
+		}
+		for _, a := range applied {
+			appliedAt := a.AppliedAt
+			status = append(status, MigrationStatus{
+				Migration: Migration{Version: a.Version, Name: a.Name, Checksum: a.Checksum},
+				AppliedAt: &appliedAt,
+				Unknown:   true,
+			})
+		}
+		return nil
+	})
+	sort.Slice(status, func(i, j int) bool { return status[i].Version < status[j].Version })
+	return status, err
+}
+
+// Up applies the pending migrations in version order. It refuses to run when
+// an applied migration was modified or a pending one is older than the latest
+// applied migration.
+func (m *Migrator) Up(ctx context.Context) (done []Migration, err error) {
+	ctx, span := tracer.Start(ctx, "database.Migrator.Up")
+	defer func() { tracing.End(span, err) }()
+
+	err = m.locked(ctx, func(db *gorm.DB) error {
+		applied, err := appliedMigrations(db)
+		if err != nil {
+			return err
+		}
+
+		var latest int64
+		for version := range applied {
+			if version > latest {
+				latest = version
+			}
+		}
+		var pending []Migration
+		for _, migration := range m.migrations {
+			a, ok := applied[migration.Version]
+			switch {
+			case ok && a.Checksum != migration.Checksum:
+				return fmt.Errorf("%w: %s", ErrChecksumMismatch, migration)
+			case !ok && migration.Version < latest:
+				return fmt.Errorf("migration %s is older than the latest applied version %d", migration, latest)
+			case !ok:
+				pending = append(pending, migration)
+			}
+		}
+
+		for _, migration := range pending {
+			err := db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(migration.Up).Error; err != nil {
+					return err
+				}
+				return tx.Create(&appliedMigration{
+					Version:   migration.Version,
+					Name:      migration.Name,
+					Checksum:  migration.Checksum,
+					AppliedAt: time.Now().UTC(),
+				}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("failed to apply migration %s: %w", migration, err)
+			}
+			slog.InfoContext(ctx, "Applied migration", "migration", migration.String())
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down reverts the latest steps applied migrations, newest first
+func (m *Migrator) Down(ctx context.Context, steps int) (done []Migration, err error) {
+	if steps < 1 {
+		return nil, fmt.Errorf("cannot revert %d migrations", steps)
+	}
+	known := make(map[int64]Migration, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = migration
+	}
+
+	ctx, span := tracer.Start(ctx, "database.Migrator.Down")
+	defer func() { tracing.End(span, err) }()
+
+	err = m.locked(ctx, func(db *gorm.DB) error {
+		var applied []appliedMigration
+		if err := db.Order("version DESC").Limit(steps).Find(&applied).Error; err != nil {
+			return err
+		}
+
+		for _, a := range applied {
+			migration, ok := known[a.Version]
+			if !ok {
+				return fmt.Errorf("applied migration %04d_%s is unknown to this build", a.Version, a.Name)
+			}
+			if a.Checksum != migration.Checksum {
+				return fmt.Errorf("%w: %s", ErrChecksumMismatch, migration)
+			}
+			err := db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(migration.Down).Error; err != nil {
+					return err
+				}
+				return tx.Delete(&appliedMigration{}, a.Version).Error
+			})
+			if err != nil {
+				return fmt.Errorf("failed to revert migration %s: %w", migration, err)
+			}
+			slog.InfoContext(ctx, "Reverted migration", "migration", migration.String())
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// locked runs fn on a single connection holding the migration lock, after
+// creating the applied-migrations table if needed
+func (m *Migrator) locked(ctx context.Context, fn func(db *gorm.DB) error) error {
+	return m.db.WithContext(ctx).Connection(func(db *gorm.DB) error {
+		if err := db.Exec("SELECT pg_advisory_lock(?)", migrationLockKey).Error; err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		defer func() {
+			// A fresh context, so that the lock is released even after cancellation
+			if err := db.WithContext(context.Background()).Exec("SELECT pg_advisory_unlock(?)", migrationLockKey).Error; err != nil {
+				slog.Warn("Failed to release migration lock", "error", err)
+			}
+		}()
+
+		err := db.Exec(`
+			CREATE TABLE IF NOT EXISTS schema_migrations (
+				version    bigint PRIMARY KEY,
+				name       text NOT NULL,
+				checksum   text NOT NULL,
+				applied_at timestamptz NOT NULL
+			)`).Error
+		if err != nil {
+			return fmt.Errorf("failed to create migrations table: %w", err)
+		}
+		return fn(db)
+	})
+}
+
+func appliedMigrations(db *gorm.DB) (map[int64]appliedMigration, error) {
+	var rows []appliedMigration
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]appliedMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
@@ -0,0 +1,521 @@
+package database_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"robohub-inventory/internal/database"
+	"robohub-inventory/internal/database/dbtest"
+)
+
+// The models below are those of the last release that created the schema
+// with AutoMigrate, reduced to their columns. JSONB columns hold a document.
+
+// document is the JSON of a JSONB column; empty is NULL
+type document string
+
+func (d document) Value() (driver.Value, error) {
+	if d == "" {
+		return nil, nil
+	}
+	return string(d), nil
+}
+
+type baselineRepository struct {
+	ID            string `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Name          string `gorm:"uniqueIndex;not null"`
+	Provider      string `gorm:"not null"`
+	URL           string `gorm:"not null"`
+	Description   string
+	DefaultBranch string `gorm:"not null;default:'main'"`
+	Visibility    string `gorm:"not null;default:'public'"`
+	LastSynced    time.Time
+	SyncStatus    string   `gorm:"not null;default:'needs_attention'"`
+	AutoSync      bool     `gorm:"default:false"`
+	LatestCommit  document `gorm:"type:jsonb"`
+	WebhookStatus string   `gorm:"default:'inactive'"`
+	WebhookID     string
+	Tags          []string `gorm:"type:text[]"`
+	PackageCount  int      `gorm:"default:0"`
+	Owner         document `gorm:"type:jsonb"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+func (baselineRepository) TableName() string { return "repositories" }
+
+type baselinePackage struct {
+	ID                     string `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Name                   string `gorm:"uniqueIndex;not null"`
+	DisplayName            string
+	Description            string
+	Documentation          string
+	RepoID                 string `gorm:"index"`
+	RepoName               string
+	Path                   string
+	Types                  []string `gorm:"type:text[]"`
+	LatestVersion          string
+	Versions               []string `gorm:"type:text[]"`
+	Tags                   []string `gorm:"type:text[]"`
+	Keywords               []string `gorm:"type:text[]"`
+	ValidationStatus       document `gorm:"type:jsonb"`
+	LinkedScenariosCount   int      `gorm:"default:0"`
+	LinkedDatasetsCount    int      `gorm:"default:0"`
+	UsedInCollectionsCount int      `gorm:"default:0"`
+	Owner                  document `gorm:"type:jsonb"`
+	LastRun                document `gorm:"type:jsonb"`
+	License                string
+	Dependencies           document `gorm:"type:jsonb"`
+	CreatedAt              time.Time
+	UpdatedAt              time.Time
+}
+
+func (baselinePackage) TableName() string { return "packages" }
+
+type baselineScenario struct {
+	ID                  string `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Name                string `gorm:"uniqueIndex;not null"`
+	Slug                string `gorm:"index"`
+	Description         string
+	DetailedDescription string   `gorm:"type:text"`
+	Category            string   `gorm:"not null"`
+	Difficulty          string   `gorm:"not null"`
+	MaintainedBy        string   `gorm:"not null"`
+	Verified            bool     `gorm:"default:false"`
+	WhatItTests         []string `gorm:"type:text[]"`
+	WhyItMatters        string
+	RealWorldAnalogs    []string `gorm:"type:text[]"`
+	Domain              string
+	SupportedSimulators []string `gorm:"type:text[]"`
+	RecommendedDatasets []string `gorm:"type:text[]"`
+	RequiredInputs      document `gorm:"type:jsonb"`
+	SuccessCriteria     document `gorm:"type:jsonb"`
+	PassDefinition      string   `gorm:"type:text"`
+	WeeklyRunCount      int      `gorm:"default:0"`
+	MonthlyRunCount     int      `gorm:"default:0"`
+	UsedByPackagesCount int      `gorm:"default:0"`
+	UsedByStacksCount   int      `gorm:"default:0"`
+	AveragePassRate     float64  `gorm:"default:0"`
+	Tags                []string `gorm:"type:text[]"`
+	Owner               document `gorm:"type:jsonb"`
+	Version             string
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+}
+
+func (baselineScenario) TableName() string { return "scenarios" }
+
+type baselineDataset struct {
+	ID                  string `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Name                string `gorm:"uniqueIndex;not null"`
+	Slug                string `gorm:"index"`
+	Description         string
+	DetailedDescription string   `gorm:"type:text"`
+	Type                string   `gorm:"not null"`
+	Modality            string   `gorm:"not null"`
+	Format              string   `gorm:"not null"`
+	License             string   `gorm:"not null"`
+	Tags                []string `gorm:"type:text[]"`
+	WhatsInside         []string `gorm:"type:text[]"`
+	UsageNotes          string   `gorm:"type:text"`
+	SizeGB              float64
+	SamplesCount        int
+	SequencesCount      int
+	Duration            int
+	SupportedScenarios  []string `gorm:"type:text[]"`
+	RoboticsPlatforms   []string `gorm:"type:text[]"`
+	Source              string   `gorm:"not null"`
+	OwnerType           string   `gorm:"not null"`
+	OwnerID             string   `gorm:"not null;index"`
+	OwnerName           string
+	Visibility          string   `gorm:"not null;default:'public'"`
+	PreviewAssets       document `gorm:"type:jsonb"`
+	Schema              document `gorm:"type:jsonb"`
+	DownloadCount       int      `gorm:"default:0"`
+	UsedInRuns          int      `gorm:"default:0"`
+	AvgRating           float64
+	RatingCount         int
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+}
+
+func (baselineDataset) TableName() string { return "datasets" }
+
+type baselineSimulator struct {
+	ID          string `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Name        string `gorm:"uniqueIndex;not null"`
+	Description string
+	Type        string `gorm:"not null"`
+	Version     string
+	Config      string   `gorm:"type:text"`
+	Tags        []string `gorm:"type:text[]"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (baselineSimulator) TableName() string { return "simulators" }
+
+// baseline holds the rows created by loadBaseline
+type baseline struct {
+	repos     []*baselineRepository
+	packages  []*baselinePackage
+	scenarios []*baselineScenario
+	datasets  []*baselineDataset
+}
+
+// loadBaseline creates the schema as the last AutoMigrate release did at
+// startup and loads its seed data, followed by rows exercising the edge cases
+// of the conversion
+func loadBaseline(t *testing.T, db *gorm.DB) baseline {
+	t.Helper()
+	if err := db.Exec(`CREATE EXTENSION IF NOT EXISTS "pgcrypto"`).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&baselineRepository{}, &baselinePackage{}, &baselineScenario{}, &baselineDataset{}, &baselineSimulator{}); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+
+	var b baseline
+	b.repos = []*baselineRepository{
+		{
+			Name: "ros-planning/navigation2", Provider: "github", URL: "https://github.com/ros-planning/navigation2",
+			Description: "ROS 2 Navigation Stack", DefaultBranch: "main", Visibility: "public", SyncStatus: "synced", AutoSync: true,
+			LatestCommit:  `{"hash":"a1b2c3d4e5f6","message":"Add new planner plugin","author":"John Doe","date":"2024-01-01T00:00:00Z","url":"https://github.com/ros-planning/navigation2/commit/a1b2c3d4e5f6"}`,
+			WebhookStatus: "active", Tags: []string{"ros2", "navigation", "autonomous"},
+			Owner: `{"id":"user-001","name":"ros-planning","avatarUrl":"https://avatars.githubusercontent.com/ros-planning"}`,
+		},
+		{
+			Name: "ros-perception/perception_pcl", Provider: "github", URL: "https://github.com/ros-perception/perception_pcl",
+			Description: "PCL (Point Cloud Library) ROS interface", DefaultBranch: "ros2", Visibility: "public", SyncStatus: "synced", AutoSync: true,
+			LatestCommit:  `{"hash":"b2c3d4e5f6a1","message":"Update point cloud filters","author":"Jane Smith","date":"2024-01-02T00:00:00Z","url":"https://github.com/ros-perception/perception_pcl/commit/b2c3d4e5f6a1"}`,
+			WebhookStatus: "active", Tags: []string{"ros2", "perception", "point-cloud"},
+			Owner: `{"id":"user-002","name":"ros-perception","avatarUrl":"https://avatars.githubusercontent.com/ros-perception"}`,
+		},
+	}
+	create(t, db, b.repos)
+
+	const planning = `{"id":"user-001","name":"ros-planning","avatarUrl":"https://avatars.githubusercontent.com/ros-planning"}`
+	b.packages = []*baselinePackage{
+		{
+			Name: "nav2_planner", DisplayName: "Nav2 Planner", Description: "Global path planning server for Nav2",
+			RepoID: b.repos[0].ID, RepoName: b.repos[0].Name, Path: "nav2_planner", Types: []string{"planner", "navigation"},
+			LatestVersion: "1.1.9", Versions: []string{"1.1.9", "1.1.8", "1.1.7"}, Tags: []string{"navigation", "planning", "ros2"},
+			Keywords:         []string{"path-planning", "global-planner", "navigation"},
+			ValidationStatus: `{"lastValidated":"2024-01-01T00:00:00Z","status":"pass","passRate":95.5}`, Owner: planning,
+		},
+		{
+			Name: "nav2_controller", DisplayName: "Nav2 Controller", Description: "Local trajectory planning and control for Nav2",
+			RepoID: b.repos[0].ID, RepoName: b.repos[0].Name, Path: "nav2_controller", Types: []string{"control", "navigation"},
+			LatestVersion: "1.1.9", Versions: []string{"1.1.9", "1.1.8", "1.1.7"}, Tags: []string{"navigation", "control", "ros2"},
+			Keywords:         []string{"trajectory", "controller", "dwa"},
+			ValidationStatus: `{"lastValidated":"2024-01-01T00:00:00Z","status":"pass","passRate":92.3}`, Owner: planning,
+		},
+		{
+			Name: "pcl_ros", DisplayName: "PCL ROS", Description: "Point Cloud Library ROS2 integration",
+			RepoID: b.repos[1].ID, RepoName: b.repos[1].Name, Path: "pcl_ros", Types: []string{"perception", "sensors"},
+			LatestVersion: "2.5.0", Versions: []string{"2.5.0", "2.4.0", "2.3.0"}, Tags: []string{"perception", "point-cloud", "ros2"},
+			Keywords:         []string{"pcl", "3d-vision", "lidar"},
+			ValidationStatus: `{"lastValidated":"2024-01-01T00:00:00Z","status":"pass","passRate":88.7}`,
+			Owner:            `{"id":"user-002","name":"ros-perception","avatarUrl":"https://avatars.githubusercontent.com/ros-perception"}`,
+		},
+	}
+	create(t, db, b.packages)
+
+	b.scenarios = []*baselineScenario{
+		{
+			Name: "Warehouse Navigation Basic", Slug: "warehouse-nav-basic",
+			Description: "Navigate through a basic warehouse environment with static obstacles",
+			Category:    "navigation", Difficulty: "easy", MaintainedBy: "RoboHub", Verified: true,
+			WhatItTests:      []string{"Obstacle avoidance", "Path planning", "Goal reaching"},
+			WhyItMatters:     "Validates basic navigation capabilities in structured environments",
+			RealWorldAnalogs: []string{"Amazon fulfillment center", "Retail warehouse"}, Domain: "indoor",
+			SupportedSimulators: []string{"Gazebo", "CARLA", "Unity"},
+			RequiredInputs:      `[{"name":"start_pose","type":"geometry_msgs/PoseStamped","description":"Starting position"}]`,
+			SuccessCriteria:     `[{"name":"Success Rate","description":"Percentage of successful goal reaches","threshold":">90%","unit":"percentage"}]`,
+			PassDefinition:      "Robot reaches goal without collisions within time limit",
+			Tags:                []string{"navigation", "warehouse", "basic"}, Owner: `{"id":"robohub-001","name":"RoboHub Team"}`, Version: "1.0.0",
+		},
+		{
+			Name: "Urban Autonomous Driving", Slug: "urban-autonomous-driving",
+			Description: "Navigate through urban environment with dynamic obstacles and traffic rules",
+			Category:    "navigation", Difficulty: "hard", MaintainedBy: "Community", Verified: true,
+			WhatItTests:      []string{"Dynamic obstacle avoidance", "Traffic rule compliance", "Lane keeping"},
+			WhyItMatters:     "Tests autonomous vehicle capabilities in complex real-world scenarios",
+			RealWorldAnalogs: []string{"City streets", "Downtown traffic"}, Domain: "urban",
+			SupportedSimulators: []string{"CARLA", "AirSim"},
+			RequiredInputs:      `[{"name":"route","type":"nav_msgs/Path","description":"Planned route"}]`,
+			SuccessCriteria:     `[{"name":"Safety Score","description":"No collisions or violations","threshold":"100%","unit":"percentage"}]`,
+			PassDefinition:      "Complete route safely while following all traffic rules",
+			Tags:                []string{"autonomous-driving", "urban", "advanced"}, Owner: `{"id":"community-001","name":"AV Community"}`, Version: "2.1.0",
+		},
+		{
+			Name: "Object Detection Indoor", Slug: "object-detection-indoor",
+			Description: "Detect and classify objects in indoor environment using camera and lidar",
+			Category:    "perception", Difficulty: "medium", MaintainedBy: "Partner", Verified: true,
+			WhatItTests:      []string{"Object detection accuracy", "Classification performance", "Multi-sensor fusion"},
+			WhyItMatters:     "Validates perception pipeline for indoor manipulation tasks",
+			RealWorldAnalogs: []string{"Home assistance", "Office automation"}, Domain: "indoor",
+			SupportedSimulators: []string{"Gazebo", "Webots"},
+			RequiredInputs:      `[{"name":"sensor_data","type":"sensor_msgs/PointCloud2","description":"3D sensor data"}]`,
+			SuccessCriteria:     `[{"name":"Detection Rate","description":"Percentage of objects detected","threshold":">85%","unit":"percentage"}]`,
+			PassDefinition:      "Detect at least 85% of objects with less than 5% false positives",
+			Tags:                []string{"perception", "object-detection", "indoor"}, Owner: `{"id":"partner-001","name":"TechPartner Inc"}`, Version: "1.5.0",
+		},
+	}
+	create(t, db, b.scenarios)
+
+	b.datasets = []*baselineDataset{
+		{
+			Name: "Warehouse Navigation Dataset v1", Slug: "warehouse-nav-v1",
+			Description: "Indoor warehouse navigation data with lidar and camera feeds",
+			Type:        "robotics", Modality: "multimodal", Format: "rosbag2", License: "MIT",
+			Tags:        []string{"warehouse", "navigation", "indoor"},
+			WhatsInside: []string{"Lidar scans", "RGB camera images", "Odometry", "Ground truth poses"},
+			SizeGB:      15.5, SamplesCount: 10000, Duration: 3600, Source: "uploaded",
+			OwnerType: "organization", OwnerID: "org-001", OwnerName: "RoboHub Labs", Visibility: "public",
+		},
+		{
+			Name: "Urban Driving CARLA", Slug: "urban-driving-carla",
+			Description: "Synthetic urban driving data generated in CARLA simulator",
+			Type:        "autonomous-driving", Modality: "multimodal", Format: "parquet", License: "CC-BY",
+			Tags:        []string{"autonomous-driving", "urban", "synthetic"},
+			WhatsInside: []string{"RGB cameras", "Depth images", "Semantic segmentation", "Vehicle telemetry"},
+			SizeGB:      50.2, SamplesCount: 25000, Duration: 7200, Source: "partner",
+			OwnerType: "organization", OwnerID: "org-002", OwnerName: "CARLA Team", Visibility: "public",
+		},
+		{
+			Name: "Indoor Object Recognition", Slug: "indoor-object-recognition",
+			Description: "Labeled indoor objects dataset for perception tasks",
+			Type:        "indoor-mapping", Modality: "camera", Format: "hdf5", License: "Apache-2.0",
+			Tags:        []string{"perception", "object-detection", "indoor"},
+			WhatsInside: []string{"Labeled RGB images", "Bounding boxes", "Object classes", "Depth maps"},
+			SizeGB:      8.3, SamplesCount: 5000, Duration: 1800, Source: "uploaded",
+			OwnerType: "user", OwnerID: "user-003", OwnerName: "DataScience Team", Visibility: "public",
+		},
+	}
+	create(t, db, b.datasets)
+
+	create(t, db, []*baselineSimulator{
+		{Name: "Gazebo Classic", Description: "Gazebo Classic simulation environment for robotics", Type: "gazebo", Version: "11.12.0",
+			Config: `{"physics_engine": "ODE", "render_mode": "headless", "real_time_factor": 1.0}`, Tags: []string{"gazebo", "ros", "simulation"}},
+		{Name: "CARLA Simulator", Description: "Open-source simulator for autonomous driving research", Type: "carla", Version: "0.9.15",
+			Config: `{"render_quality": "Epic", "weather": "ClearNoon", "fixed_delta_seconds": 0.05}`, Tags: []string{"carla", "autonomous-driving", "urban"}},
+		{Name: "Unity Robotics Hub", Description: "Unity-based robotics simulation platform", Type: "unity", Version: "2023.1.0",
+			Config: `{"graphics_api": "Vulkan", "physics_timestep": 0.02, "ros_bridge": true}`, Tags: []string{"unity", "robotics", "simulation"}},
+	})
+
+	for _, repo := range b.repos {
+		exec(t, db, "UPDATE repositories SET package_count = (SELECT count(*) FROM packages WHERE repo_id = ?) WHERE id = ?", repo.ID, repo.ID)
+	}
+
+	// References in another case, to missing rows and repeated ones
+	edge := []*baselinePackage{
+		{Name: "dangling", RepoID: "8d7bb8a4-6f3c-4a5e-9d25-2f0c3a1b7e11", Owner: planning},
+		{Name: "upper_case", RepoID: strings.ToUpper(b.repos[1].ID), Owner: `{"id":"user-002","name":"ros-perception"}`,
+			LastRun: document(`{"status":"pass","runAt":"2024-01-01T00:00:00Z","scenarioId":"` + strings.ToUpper(b.scenarios[1].ID) + `"}`)},
+		{Name: "ownerless", Owner: `{}`},
+	}
+	create(t, db, edge)
+	b.packages = append(b.packages, edge...)
+	exec(t, db, "UPDATE scenarios SET recommended_datasets = ? WHERE id = ?",
+		[]string{strings.ToUpper(b.datasets[0].ID), "missing", b.datasets[1].ID, b.datasets[0].ID}, b.scenarios[0].ID)
+	exec(t, db, "UPDATE datasets SET supported_scenarios = ? WHERE id = ?",
+		[]string{b.scenarios[0].ID, b.scenarios[2].ID}, b.datasets[0].ID)
+	return b
+}
+
+func create[T any](t *testing.T, db *gorm.DB, rows []*T) {
+	t.Helper()
+	for _, row := range rows {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatalf("failed to create %T: %v", row, err)
+		}
+	}
+}
+
+func exec(t *testing.T, db *gorm.DB, sql string, args ...any) {
+	t.Helper()
+	if err := db.Exec(sql, args...).Error; err != nil {
+		t.Fatalf("%s: %v", sql, err)
+	}
+}
+
+// column returns the first column of the rows of a query
+func column(t *testing.T, db *gorm.DB, sql string, args ...any) []string {
+	t.Helper()
+	var values []string
+	if err := db.Raw(sql, args...).Scan(&values).Error; err != nil {
+		t.Fatalf("%s: %v", sql, err)
+	}
+	return values
+}
+
+// schemaObjects lists what a database's schema consists of. Kept legacy
+// columns are left out.
+var schemaObjects = map[string]string{
+	"columns": `SELECT format('%s.%s %s nullable=%s default=%s', table_name, column_name,
+		CASE WHEN data_type = 'ARRAY' THEN udt_name ELSE data_type END, is_nullable, coalesce(column_default, ''))
+		FROM information_schema.columns
+		WHERE table_schema = current_schema() AND column_name NOT LIKE 'legacy\_%'
+		ORDER BY 1`,
+	"indexes": `SELECT indexdef FROM pg_indexes WHERE schemaname = current_schema() ORDER BY 1`,
+	"constraints": `SELECT format('%s %s %s', conrelid::regclass, conname, pg_get_constraintdef(oid))
+		FROM pg_constraint WHERE connamespace = current_schema()::regnamespace ORDER BY 1`,
+	"triggers": `SELECT format('%s %s', tgrelid::regclass, tgname) FROM pg_trigger WHERE NOT tgisinternal ORDER BY 1`,
+}
+
+func TestUpConvertsAutoMigrateDatabase(t *testing.T) {
+	fresh := dbtest.Migrated(t)
+	legacy := dbtest.New(t)
+	b := loadBaseline(t, legacy)
+
+	migrator, err := database.NewMigrator(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+
+	t.Run("schema", func(t *testing.T) {
+		for kind, sql := range schemaObjects {
+			want, got := column(t, fresh, sql), column(t, legacy, sql)
+			for _, missing := range subtract(want, got) {
+				t.Errorf("converted database lacks %s: %s", kind, missing)
+			}
+			for _, extra := range subtract(got, want) {
+				t.Errorf("converted database has extra %s: %s", kind, extra)
+			}
+		}
+	})
+
+	t.Run("legacy columns", func(t *testing.T) {
+		got := column(t, legacy, `SELECT table_name || '.' || column_name FROM information_schema.columns
+			WHERE table_schema = current_schema() AND column_name LIKE 'legacy\_%' ORDER BY 1`)
+		want := []string{
+			"datasets.legacy_owner_name",
+			"datasets.legacy_supported_scenarios",
+			"packages.legacy_owner",
+			"packages.legacy_repo_id",
+			"repositories.legacy_owner",
+			"scenarios.legacy_owner",
+			"scenarios.legacy_recommended_datasets",
+		}
+		assertEqual(t, "legacy columns", got, want)
+
+		assertEqual(t, "legacy repo IDs",
+			column(t, legacy, "SELECT legacy_repo_id FROM packages WHERE name IN ('dangling', 'upper_case') ORDER BY name"),
+			[]string{b.packages[3].RepoID, b.packages[4].RepoID})
+		assertEqual(t, "legacy owners",
+			column(t, legacy, "SELECT legacy_owner->>'name' FROM repositories ORDER BY name"),
+			[]string{"ros-perception", "ros-planning"})
+		assertEqual(t, "legacy owner names",
+			column(t, legacy, "SELECT legacy_owner_name FROM datasets ORDER BY name"),
+			[]string{"DataScience Team", "CARLA Team", "RoboHub Labs"})
+	})
+
+	t.Run("rows", func(t *testing.T) {
+		for table, want := range map[string]string{
+			"repositories": "2", "packages": "6", "scenarios": "3", "datasets": "3", "simulators": "3",
+		} {
+			assertEqual(t, table, column(t, legacy, "SELECT count(*)::text FROM "+table), []string{want})
+		}
+
+		assertEqual(t, "organizations",
+			column(t, legacy, "SELECT name || ' ' || display_name FROM organizations ORDER BY name"),
+			[]string{
+				"av-community AV Community",
+				"carla-team CARLA Team",
+				"datascience-team DataScience Team",
+				"robohub-labs RoboHub Labs",
+				"robohub-team RoboHub Team",
+				"ros-perception ros-perception",
+				"ros-planning ros-planning",
+				"techpartner-inc TechPartner Inc",
+			})
+
+		owners := `SELECT t.name || ' ' || coalesce(t.owner_type, '') || ' ' || coalesce(o.name, '')
+			FROM %s t LEFT JOIN organizations o ON o.id::text = t.owner_id ORDER BY t.name`
+		assertEqual(t, "repository owners", column(t, legacy, strings.Replace(owners, "%s", "repositories", 1)), []string{
+			"ros-perception/perception_pcl organization ros-perception",
+			"ros-planning/navigation2 organization ros-planning",
+		})
+		assertEqual(t, "package owners", column(t, legacy, strings.Replace(owners, "%s", "packages", 1)), []string{
+			"dangling organization ros-planning",
+			"nav2_controller organization ros-planning",
+			"nav2_planner organization ros-planning",
+			"ownerless  ",
+			"pcl_ros organization ros-perception",
+			"upper_case organization ros-perception",
+		})
+		assertEqual(t, "scenario owners", column(t, legacy, strings.Replace(owners, "%s", "scenarios", 1)), []string{
+			"Object Detection Indoor organization techpartner-inc",
+			"Urban Autonomous Driving organization av-community",
+			"Warehouse Navigation Basic organization robohub-team",
+		})
+		assertEqual(t, "dataset owners", column(t, legacy, strings.Replace(owners, "%s", "datasets", 1)), []string{
+			"Indoor Object Recognition organization datascience-team",
+			"Urban Driving CARLA organization carla-team",
+			"Warehouse Navigation Dataset v1 organization robohub-labs",
+		})
+
+		assertEqual(t, "repo IDs",
+			column(t, legacy, "SELECT name || ' ' || coalesce(repo_id::text, '') FROM packages ORDER BY name"),
+			[]string{
+				"dangling ",
+				"nav2_controller " + b.repos[0].ID,
+				"nav2_planner " + b.repos[0].ID,
+				"ownerless ",
+				"pcl_ros " + b.repos[1].ID,
+				"upper_case " + b.repos[1].ID,
+			})
+		assertEqual(t, "package counts",
+			column(t, legacy, "SELECT name || ' ' || package_count FROM repositories ORDER BY name"),
+			[]string{"ros-perception/perception_pcl 1", "ros-planning/navigation2 2"})
+		assertEqual(t, "last run scenarios",
+			column(t, legacy, "SELECT name || ' ' || last_run_scenario_id FROM packages WHERE last_run_scenario_id IS NOT NULL"),
+			[]string{"upper_case " + b.scenarios[1].ID})
+
+		assertEqual(t, "recommended datasets",
+			column(t, legacy, "SELECT scenario_id || ' ' || dataset_id || ' ' || position FROM scenario_recommended_datasets ORDER BY position"),
+			[]string{
+				b.scenarios[0].ID + " " + b.datasets[0].ID + " 0",
+				b.scenarios[0].ID + " " + b.datasets[1].ID + " 2",
+			})
+		assertEqual(t, "supported scenarios",
+			column(t, legacy, "SELECT dataset_id || ' ' || scenario_id || ' ' || position FROM dataset_supported_scenarios ORDER BY position"),
+			[]string{
+				b.datasets[0].ID + " " + b.scenarios[0].ID + " 0",
+				b.datasets[0].ID + " " + b.scenarios[2].ID + " 1",
+			})
+	})
+}
+
+// subtract returns the elements of a missing from b
+func subtract(a, b []string) []string {
+	seen := make(map[string]int, len(b))
+	for _, v := range b {
+		seen[v]++
+	}
+	var missing []string
+	for _, v := range a {
+		if seen[v] > 0 {
+			seen[v]--
+			continue
+		}
+		missing = append(missing, v)
+	}
+	return missing
+}
+
+func assertEqual(t *testing.T, what string, got, want []string) {
+	t.Helper()
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("%s:\ngot  %q\nwant %q", what, got, want)
+	}
+}
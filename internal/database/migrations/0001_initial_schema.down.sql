@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS
+    dataset_supported_scenarios,
+    scenario_recommended_datasets,
+    packages,
+    simulators,
+    datasets,
+    scenarios,
+    repositories,
+    api_keys,
+    memberships,
+    organizations,
+    users;
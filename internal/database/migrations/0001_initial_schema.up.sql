@@ -0,0 +1,380 @@
+-- Catalog, identity and API key tables.
+--
+-- Every statement tolerates existing objects, so that databases created by
+-- AutoMigrate in earlier releases are adopted. Tables still in the shape of
+-- an older release are converted first, as those releases did at startup;
+-- each conversion checks for the legacy columns and does nothing without them.
+-- Converted columns are kept as legacy_<column> rather than dropped.
+
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+
+CREATE TABLE IF NOT EXISTS users (
+    id         uuid DEFAULT gen_random_uuid(),
+    subject    text NOT NULL,
+    name       text,
+    email      text,
+    avatar_url text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_subject ON users (subject);
+
+CREATE TABLE IF NOT EXISTS organizations (
+    id           uuid DEFAULT gen_random_uuid(),
+    name         text NOT NULL,
+    display_name text,
+    description  text,
+    avatar_url   text,
+    created_at   timestamptz,
+    updated_at   timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_organizations_name ON organizations (name);
+
+CREATE TABLE IF NOT EXISTS memberships (
+    organization_id uuid,
+    user_id         uuid,
+    role            text NOT NULL,
+    created_at      timestamptz,
+    updated_at      timestamptz,
+    PRIMARY KEY (organization_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS idx_memberships_user_id ON memberships (user_id);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id           uuid DEFAULT gen_random_uuid(),
+    name         text NOT NULL,
+    prefix       text NOT NULL,
+    hash         text NOT NULL,
+    subject      text NOT NULL,
+    last_used_at timestamptz,
+    expires_at   timestamptz,
+    revoked_at   timestamptz,
+    created_at   timestamptz,
+    updated_at   timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_prefix ON api_keys (prefix);
+CREATE INDEX IF NOT EXISTS idx_api_keys_subject ON api_keys (subject);
+
+-- Packages referenced their repository by a text column. References to
+-- missing repositories cannot become foreign keys and are left out of the new
+-- uuid column; the index is recreated on it below.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_schema = current_schema() AND table_name = 'packages'
+               AND column_name = 'repo_id' AND data_type = 'text') THEN
+        DROP INDEX IF EXISTS idx_packages_repo_id;
+        ALTER TABLE packages RENAME COLUMN repo_id TO legacy_repo_id;
+        ALTER TABLE packages ADD COLUMN repo_id uuid;
+        UPDATE packages SET repo_id = repositories.id
+        FROM repositories
+        WHERE repositories.id::text = lower(packages.legacy_repo_id);
+    END IF;
+END
+$$;
+
+-- Columns of owner references, soft deletes and revisions
+ALTER TABLE IF EXISTS repositories
+    ADD COLUMN IF NOT EXISTS owner_type text,
+    ADD COLUMN IF NOT EXISTS owner_id   text,
+    ADD COLUMN IF NOT EXISTS revision   bigint NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+ALTER TABLE IF EXISTS packages
+    ADD COLUMN IF NOT EXISTS owner_type           text,
+    ADD COLUMN IF NOT EXISTS owner_id             text,
+    ADD COLUMN IF NOT EXISTS last_run_scenario_id uuid,
+    ADD COLUMN IF NOT EXISTS revision             bigint NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS deleted_at           timestamptz;
+ALTER TABLE IF EXISTS scenarios
+    ADD COLUMN IF NOT EXISTS owner_type text,
+    ADD COLUMN IF NOT EXISTS owner_id   text,
+    ADD COLUMN IF NOT EXISTS revision   bigint NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+ALTER TABLE IF EXISTS datasets
+    ADD COLUMN IF NOT EXISTS revision   bigint NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+ALTER TABLE IF EXISTS simulators
+    ADD COLUMN IF NOT EXISTS revision   bigint NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+
+-- Owner details were copied into the rows: a JSONB owner on packages,
+-- repositories and scenarios and an owner_name on datasets. The legacy owner
+-- IDs never referred to real principals, so each distinct owner name becomes
+-- an organization without members, which platform admins can then assign
+-- maintainers to. Dataset owner references that resolve are kept.
+DO $$
+DECLARE
+    legacy record;
+    slug   text;
+    unresolved CONSTANT text := $sql$(t.owner_id IS NULL OR t.owner_id = '' OR (
+        t.owner_id NOT IN (SELECT id::text FROM users) AND
+        t.owner_id NOT IN (SELECT id::text FROM organizations)))$sql$;
+BEGIN
+    FOR legacy IN SELECT * FROM (VALUES
+        ('packages', 'owner', $sql$owner->>'name'$sql$, $sql$owner->>'avatarUrl'$sql$),
+        ('repositories', 'owner', $sql$owner->>'name'$sql$, $sql$owner->>'avatarUrl'$sql$),
+        ('scenarios', 'owner', $sql$owner->>'name'$sql$, $sql$owner->>'avatarUrl'$sql$),
+        ('datasets', 'owner_name', 'owner_name', $sql$''$sql$)
+    ) AS l (tbl, col, name, avatar)
+    LOOP
+        CONTINUE WHEN NOT EXISTS (SELECT 1 FROM information_schema.columns
+            WHERE table_schema = current_schema() AND table_name = legacy.tbl AND column_name = legacy.col);
+        slug := format($sql$trim(both '-' from regexp_replace(lower(%s), '[^a-z0-9]+', '-', 'g'))$sql$, legacy.name);
+        EXECUTE format($sql$
+            INSERT INTO organizations (name, display_name, avatar_url, created_at, updated_at)
+            SELECT DISTINCT ON (%1$s) %1$s, %2$s, coalesce(%3$s, ''), now(), now()
+            FROM %4$I t
+            WHERE %5$s AND %1$s <> ''
+            ON CONFLICT (name) DO NOTHING$sql$,
+            slug, legacy.name, legacy.avatar, legacy.tbl, unresolved);
+        EXECUTE format($sql$
+            UPDATE %1$I t SET owner_type = 'organization', owner_id = o.id::text
+            FROM organizations o
+            WHERE %2$s AND o.name = %3$s$sql$,
+            legacy.tbl, unresolved, slug);
+        EXECUTE format('ALTER TABLE %I RENAME COLUMN %I TO %I', legacy.tbl, legacy.col, 'legacy_' || legacy.col);
+    END LOOP;
+END
+$$;
+
+-- Names were unique among all rows before soft deletes. They are unique among
+-- live rows now, so that a name is free again once its entity is in the trash.
+DROP INDEX IF EXISTS idx_repositories_name;
+DROP INDEX IF EXISTS idx_packages_name;
+DROP INDEX IF EXISTS idx_scenarios_name;
+DROP INDEX IF EXISTS idx_datasets_name;
+DROP INDEX IF EXISTS idx_simulators_name;
+
+CREATE TABLE IF NOT EXISTS repositories (
+    id             uuid DEFAULT gen_random_uuid(),
+    name           text NOT NULL,
+    provider       text NOT NULL,
+    url            text NOT NULL,
+    description    text,
+    default_branch text NOT NULL DEFAULT 'main',
+    visibility     text NOT NULL DEFAULT 'public',
+    last_synced    timestamptz,
+    sync_status    text NOT NULL DEFAULT 'needs_attention',
+    auto_sync      boolean DEFAULT false,
+    latest_commit  jsonb,
+    webhook_status text DEFAULT 'inactive',
+    webhook_id     text,
+    tags           text[],
+    package_count  bigint DEFAULT 0,
+    owner_type     text,
+    owner_id       text,
+    revision       bigint NOT NULL DEFAULT 1,
+    created_at     timestamptz,
+    updated_at     timestamptz,
+    deleted_at     timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_repositories_name_active ON repositories (name) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_repositories_owner ON repositories (owner_type, owner_id);
+CREATE INDEX IF NOT EXISTS idx_repositories_deleted_at ON repositories (deleted_at);
+
+CREATE TABLE IF NOT EXISTS scenarios (
+    id                     uuid DEFAULT gen_random_uuid(),
+    name                   text NOT NULL,
+    slug                   text,
+    description            text,
+    detailed_description   text,
+    category               text NOT NULL,
+    difficulty             text NOT NULL,
+    maintained_by          text NOT NULL,
+    verified               boolean DEFAULT false,
+    what_it_tests          text[],
+    why_it_matters         text,
+    real_world_analogs     text[],
+    domain                 text,
+    supported_simulators   text[],
+    required_inputs        jsonb,
+    success_criteria       jsonb,
+    pass_definition        text,
+    weekly_run_count       bigint DEFAULT 0,
+    monthly_run_count      bigint DEFAULT 0,
+    used_by_packages_count bigint DEFAULT 0,
+    used_by_stacks_count   bigint DEFAULT 0,
+    average_pass_rate      decimal DEFAULT 0,
+    tags                   text[],
+    owner_type             text,
+    owner_id               text,
+    version                text,
+    revision               bigint NOT NULL DEFAULT 1,
+    created_at             timestamptz,
+    updated_at             timestamptz,
+    deleted_at             timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_scenarios_name_active ON scenarios (name) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_scenarios_slug ON scenarios (slug);
+CREATE INDEX IF NOT EXISTS idx_scenarios_owner ON scenarios (owner_type, owner_id);
+CREATE INDEX IF NOT EXISTS idx_scenarios_deleted_at ON scenarios (deleted_at);
+
+CREATE TABLE IF NOT EXISTS datasets (
+    id                   uuid DEFAULT gen_random_uuid(),
+    name                 text NOT NULL,
+    slug                 text,
+    description          text,
+    detailed_description text,
+    type                 text NOT NULL,
+    modality             text NOT NULL,
+    format               text NOT NULL,
+    license              text NOT NULL,
+    tags                 text[],
+    whats_inside         text[],
+    usage_notes          text,
+    size_gb              decimal,
+    samples_count        bigint,
+    sequences_count      bigint,
+    duration             bigint,
+    robotics_platforms   text[],
+    source               text NOT NULL,
+    owner_type           text NOT NULL,
+    owner_id             text NOT NULL,
+    visibility           text NOT NULL DEFAULT 'public',
+    preview_assets       jsonb,
+    schema               jsonb,
+    download_count       bigint DEFAULT 0,
+    used_in_runs         bigint DEFAULT 0,
+    avg_rating           decimal,
+    rating_count         bigint,
+    revision             bigint NOT NULL DEFAULT 1,
+    created_at           timestamptz,
+    updated_at           timestamptz,
+    deleted_at           timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_datasets_name_active ON datasets (name) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_datasets_slug ON datasets (slug);
+CREATE INDEX IF NOT EXISTS idx_datasets_owner_id ON datasets (owner_id);
+CREATE INDEX IF NOT EXISTS idx_datasets_deleted_at ON datasets (deleted_at);
+
+CREATE TABLE IF NOT EXISTS packages (
+    id                        uuid DEFAULT gen_random_uuid(),
+    name                      text NOT NULL,
+    display_name              text,
+    description               text,
+    documentation             text,
+    repo_id                   uuid,
+    repo_name                 text,
+    path                      text,
+    types                     text[],
+    latest_version            text,
+    versions                  text[],
+    tags                      text[],
+    keywords                  text[],
+    validation_status         jsonb,
+    linked_scenarios_count    bigint DEFAULT 0,
+    linked_datasets_count     bigint DEFAULT 0,
+    used_in_collections_count bigint DEFAULT 0,
+    owner_type                text,
+    owner_id                  text,
+    last_run                  jsonb,
+    last_run_scenario_id      uuid,
+    license                   text,
+    dependencies              jsonb,
+    revision                  bigint NOT NULL DEFAULT 1,
+    created_at                timestamptz,
+    updated_at                timestamptz,
+    deleted_at                timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_packages_name_active ON packages (name) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_packages_repo_id ON packages (repo_id);
+CREATE INDEX IF NOT EXISTS idx_packages_last_run_scenario_id ON packages (last_run_scenario_id);
+CREATE INDEX IF NOT EXISTS idx_packages_owner ON packages (owner_type, owner_id);
+CREATE INDEX IF NOT EXISTS idx_packages_deleted_at ON packages (deleted_at);
+
+CREATE TABLE IF NOT EXISTS scenario_recommended_datasets (
+    scenario_id uuid,
+    dataset_id  uuid,
+    position    bigint NOT NULL,
+    PRIMARY KEY (scenario_id, dataset_id)
+);
+CREATE INDEX IF NOT EXISTS idx_scenario_recommended_datasets_dataset_id ON scenario_recommended_datasets (dataset_id);
+
+CREATE TABLE IF NOT EXISTS dataset_supported_scenarios (
+    dataset_id  uuid,
+    scenario_id uuid,
+    position    bigint NOT NULL,
+    PRIMARY KEY (dataset_id, scenario_id)
+);
+CREATE INDEX IF NOT EXISTS idx_dataset_supported_scenarios_scenario_id ON dataset_supported_scenarios (scenario_id);
+
+CREATE TABLE IF NOT EXISTS simulators (
+    id          uuid DEFAULT gen_random_uuid(),
+    name        text NOT NULL,
+    description text,
+    type        text NOT NULL,
+    version     text,
+    config      text,
+    tags        text[],
+    revision    bigint NOT NULL DEFAULT 1,
+    created_at  timestamptz,
+    updated_at  timestamptz,
+    deleted_at  timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_simulators_name_active ON simulators (name) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_simulators_deleted_at ON simulators (deleted_at);
+
+-- Scenarios and datasets listed each other by ID in text[] columns before
+-- the join tables. Links to missing entities are dropped.
+DO $$
+DECLARE
+    legacy record;
+BEGIN
+    FOR legacy IN SELECT * FROM (VALUES
+        ('scenarios', 'recommended_datasets', 'scenario_recommended_datasets', 'scenario_id', 'dataset_id', 'datasets'),
+        ('datasets', 'supported_scenarios', 'dataset_supported_scenarios', 'dataset_id', 'scenario_id', 'scenarios')
+    ) AS l (tbl, col, join_table, source_col, target_col, target)
+    LOOP
+        CONTINUE WHEN NOT EXISTS (SELECT 1 FROM information_schema.columns
+            WHERE table_schema = current_schema() AND table_name = legacy.tbl AND column_name = legacy.col);
+        EXECUTE format($sql$
+            INSERT INTO %3$I (%4$I, %5$I, position)
+            SELECT source.id, target.id, MIN(link.ord) - 1
+            FROM %1$I source
+            CROSS JOIN LATERAL unnest(source.%2$I) WITH ORDINALITY AS link (ref, ord)
+            JOIN %6$I target ON target.id::text = lower(link.ref)
+            GROUP BY source.id, target.id
+            ON CONFLICT DO NOTHING$sql$,
+            legacy.tbl, legacy.col, legacy.join_table, legacy.source_col, legacy.target_col, legacy.target);
+        EXECUTE format('ALTER TABLE %I RENAME COLUMN %I TO %I', legacy.tbl, legacy.col, 'legacy_' || legacy.col);
+    END LOOP;
+END
+$$;
+
+-- The scenario of the last run was only kept in the last_run document
+UPDATE packages SET last_run_scenario_id = scenarios.id
+FROM scenarios
+WHERE packages.last_run_scenario_id IS NULL
+AND scenarios.id::text = lower(packages.last_run->>'scenarioId');
+
+-- ADD CONSTRAINT has no IF NOT EXISTS
+DO $$
+DECLARE
+    fk record;
+BEGIN
+    FOR fk IN SELECT * FROM (VALUES
+        ('memberships', 'fk_memberships_organization', 'organization_id', 'organizations', 'CASCADE'),
+        ('memberships', 'fk_memberships_user', 'user_id', 'users', 'CASCADE'),
+        ('packages', 'fk_packages_repository', 'repo_id', 'repositories', 'CASCADE'),
+        ('packages', 'fk_packages_last_run_scenario', 'last_run_scenario_id', 'scenarios', 'SET NULL'),
+        ('scenario_recommended_datasets', 'fk_scenario_recommended_datasets_scenario', 'scenario_id', 'scenarios', 'CASCADE'),
+        ('scenario_recommended_datasets', 'fk_scenario_recommended_datasets_dataset', 'dataset_id', 'datasets', 'CASCADE'),
+        ('dataset_supported_scenarios', 'fk_dataset_supported_scenarios_dataset', 'dataset_id', 'datasets', 'CASCADE'),
+        ('dataset_supported_scenarios', 'fk_dataset_supported_scenarios_scenario', 'scenario_id', 'scenarios', 'CASCADE')
+    ) AS t (tbl, name, col, ref, on_delete)
+    LOOP
+        IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = fk.name) THEN
+            EXECUTE format('ALTER TABLE %I ADD CONSTRAINT %I FOREIGN KEY (%I) REFERENCES %I (id) ON DELETE %s',
+                fk.tbl, fk.name, fk.col, fk.ref, fk.on_delete);
+        END IF;
+    END LOOP;
+END
+$$;
@@ -0,0 +1,6 @@
+ALTER TABLE repositories DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE datasets DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE scenarios DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE packages DROP COLUMN IF EXISTS search_vector;
+
+DROP FUNCTION IF EXISTS robohub_text_array_to_string(text[]);
@@ -0,0 +1,35 @@
+-- Weighted full-text documents of the searchable tables: weight A is the
+-- name, B the prose, C tags and keywords.
+
+-- array_to_string is only STABLE, which generated columns reject
+CREATE OR REPLACE FUNCTION robohub_text_array_to_string(text[]) RETURNS text
+LANGUAGE sql IMMUTABLE PARALLEL SAFE
+AS $$ SELECT coalesce(array_to_string($1, ' '), '') $$;
+
+ALTER TABLE packages ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', coalesce(name, '') || ' ' || coalesce(display_name, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'B') ||
+    setweight(to_tsvector('english', robohub_text_array_to_string(tags) || ' ' || robohub_text_array_to_string(keywords)), 'C')
+) STORED;
+CREATE INDEX IF NOT EXISTS idx_packages_search_vector ON packages USING GIN (search_vector);
+
+ALTER TABLE scenarios ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'B') ||
+    setweight(to_tsvector('english', robohub_text_array_to_string(tags) || ' ' || coalesce(category, '') || ' ' || coalesce(domain, '')), 'C')
+) STORED;
+CREATE INDEX IF NOT EXISTS idx_scenarios_search_vector ON scenarios USING GIN (search_vector);
+
+ALTER TABLE datasets ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'B') ||
+    setweight(to_tsvector('english', robohub_text_array_to_string(tags) || ' ' || coalesce(modality, '') || ' ' || coalesce(format, '')), 'C')
+) STORED;
+CREATE INDEX IF NOT EXISTS idx_datasets_search_vector ON datasets USING GIN (search_vector);
+
+ALTER TABLE repositories ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', coalesce(name, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'B') ||
+    setweight(to_tsvector('english', robohub_text_array_to_string(tags)), 'C')
+) STORED;
+CREATE INDEX IF NOT EXISTS idx_repositories_search_vector ON repositories USING GIN (search_vector);
@@ -0,0 +1,8 @@
+-- Token buckets shared by replicas when RATE_LIMIT_STORE=postgres. Losing
+-- them in a crash only resets the limits, so the table skips the WAL.
+CREATE UNLOGGED TABLE IF NOT EXISTS rate_limit_buckets (
+    key        text PRIMARY KEY,
+    tokens     double precision NOT NULL,
+    allowed    boolean NOT NULL,
+    updated_at timestamptz NOT NULL
+);
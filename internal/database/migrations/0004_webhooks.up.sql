@@ -0,0 +1,40 @@
+-- Webhook subscriptions and their delivery log (API_CONTRACT.md §9).
+
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id              uuid DEFAULT gen_random_uuid(),
+    url             text NOT NULL,
+    description     text,
+    secret          text NOT NULL,
+    events          text[],
+    active          boolean NOT NULL DEFAULT true,
+    failing_since   timestamptz,
+    disabled_at     timestamptz,
+    disabled_reason text,
+    created_by      text NOT NULL,
+    created_at      timestamptz,
+    updated_at      timestamptz,
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id              uuid DEFAULT gen_random_uuid(),
+    subscription_id uuid NOT NULL REFERENCES webhook_subscriptions (id) ON DELETE CASCADE,
+    event_id        uuid NOT NULL,
+    event_type      text NOT NULL,
+    event           jsonb NOT NULL,
+    status          text NOT NULL,
+    attempt         integer NOT NULL DEFAULT 0,
+    max_retries     integer NOT NULL,
+    next_attempt_at timestamptz NOT NULL,
+    last_attempt_at timestamptz,
+    response_status integer,
+    last_error      text,
+    delivered_at    timestamptz,
+    redelivery_of   uuid,
+    created_at      timestamptz,
+    updated_at      timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription_id ON webhook_deliveries (subscription_id, created_at DESC, id DESC);
+-- Only pending deliveries are polled, so the index stays small
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS event_consumers;
+DROP TABLE IF EXISTS events;
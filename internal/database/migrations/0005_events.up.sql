@@ -0,0 +1,27 @@
+-- Outbox of domain events (pkg/event) and the position of each subscriber.
+--
+-- Events are ordered by the ID of the transaction that recorded them, then by
+-- sequence, so that readers can skip the transactions still in progress
+-- without missing their events once they commit.
+
+CREATE TABLE IF NOT EXISTS events (
+    id             uuid,
+    type           text NOT NULL,
+    entity_type    text NOT NULL,
+    entity_id      text NOT NULL,
+    occurred_at    timestamptz NOT NULL,
+    data           jsonb,
+    transaction_id bigint NOT NULL DEFAULT pg_current_xact_id()::text::bigint,
+    sequence       bigserial,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_events_position ON events (transaction_id, sequence);
+CREATE INDEX IF NOT EXISTS idx_events_occurred_at ON events (occurred_at);
+
+CREATE TABLE IF NOT EXISTS event_consumers (
+    name           text,
+    transaction_id bigint NOT NULL,
+    sequence       bigint NOT NULL,
+    updated_at     timestamptz,
+    PRIMARY KEY (name)
+);
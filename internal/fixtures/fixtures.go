@@ -0,0 +1,411 @@
+// Package fixtures loads catalog entities from a directory of fixture files.
+//
+// A directory holds one file per kind, named organizations, repositories,
+// scenarios, datasets, packages or simulators with a .yaml, .yml or .json
+// extension. Each file is a list of entities in their API representation.
+// References between entities are given by name, with these fixture-only
+// fields:
+//
+//   - owner: name of the organization owning a repository, scenario, dataset or package
+//   - repo: name of the repository of a package
+//   - lastRunScenario: name of the scenario of the last run of a package
+//   - recommendedDatasets: names of the datasets recommended by a scenario
+//   - supportedScenarios: names of the scenarios supported by a dataset
+//
+// Loading is idempotent: entities are matched by name, and those that already
+// exist are left unchanged.
+package fixtures
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+
+	"robohub-inventory/internal/tracing"
+	"robohub-inventory/pkg/auth"
+	"robohub-inventory/pkg/counter"
+	"robohub-inventory/pkg/dataset"
+	"robohub-inventory/pkg/identity"
+	pkg "robohub-inventory/pkg/package"
+	"robohub-inventory/pkg/repository"
+	"robohub-inventory/pkg/scenario"
+	"robohub-inventory/pkg/simulator"
+	"robohub-inventory/pkg/store"
+)
+
+// Kinds lists the fixture files in load order, so that references resolve
+// to entities loaded before
+var Kinds = []string{"organizations", "repositories", "scenarios", "datasets", "packages", "simulators"}
+
+var extensions = []string{".yaml", ".yml", ".json"}
+
+// Count reports the entities of one kind found in the fixtures
+type Count struct {
+	Kind     string `json:"kind"`
+	Created  int    `json:"created"`
+	Existing int    `json:"existing"`
+}
+
+// Result reports the entities of each kind, in load order
+type Result struct {
+	Counts []Count `json:"counts"`
+}
+
+func (r *Result) String() string {
+	var buf bytes.Buffer
+	for i, c := range r.Counts {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		fmt.Fprintf(&buf, "%s: %d created, %d existing", c.Kind, c.Created, c.Existing)
+	}
+	return buf.String()
+}
+
+// record is one entity of a fixture file
+type record map[string]interface{}
+
+// links are the scenario-dataset references of a created entity, resolved
+// once both kinds are loaded
+type links struct {
+	table, name string // The linking entity
+	targetTable string
+	targets     []string
+	create      func(id, targetID string, position int) interface{}
+}
+
+// loader creates the entities of one directory in a transaction
+type loader struct {
+	tx      *gorm.DB
+	dir     string
+	pending []links
+	result  Result
+}
+
+var tracer = otel.Tracer("robohub-inventory/internal/fixtures")
+
+// Load creates the entities of the fixture files in dir that do not exist
+// yet, in one transaction, and recomputes the relationship counters
+func Load(ctx context.Context, db *gorm.DB, dir string) (result *Result, err error) {
+	ctx, span := tracer.Start(ctx, "fixtures.Load", trace.WithAttributes(attribute.String("dir", dir)))
+	defer func() { tracing.End(span, err) }()
+
+	err = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		l := &loader{tx: tx, dir: dir}
+		for _, kind := range Kinds {
+			records, err := l.read(kind)
+			if err != nil {
+				return err
+			}
+			count := Count{Kind: kind}
+			for i, rec := range records {
+				created, err := l.load(kind, rec)
+				if err != nil {
+					return fmt.Errorf("%s[%d]: %w", kind, i, err)
+				}
+				if created {
+					count.Created++
+				} else {
+					count.Existing++
+				}
+			}
+			l.result.Counts = append(l.result.Counts, count)
+
+			// Links point both ways between scenarios and datasets
+			if kind == "datasets" {
+				if err := l.link(); err != nil {
+					return err
+				}
+			}
+		}
+
+		// Fixture rows are inserted directly, bypassing the counter refreshes of the repositories
+		if _, err := counter.Reconcile(tx); err != nil {
+			return fmt.Errorf("failed to reconcile counters: %w", err)
+		}
+		result = &l.result
+		return nil
+	})
+	return result, err
+}
+
+// read parses the fixture file of kind, if any
+func (l *loader) read(kind string) ([]record, error) {
+	var path string
+	for _, ext := range extensions {
+		candidate := filepath.Join(l.dir, kind+ext)
+		if _, err := os.Stat(candidate); err == nil {
+			if path != "" {
+				return nil, fmt.Errorf("both %s and %s define %s", path, candidate, kind)
+			}
+			path = candidate
+		}
+	}
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	// YAML is a superset of JSON, so one parser reads both formats
+	var records []record
+	if err := yaml.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return records, nil
+}
+
+// load creates the entity of rec unless one with the same name exists and
+// reports whether it was created
+func (l *loader) load(kind string, rec record) (bool, error) {
+	name, _ := rec["name"].(string)
+	if name == "" {
+		return false, errors.New("name is required")
+	}
+	id, err := l.lookup(kind, name)
+	if err != nil || id != "" {
+		return false, err
+	}
+
+	var entity interface{}
+	switch kind {
+	case "organizations":
+		entity, err = l.organization(rec)
+	case "repositories":
+		entity, err = l.repository(rec)
+	case "scenarios":
+		entity, err = l.scenario(rec)
+	case "datasets":
+		entity, err = l.dataset(rec)
+	case "packages":
+		entity, err = l.pkg(rec)
+	case "simulators":
+		entity, err = l.simulator(rec)
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := l.tx.Create(entity).Error; err != nil {
+		return false, fmt.Errorf("failed to create %q: %w", name, err)
+	}
+	return true, nil
+}
+
+func (l *loader) organization(rec record) (*identity.Organization, error) {
+	var org identity.Organization
+	return &org, decode(rec, &org)
+}
+
+func (l *loader) repository(rec record) (*repository.Repository, error) {
+	var repo repository.Repository
+	owner, err := l.take(rec, "owner", "organizations")
+	if err != nil {
+		return nil, err
+	}
+	if err := decode(rec, &repo); err != nil {
+		return nil, err
+	}
+	repo.OwnerType, repo.OwnerID = ownerOf(owner)
+	return &repo, nil
+}
+
+func (l *loader) scenario(rec record) (*scenario.Scenario, error) {
+	var s scenario.Scenario
+	owner, err := l.take(rec, "owner", "organizations")
+	if err != nil {
+		return nil, err
+	}
+	datasets, err := names(rec, "recommendedDatasets")
+	if err != nil {
+		return nil, err
+	}
+	if err := decode(rec, &s); err != nil {
+		return nil, err
+	}
+	s.OwnerType, s.OwnerID = ownerOf(owner)
+	l.pending = append(l.pending, links{
+		table: "scenarios", name: s.Name, targetTable: "datasets", targets: datasets,
+		create: func(id, targetID string, position int) interface{} {
+			return &scenario.RecommendedDataset{ScenarioID: id, DatasetID: targetID, Position: position}
+		},
+	})
+	return &s, nil
+}
+
+func (l *loader) dataset(rec record) (*dataset.Dataset, error) {
+	var d dataset.Dataset
+	owner, err := l.take(rec, "owner", "organizations")
+	if err != nil {
+		return nil, err
+	}
+	scenarios, err := names(rec, "supportedScenarios")
+	if err != nil {
+		return nil, err
+	}
+	if err := decode(rec, &d); err != nil {
+		return nil, err
+	}
+	d.OwnerType, d.OwnerID = ownerOf(owner)
+	l.pending = append(l.pending, links{
+		table: "datasets", name: d.Name, targetTable: "scenarios", targets: scenarios,
+		create: func(id, targetID string, position int) interface{} {
+			return &dataset.SupportedScenario{DatasetID: id, ScenarioID: targetID, Position: position}
+		},
+	})
+	return &d, nil
+}
+
+func (l *loader) pkg(rec record) (*pkg.Package, error) {
+	var p pkg.Package
+	owner, err := l.take(rec, "owner", "organizations")
+	if err != nil {
+		return nil, err
+	}
+	repoName, _ := rec["repo"].(string)
+	repoID, err := l.take(rec, "repo", "repositories")
+	if err != nil {
+		return nil, err
+	}
+	scenarioID, err := l.take(rec, "lastRunScenario", "scenarios")
+	if err != nil {
+		return nil, err
+	}
+	if err := decode(rec, &p); err != nil {
+		return nil, err
+	}
+	p.OwnerType, p.OwnerID = ownerOf(owner)
+	p.RepoID, p.RepoName = store.NullID(repoID), repoName
+	if scenarioID != "" {
+		if p.LastRun == nil {
+			p.LastRun = &pkg.LastRun{}
+		}
+		p.LastRun.ScenarioID = scenarioID
+	}
+	return &p, nil
+}
+
+func (l *loader) simulator(rec record) (*simulator.Simulator, error) {
+	var sim simulator.Simulator
+	return &sim, decode(rec, &sim)
+}
+
+// link creates the scenario-dataset links of the entities created so far
+func (l *loader) link() error {
+	for _, pending := range l.pending {
+		id, err := l.resolve(pending.table, pending.name)
+		if err != nil {
+			return err
+		}
+		seen := map[string]bool{}
+		for _, target := range pending.targets {
+			targetID, err := l.resolve(pending.targetTable, target)
+			if err != nil {
+				return fmt.Errorf("%s %q: %w", pending.table, pending.name, err)
+			}
+			if seen[targetID] {
+				continue
+			}
+			if err := l.tx.Create(pending.create(id, targetID, len(seen))).Error; err != nil {
+				return fmt.Errorf("failed to link %s %q: %w", pending.table, pending.name, err)
+			}
+			seen[targetID] = true
+		}
+	}
+	l.pending = nil
+	return nil
+}
+
+// take removes the reference field key from rec and resolves it to the ID of
+// the entity of table with that name; an absent field resolves to ""
+func (l *loader) take(rec record, key, table string) (string, error) {
+	value, ok := rec[key]
+	if !ok {
+		return "", nil
+	}
+	delete(rec, key)
+	name, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("%s must be a name", key)
+	}
+	id, err := l.resolve(table, name)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", key, err)
+	}
+	return id, nil
+}
+
+// resolve returns the ID of the entity of table named name, which must exist
+func (l *loader) resolve(table, name string) (string, error) {
+	id, err := l.lookup(table, name)
+	if err == nil && id == "" {
+		err = fmt.Errorf("%s %q not found", table, name)
+	}
+	return id, err
+}
+
+// lookup returns the ID of the live entity of table named name, or "" if there is none
+func (l *loader) lookup(table, name string) (string, error) {
+	stmt := l.tx.Table(table).Where("name = ?", name)
+	if table != "organizations" {
+		stmt = stmt.Where("deleted_at IS NULL")
+	}
+	var ids []string
+	err := stmt.Limit(1).Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return "", err
+	}
+	return ids[0], nil
+}
+
+// names removes the list of names under key from rec
+func names(rec record, key string) ([]string, error) {
+	value, ok := rec[key]
+	if !ok {
+		return nil, nil
+	}
+	delete(rec, key)
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be a list of names", key)
+	}
+	names := make([]string, 0, len(list))
+	for _, item := range list {
+		name, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a list of names", key)
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// decode fills entity from the API fields of rec, rejecting unknown fields
+func decode(rec record, entity interface{}) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(entity)
+}
+
+// ownerOf returns the owner fields of an entity owned by the organization orgID, if any
+func ownerOf(orgID string) (string, string) {
+	if orgID == "" {
+		return "", ""
+	}
+	return auth.OwnerOrganization, orgID
+}
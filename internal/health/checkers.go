@@ -0,0 +1,42 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"gorm.io/gorm"
+)
+
+// Database pings the database. Failures are logged rather than reported,
+// since driver errors name the database host and user.
+func Database(db *gorm.DB) Checker {
+	return CheckerFunc(func(ctx context.Context) Result {
+		sqlDB, err := db.DB()
+		if err == nil {
+			err = sqlDB.PingContext(ctx)
+		}
+		if err != nil {
+			slog.WarnContext(ctx, "Database health check failed", "error", err)
+			return Result{Status: StatusDown, Message: "ping failed"}
+		}
+		return Result{Status: StatusOK}
+	})
+}
+
+// QueueDepth reports a work queue as degraded once more than threshold items
+// are waiting. depth counts the waiting items.
+func QueueDepth(depth func(ctx context.Context) (int64, error), threshold int64) Checker {
+	return CheckerFunc(func(ctx context.Context) Result {
+		n, err := depth(ctx)
+		if err != nil {
+			slog.WarnContext(ctx, "Queue health check failed", "error", err)
+			return Result{Status: StatusDown, Message: "count failed"}
+		}
+		msg := fmt.Sprintf("%d queued", n)
+		if n > threshold {
+			return Result{Status: StatusDegraded, Message: msg}
+		}
+		return Result{Status: StatusOK, Message: msg}
+	})
+}
@@ -0,0 +1,130 @@
+// Package health checks the dependencies of the service for the health and
+// readiness endpoints. Checkers are registered by name; critical checkers
+// gate readiness, the others only degrade the reported health.
+package health
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Status is the state of the service or one of its dependencies (API_CONTRACT.md §13)
+type Status string
+
+const (
+	StatusOK       Status = "ok"
+	StatusDegraded Status = "degraded"
+	StatusDown     Status = "down"
+)
+
+// Checker checks one dependency. Check must return once ctx is done.
+type Checker interface {
+	Check(ctx context.Context) Result
+}
+
+// CheckerFunc adapts a function to Checker
+type CheckerFunc func(ctx context.Context) Result
+
+func (f CheckerFunc) Check(ctx context.Context) Result {
+	return f(ctx)
+}
+
+// Result is the outcome of a check
+type Result struct {
+	Status  Status `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// Report is the health of the service and of each dependency checked
+type Report struct {
+	Status   Status
+	Services map[string]Result
+}
+
+type check struct {
+	name     string
+	checker  Checker
+	critical bool
+}
+
+// Health runs the registered checkers, each with a timeout
+type Health struct {
+	timeout time.Duration
+	mu      sync.RWMutex
+	checks  []check
+}
+
+func New(timeout time.Duration) *Health {
+	return &Health{timeout: timeout}
+}
+
+// Register adds a checker. The service is down, and not ready, while a
+// critical checker is down; other checkers only degrade it.
+func (h *Health) Register(name string, checker Checker, critical bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks = append(h.checks, check{name: name, checker: checker, critical: critical})
+	sort.Slice(h.checks, func(i, j int) bool { return h.checks[i].name < h.checks[j].name })
+}
+
+// Check runs every checker concurrently
+func (h *Health) Check(ctx context.Context) Report {
+	return h.run(ctx, func(check) bool { return true })
+}
+
+// Ready runs the critical checkers only
+func (h *Health) Ready(ctx context.Context) Report {
+	return h.run(ctx, func(c check) bool { return c.critical })
+}
+
+func (h *Health) run(ctx context.Context, include func(check) bool) Report {
+	h.mu.RLock()
+	var checks []check
+	for _, c := range h.checks {
+		if include(c) {
+			checks = append(checks, c)
+		}
+	}
+	h.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c check) {
+			defer wg.Done()
+			results[i] = h.check(ctx, c.checker)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Services: make(map[string]Result, len(checks))}
+	for i, c := range checks {
+		res := results[i]
+		report.Services[c.name] = res
+		switch {
+		case res.Status == StatusDown && c.critical:
+			report.Status = StatusDown
+		case res.Status != StatusOK && report.Status == StatusOK:
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+// check runs checker with the timeout, reporting a checker that overruns it as down
+func (h *Health) check(ctx context.Context, checker Checker) Result {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	done := make(chan Result, 1)
+	go func() { done <- checker.Check(ctx) }()
+	select {
+	case res := <-done:
+		return res
+	case <-ctx.Done():
+		return Result{Status: StatusDown, Message: "check timed out"}
+	}
+}
@@ -0,0 +1,132 @@
+package http
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"robohub-inventory/internal/http/response"
+	"robohub-inventory/internal/jwtauth"
+	"robohub-inventory/internal/logger"
+	"robohub-inventory/pkg/apikey"
+	"robohub-inventory/pkg/auth"
+	"robohub-inventory/pkg/identity"
+)
+
+// errAuthUnavailable reports that credentials could not be checked, as
+// opposed to being invalid
+var errAuthUnavailable = errors.New("unable to verify credentials")
+
+// Authenticator resolves the caller of a request from one of the three
+// schemes in API_CONTRACT.md §7: Bearer JWT, X-API-Key or X-Agent-ID.
+type Authenticator struct {
+	verifier   *jwtauth.Verifier
+	keys       *apikey.Service
+	identities *identity.Service
+	agents     map[string]bool
+	limiter    *RateLimiter
+}
+
+// NewAuthenticator creates an authenticator. X-Agent-ID is trusted for the
+// configured agent IDs only and must be stripped at the ingress so that it
+// cannot be set by external callers. Authenticated principals are resolved to
+// their user and organization roles through identities. Rejected credentials
+// are charged to the client IP on limiter, which may be nil.
+func NewAuthenticator(verifier *jwtauth.Verifier, keys *apikey.Service, identities *identity.Service, agentIDs []string, limiter *RateLimiter) *Authenticator {
+	agents := make(map[string]bool, len(agentIDs))
+	for _, id := range agentIDs {
+		agents[id] = true
+	}
+	return &Authenticator{verifier: verifier, keys: keys, identities: identities, agents: agents, limiter: limiter}
+}
+
+// Authenticate attaches the principal of any presented credential to the
+// request context. Requests without credentials pass through anonymously;
+// invalid credentials are rejected with 401, or 429 once the client IP has
+// exceeded the anonymous rate limit.
+func (a *Authenticator) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := a.principal(r)
+		if errors.Is(err, errAuthUnavailable) {
+			response.Error(w, r, http.StatusServiceUnavailable, response.CodeServiceUnavailable, err.Error(), nil)
+			return
+		}
+		if err != nil {
+			if a.limiter == nil || a.limiter.ChargeFailure(w, r) {
+				unauthorized(w, r, err.Error())
+			}
+			return
+		}
+		if principal != nil {
+			if err := a.identities.Resolve(r.Context(), principal); err != nil {
+				slog.ErrorContext(r.Context(), "Identity lookup failed", "subject", principal.Subject, "error", err)
+				response.Error(w, r, http.StatusServiceUnavailable, response.CodeServiceUnavailable, errAuthUnavailable.Error(), nil)
+				return
+			}
+			logger.AddFields(r.Context(), "user", principal.Subject)
+			r = r.WithContext(auth.WithPrincipal(r.Context(), principal))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireAuth rejects anonymous requests
+func (a *Authenticator) RequireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := auth.FromContext(r.Context()); !ok {
+			unauthorized(w, r, "authentication required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (a *Authenticator) principal(r *http.Request) (*auth.Principal, error) {
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, token, ok := strings.Cut(header, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			return nil, errors.New("malformed Authorization header")
+		}
+		principal, err := a.verifier.Verify(r.Context(), strings.TrimSpace(token))
+		if errors.Is(err, jwtauth.ErrKeysUnavailable) {
+			slog.ErrorContext(r.Context(), "JWKS lookup failed", "error", err)
+			return nil, errAuthUnavailable
+		}
+		if err != nil {
+			return nil, err
+		}
+		return principal, nil
+	}
+
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		k, err := a.keys.Authenticate(r.Context(), key)
+		if errors.Is(err, apikey.ErrUnauthenticated) {
+			return nil, err
+		}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "API key lookup failed", "error", err)
+			return nil, errAuthUnavailable
+		}
+		return &auth.Principal{
+			Kind:    auth.KindAPIKey,
+			Subject: k.Subject,
+			Name:    k.Name,
+			KeyID:   k.ID,
+		}, nil
+	}
+
+	if agentID := r.Header.Get("X-Agent-ID"); agentID != "" {
+		if !a.agents[agentID] {
+			return nil, errors.New("unknown agent")
+		}
+		return &auth.Principal{Kind: auth.KindAgent, Subject: agentID, Name: agentID}, nil
+	}
+
+	return nil, nil
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="robohub"`)
+	response.Error(w, r, http.StatusUnauthorized, response.CodeUnauthorized, message, nil)
+}
@@ -0,0 +1,38 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAuthenticateChargesRejectedCredentials(t *testing.T) {
+	limiter := NewRateLimiter(NewMemoryRateLimitStore(), RateLimits{Public: 3, Authenticated: 100, Window: time.Minute})
+	a := NewAuthenticator(nil, nil, nil, []string{"agent-1"}, limiter)
+	handler := a.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("request with rejected credentials reached the handler")
+	}))
+
+	want := []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests}
+	for i, status := range want {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/packages", nil)
+		req.RemoteAddr = "203.0.113.7:4321"
+		req.Header.Set("X-Agent-ID", "guess")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != status {
+			t.Fatalf("request %d: status %d, want %d", i+1, rec.Code, status)
+		}
+	}
+
+	// Another client is not affected
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/packages", nil)
+	req.RemoteAddr = "198.51.100.1:4321"
+	req.Header.Set("X-Agent-ID", "guess")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("other client: status %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,103 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+type clientIPKey struct{}
+
+// ClientIP resolves the address of the client of a request. The socket peer
+// is the client unless it is one of the trusted proxies; only then are
+// X-Forwarded-For and X-Real-IP read, since any other caller can set them.
+type ClientIP struct {
+	trusted []*net.IPNet
+}
+
+// NewClientIP creates a resolver trusting the forwarding headers set by the
+// given proxies, each an IP address or a CIDR range
+func NewClientIP(trustedProxies []string) (*ClientIP, error) {
+	c := &ClientIP{}
+	for _, proxy := range trustedProxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", proxy)
+			}
+			c.trusted = append(c.trusted, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q", proxy)
+		}
+		c.trusted = append(c.trusted, network)
+	}
+	return c, nil
+}
+
+// Resolve stores the client address of the request in its context, where
+// ClientIPFrom reads it. r.RemoteAddr is left as the socket peer.
+func (c *ClientIP) Resolve(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), clientIPKey{}, c.resolve(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// resolve walks X-Forwarded-For from the nearest hop while the hops are
+// trusted proxies, and falls back to X-Real-IP without the header
+func (c *ClientIP) resolve(r *http.Request) string {
+	peer, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		peer = r.RemoteAddr
+	}
+	if !c.trusts(peer) {
+		return peer
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	if len(hops) == 0 {
+		if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(ip) != nil {
+			return ip
+		}
+		return peer
+	}
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+		client = hop
+		if !c.trusts(hop) {
+			break
+		}
+	}
+	return client
+}
+
+func (c *ClientIP) trusts(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, network := range c.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIPFrom returns the client address resolved for the request of ctx
+func ClientIPFrom(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
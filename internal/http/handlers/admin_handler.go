@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"robohub-inventory/internal/http/response"
+	"robohub-inventory/pkg/counter"
+)
+
+// AdminHandler serves platform maintenance jobs
+type AdminHandler struct {
+	counters *counter.Service
+}
+
+func NewAdminHandler(counters *counter.Service) *AdminHandler {
+	return &AdminHandler{counters: counters}
+}
+
+// ReconcileCounters handles POST /admin/reconcile, recomputing every relationship counter
+func (h *AdminHandler) ReconcileCounters(w http.ResponseWriter, r *http.Request) {
+	res, err := h.counters.Reconcile(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "Reconciled counters",
+		"repositories", res.Repositories, "packages", res.Packages, "scenarios", res.Scenarios)
+	response.JSON(w, http.StatusOK, res)
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"robohub-inventory/internal/http/response"
+	"robohub-inventory/pkg/apikey"
+	"robohub-inventory/pkg/auth"
+	"robohub-inventory/pkg/query"
+)
+
+type APIKeyHandler struct {
+	service *apikey.Service
+}
+
+func NewAPIKeyHandler(service *apikey.Service) *APIKeyHandler {
+	return &APIKeyHandler{service: service}
+}
+
+// createAPIKeyRequest is the body of POST /api-keys
+type createAPIKeyRequest struct {
+	Name      string     `json:"name"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// createdAPIKey includes the plaintext key, which is only shown once
+type createdAPIKey struct {
+	*apikey.APIKey
+	Key string `json:"key"`
+}
+
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.FromContext(r.Context())
+
+	var req createAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	key, plaintext, err := h.service.CreateKey(r.Context(), principal.Subject, req.Name, req.ExpiresAt)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, createdAPIKey{APIKey: key, Key: plaintext})
+}
+
+func (h *APIKeyHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.FromContext(r.Context())
+
+	keys, err := h.service.ListKeys(r.Context(), principal.Subject)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	page := query.Page{Limit: max(len(keys), 1)}
+	response.JSON(w, http.StatusOK, newPageResponse(r, keys, int64(len(keys)), page, apiKeyCursor))
+}
+
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.FromContext(r.Context())
+
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		writeInvalidID(w, r)
+		return
+	}
+
+	if err := h.service.RevokeKey(r.Context(), principal.Subject, id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func apiKeyCursor(k *apikey.APIKey) query.Cursor {
+	return query.Cursor{CreatedAt: k.CreatedAt, ID: k.ID}
+}
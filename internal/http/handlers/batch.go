@@ -0,0 +1,13 @@
+package handlers
+
+// batchRequest is the body of POST /{entities}/batch (API_CONTRACT.md §11)
+type batchRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// batchResponse returns the entities found, in request order, and the IDs
+// that did not match any entity
+type batchResponse[T any] struct {
+	Items    []T      `json:"items"`
+	NotFound []string `json:"notFound"`
+}
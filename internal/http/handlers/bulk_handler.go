@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"robohub-inventory/internal/http/response"
+	"robohub-inventory/pkg/bulk"
+	"robohub-inventory/pkg/query"
+)
+
+// maxImportBytes bounds the body of a bulk import
+const maxImportBytes = 16 << 20
+
+type BulkHandler struct {
+	service *bulk.Service
+}
+
+func NewBulkHandler(service *bulk.Service) *BulkHandler {
+	return &BulkHandler{service: service}
+}
+
+// Import handles POST /bulk, whose items carry their own type
+func (h *BulkHandler) Import(w http.ResponseWriter, r *http.Request) {
+	h.importItems(w, r, "")
+}
+
+func (h *BulkHandler) ImportPackages(w http.ResponseWriter, r *http.Request) {
+	h.importItems(w, r, bulk.TypePackage)
+}
+
+func (h *BulkHandler) ImportRepositories(w http.ResponseWriter, r *http.Request) {
+	h.importItems(w, r, bulk.TypeRepository)
+}
+
+func (h *BulkHandler) ImportScenarios(w http.ResponseWriter, r *http.Request) {
+	h.importItems(w, r, bulk.TypeScenario)
+}
+
+func (h *BulkHandler) ImportDatasets(w http.ResponseWriter, r *http.Request) {
+	h.importItems(w, r, bulk.TypeDataset)
+}
+
+func (h *BulkHandler) ImportSimulators(w http.ResponseWriter, r *http.Request) {
+	h.importItems(w, r, bulk.TypeSimulator)
+}
+
+// importItems reads a JSON array or NDJSON body and upserts its items.
+// Committed imports and dry runs return 200; atomic imports rolled back
+// because of rejected items return 422. Both carry the per-item report.
+func (h *BulkHandler) importItems(w http.ResponseWriter, r *http.Request, typ bulk.Type) {
+	opts, err := parseImportOptions(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	items, err := bulk.ReadItems(http.MaxBytesReader(w, r.Body, maxImportBytes), typ)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	res, err := h.service.Import(r.Context(), items, opts)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	for i := range res.Items {
+		item := &res.Items[i]
+		if item.Err == nil {
+			continue
+		}
+		status, code, message, _ := classifyError(item.Err)
+		if status == http.StatusInternalServerError {
+			slog.ErrorContext(r.Context(), "Bulk import item failed", "type", item.Type, "index", item.Index, "error", item.Err)
+		}
+		item.Error = &bulk.ItemError{Code: code, Message: message}
+	}
+
+	status := http.StatusOK
+	if !res.Committed && !res.DryRun {
+		status = http.StatusUnprocessableEntity
+	}
+	response.JSON(w, status, res)
+}
+
+// parseImportOptions reads ?dryRun= and ?atomic=; imports are atomic unless
+// atomic=false asks for per-item results
+func parseImportOptions(r *http.Request) (bulk.Options, error) {
+	opts := bulk.Options{Atomic: true}
+	for name, target := range map[string]*bool{"dryRun": &opts.DryRun, "atomic": &opts.Atomic} {
+		v := r.URL.Query().Get(name)
+		if v == "" {
+			continue
+		}
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return opts, fmt.Errorf("%w: %s must be true or false", query.ErrInvalidQuery, name)
+		}
+		*target = b
+	}
+	return opts, nil
+}
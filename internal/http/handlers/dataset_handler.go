@@ -3,10 +3,11 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
-	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"robohub-inventory/internal/http/response"
 	"robohub-inventory/pkg/dataset"
+	"robohub-inventory/pkg/query"
 )
 
 type DatasetHandler struct {
@@ -20,89 +21,180 @@ func NewDatasetHandler(service *dataset.Service) *DatasetHandler {
 func (h *DatasetHandler) CreateDataset(w http.ResponseWriter, r *http.Request) {
 	var d dataset.Dataset
 	if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeDecodeError(w, r, err)
 		return
 	}
 
 	if err := h.service.CreateDataset(r.Context(), &d); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(d)
+	writeEntity(w, r, http.StatusCreated, d.Revision, d)
 }
 
 func (h *DatasetHandler) GetDataset(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		writeInvalidID(w, r)
 		return
 	}
 
 	d, err := h.service.GetDataset(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		writeError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(d)
+	writeEntity(w, r, http.StatusOK, d.Revision, d)
 }
 
-func (h *DatasetHandler) ListDatasets(w http.ResponseWriter, r *http.Request) {
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+func (h *DatasetHandler) BatchGetDatasets(w http.ResponseWriter, r *http.Request) {
+	var req batchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	items, notFound, err := h.service.GetDatasetsBatch(r.Context(), req.IDs)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, batchResponse[*dataset.Dataset]{Items: items, NotFound: notFound})
+}
 
-	if limit <= 0 {
-		limit = 100
+func (h *DatasetHandler) ListDatasets(w http.ResponseWriter, r *http.Request) {
+	spec, err := query.Parse(r.URL.Query(), dataset.ListSchema)
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
 
-	datasets, err := h.service.ListDatasets(r.Context(), limit, offset)
+	datasets, total, err := h.service.ListDatasets(r.Context(), spec)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(datasets)
+	response.JSON(w, http.StatusOK, newPageResponse(r, datasets, total, spec.Page, datasetCursor))
 }
 
 func (h *DatasetHandler) UpdateDataset(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		writeInvalidID(w, r)
 		return
 	}
 
 	var d dataset.Dataset
 	if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeDecodeError(w, r, err)
 		return
 	}
 
+	revision, err := ifMatch(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if revision != 0 {
+		d.Revision = revision
+	}
+
 	d.ID = id
 	if err := h.service.UpdateDataset(r.Context(), &d); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
+		return
+	}
+
+	writeEntity(w, r, http.StatusOK, d.Revision, d)
+}
+
+// PatchDataset handles PATCH /datasets/{id} with a merge patch or JSON Patch body
+func (h *DatasetHandler) PatchDataset(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		writeInvalidID(w, r)
+		return
+	}
+
+	revision, err := ifMatch(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	p, ok := readPatch(w, r)
+	if !ok {
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(d)
+	d, err := h.service.PatchDataset(r.Context(), id, revision, p)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeEntity(w, r, http.StatusOK, d.Revision, d)
 }
 
 func (h *DatasetHandler) DeleteDataset(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		writeInvalidID(w, r)
+		return
+	}
+
+	revision, err := ifMatch(r)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	if err := h.service.DeleteDataset(r.Context(), id); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.service.DeleteDataset(r.Context(), id, revision); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// ListDeletedDatasets handles GET /datasets/trash
+func (h *DatasetHandler) ListDeletedDatasets(w http.ResponseWriter, r *http.Request) {
+	spec, err := query.Parse(r.URL.Query(), dataset.ListSchema)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	datasets, total, err := h.service.ListDeletedDatasets(r.Context(), spec)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, newPageResponse(r, datasets, total, spec.Page, datasetCursor))
+}
+
+// RestoreDataset handles POST /datasets/{id}/restore
+func (h *DatasetHandler) RestoreDataset(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		writeInvalidID(w, r)
+		return
+	}
+
+	d, err := h.service.RestoreDataset(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeEntity(w, r, http.StatusOK, d.Revision, d)
+}
+
+func datasetCursor(d *dataset.Dataset) query.Cursor {
+	return query.Cursor{CreatedAt: d.CreatedAt, ID: d.ID}
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+
+	"robohub-inventory/internal/http/response"
+	"robohub-inventory/pkg/apikey"
+	"robohub-inventory/pkg/auth"
+	"robohub-inventory/pkg/bulk"
+	"robohub-inventory/pkg/dataset"
+	"robohub-inventory/pkg/event"
+	"robohub-inventory/pkg/identity"
+	pkg "robohub-inventory/pkg/package"
+	"robohub-inventory/pkg/patch"
+	"robohub-inventory/pkg/query"
+	"robohub-inventory/pkg/repository"
+	"robohub-inventory/pkg/scenario"
+	"robohub-inventory/pkg/search"
+	"robohub-inventory/pkg/simulator"
+	"robohub-inventory/pkg/store"
+	"robohub-inventory/pkg/webhook"
+)
+
+// errorMapping ties a set of service errors to an HTTP status and error code
+type errorMapping struct {
+	status int
+	code   string
+	errs   []error
+}
+
+var errorMappings = []errorMapping{
+	{
+		status: http.StatusBadRequest,
+		code:   response.CodeValidation,
+		errs: []error{
+			pkg.ErrInvalidPackage,
+			repository.ErrInvalidRepository,
+			scenario.ErrInvalidScenario,
+			dataset.ErrInvalidDataset,
+			simulator.ErrInvalidSimulator,
+			apikey.ErrInvalidAPIKey,
+			bulk.ErrInvalidImport,
+			patch.ErrInvalidPatch,
+			auth.ErrInvalidOwner,
+			identity.ErrInvalidOrganization,
+			identity.ErrInvalidMembership,
+			query.ErrInvalidQuery,
+			search.ErrInvalidSearch,
+			webhook.ErrInvalidSubscription,
+			event.ErrInvalidFilter,
+			gorm.ErrInvalidField,
+		},
+	},
+	{
+		status: http.StatusUnauthorized,
+		code:   response.CodeUnauthorized,
+		errs: []error{
+			auth.ErrUnauthenticated,
+		},
+	},
+	{
+		status: http.StatusForbidden,
+		code:   response.CodeForbidden,
+		errs: []error{
+			auth.ErrForbidden,
+		},
+	},
+	{
+		status: http.StatusNotFound,
+		code:   response.CodeNotFound,
+		errs: []error{
+			pkg.ErrPackageNotFound,
+			repository.ErrRepositoryNotFound,
+			scenario.ErrScenarioNotFound,
+			dataset.ErrDatasetNotFound,
+			simulator.ErrSimulatorNotFound,
+			apikey.ErrAPIKeyNotFound,
+			identity.ErrUserNotFound,
+			identity.ErrOrganizationNotFound,
+			identity.ErrMembershipNotFound,
+			webhook.ErrSubscriptionNotFound,
+			webhook.ErrDeliveryNotFound,
+			gorm.ErrRecordNotFound,
+		},
+	},
+	{
+		status: http.StatusConflict,
+		code:   response.CodeConflict,
+		errs: []error{
+			pkg.ErrPackageAlreadyExists,
+			repository.ErrRepositoryAlreadyExists,
+			scenario.ErrScenarioAlreadyExists,
+			dataset.ErrDatasetAlreadyExists,
+			simulator.ErrSimulatorAlreadyExists,
+			identity.ErrOrganizationAlreadyExists,
+			identity.ErrLastAdmin,
+			pkg.ErrRepositoryDeleted,
+			patch.ErrTestFailed,
+			gorm.ErrDuplicatedKey,
+		},
+	},
+	{
+		status: http.StatusConflict,
+		code:   response.CodeNameConflict,
+		errs: []error{
+			pkg.ErrPackageNameTaken,
+			repository.ErrRepositoryNameTaken,
+			repository.ErrPackageNameTaken,
+			scenario.ErrScenarioNameTaken,
+			dataset.ErrDatasetNameTaken,
+			simulator.ErrSimulatorNameTaken,
+		},
+	},
+	{
+		status: http.StatusPreconditionFailed,
+		code:   response.CodePreconditionFailed,
+		errs: []error{
+			store.ErrRevisionMismatch,
+		},
+	},
+	{
+		status: http.StatusUnsupportedMediaType,
+		code:   response.CodeUnsupportedMediaType,
+		errs: []error{
+			patch.ErrUnsupportedMediaType,
+		},
+	},
+	{
+		status: http.StatusUnprocessableEntity,
+		code:   response.CodeInvalidReference,
+		errs: []error{
+			store.ErrInvalidReference,
+			gorm.ErrForeignKeyViolated,
+		},
+	},
+	{
+		status: http.StatusServiceUnavailable,
+		code:   response.CodeServiceUnavailable,
+		errs: []error{
+			context.DeadlineExceeded,
+		},
+	},
+}
+
+// PostgreSQL error codes that are caused by bad client input
+var pgClientErrorCodes = map[string]struct{}{
+	"22P02": {}, // invalid_text_representation, e.g. a malformed UUID
+	"22001": {}, // string_data_right_truncation
+	"23502": {}, // not_null_violation
+}
+
+// writeError maps err onto the contract error envelope and writes it
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	status, code, message, details := classifyError(err)
+	if status == http.StatusInternalServerError {
+		slog.ErrorContext(r.Context(), "Unhandled error", "error", err)
+	}
+	response.Error(w, r, status, code, message, details)
+}
+
+// classifyError returns the status, error code, message and details that
+// report err to clients. Unrecognized errors are hidden behind a generic 500.
+func classifyError(err error) (int, string, string, map[string]interface{}) {
+	for _, m := range errorMappings {
+		for _, target := range m.errs {
+			if errors.Is(err, target) {
+				return m.status, m.code, err.Error(), nil
+			}
+		}
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		if _, ok := pgClientErrorCodes[pgErr.Code]; ok {
+			var details map[string]interface{}
+			if pgErr.ColumnName != "" {
+				details = map[string]interface{}{"column": pgErr.ColumnName}
+			}
+			return http.StatusBadRequest, response.CodeValidation, pgErr.Message, details
+		}
+	}
+
+	return http.StatusInternalServerError, response.CodeInternal, "internal server error", nil
+}
+
+// writeDecodeError reports a request body that could not be decoded
+func writeDecodeError(w http.ResponseWriter, r *http.Request, err error) {
+	response.Error(w, r, http.StatusBadRequest, response.CodeValidation, fmt.Sprintf("invalid request body: %v", err), nil)
+}
+
+// writeInvalidID reports a missing or malformed path identifier
+func writeInvalidID(w http.ResponseWriter, r *http.Request) {
+	response.Error(w, r, http.StatusBadRequest, response.CodeValidation, "invalid ID", nil)
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"robohub-inventory/internal/http/response"
+	"robohub-inventory/pkg/store"
+)
+
+// etag formats an entity revision as a strong entity tag
+func etag(revision int64) string {
+	return `"` + strconv.FormatInt(revision, 10) + `"`
+}
+
+// writeEntity writes a single entity together with its ETag. A GET whose
+// If-None-Match already names the current revision gets 304 Not Modified.
+func writeEntity(w http.ResponseWriter, r *http.Request, status int, revision int64, v interface{}) {
+	tag := etag(revision)
+	w.Header().Set("ETag", tag)
+	if (r.Method == http.MethodGet || r.Method == http.MethodHead) && noneMatch(r.Header.Get("If-None-Match"), tag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	response.JSON(w, status, v)
+}
+
+// noneMatch reports whether an If-None-Match header matches tag, using the
+// weak comparison RFC 9110 prescribes for it
+func noneMatch(header, tag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatch returns the revision named by the If-Match header, or 0 when the
+// header is absent or "*". Anything other than a single entity tag taken
+// from an ETag header can never match and fails the precondition.
+func ifMatch(r *http.Request) (int64, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+	if len(header) < 2 || header[0] != '"' || header[len(header)-1] != '"' {
+		return 0, fmt.Errorf("%w: If-Match must be a single entity tag", store.ErrRevisionMismatch)
+	}
+	revision, err := strconv.ParseInt(header[1:len(header)-1], 10, 64)
+	if err != nil || revision < 1 {
+		return 0, fmt.Errorf("%w: If-Match %s does not name a revision", store.ErrRevisionMismatch, header)
+	}
+	return revision, nil
+}
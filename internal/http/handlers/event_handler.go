@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"robohub-inventory/pkg/event"
+	"robohub-inventory/pkg/query"
+)
+
+const (
+	// streamBatchSize is the number of events read per query of a stream
+	streamBatchSize = 100
+	// streamHeartbeat keeps idle streams from being closed by proxies
+	streamHeartbeat = 15 * time.Second
+	// streamRetry is the reconnection delay suggested to clients, in milliseconds
+	streamRetry = 3000
+)
+
+// EventHandler serves the change stream of the event log
+type EventHandler struct {
+	feed *event.Feed
+}
+
+func NewEventHandler(feed *event.Feed) *EventHandler {
+	return &EventHandler{feed: feed}
+}
+
+// Stream handles GET /events/stream, sending the events about entities the
+// caller may read as Server-Sent Events. The entityType, entityId and type
+// parameters filter the events. A client reconnecting with Last-Event-ID, or
+// lastEventId, resumes after that event; if it is no longer in the log, a
+// reset event tells the client to reload what it shows.
+func (h *EventHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+	filter := event.Filter{
+		EntityTypes: query.Values(values, "entityType"),
+		EntityIDs:   query.Values(values, "entityId"),
+		Types:       query.Values(values, "type"),
+	}
+	if err := event.ValidateFilter(filter); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = values.Get("lastEventId")
+	}
+	pos, resumed, err := h.feed.Start(r.Context(), lastEventID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// The stream outlives the server write timeout
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetry)
+	if !resumed {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		head, changed := h.feed.Head()
+		if head.After(pos) {
+			records, err := h.feed.Read(r.Context(), pos, head, filter, streamBatchSize)
+			if err != nil {
+				if r.Context().Err() == nil {
+					slog.ErrorContext(r.Context(), "Failed to read events", "error", err)
+				}
+				return
+			}
+			for _, rec := range records {
+				if err := writeEvent(w, rec.Event); err != nil {
+					return
+				}
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+			if len(records) == streamBatchSize {
+				pos = records[len(records)-1].Position
+				continue
+			}
+			pos = head
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-h.feed.Done():
+			return
+		case <-changed:
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// writeEvent writes an event as an SSE message named after its type
+func writeEvent(w http.ResponseWriter, e event.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+	return err
+}
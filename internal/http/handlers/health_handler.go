@@ -1,21 +1,75 @@
 package handlers
 
 import (
-	"encoding/json"
 	"net/http"
+	"time"
+
+	"robohub-inventory/internal/buildinfo"
+	"robohub-inventory/internal/health"
+	"robohub-inventory/internal/http/response"
 )
 
-type HealthHandler struct{}
+// HealthHandler serves the health, liveness, readiness and version endpoints
+type HealthHandler struct {
+	health *health.Health
+}
+
+func NewHealthHandler(h *health.Health) *HealthHandler {
+	return &HealthHandler{health: h}
+}
 
-func NewHealthHandler() *HealthHandler {
-	return &HealthHandler{}
+// healthBody is the health check response (API_CONTRACT.md §13)
+type healthBody struct {
+	Status    health.Status            `json:"status"`
+	Version   string                   `json:"version"`
+	Timestamp time.Time                `json:"timestamp"`
+	Services  map[string]health.Status `json:"services"`
+	Details   map[string]health.Result `json:"details,omitempty"`
 }
 
+// Health handles GET /health, checking every dependency. It responds 503
+// while a critical dependency is down.
 func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{
-		"status":  "healthy",
-		"service": "robohub-inventory",
-	})
+	h.write(w, h.health.Check(r.Context()))
+}
+
+// Ready handles GET /readyz, checking the dependencies needed to serve
+// requests. It responds 503 while one of them is down.
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	h.write(w, h.health.Ready(r.Context()))
+}
+
+// Live handles GET /livez. It checks no dependency, so that an outage of one
+// does not get the process restarted.
+func (h *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	response.JSON(w, http.StatusOK, map[string]string{"status": string(health.StatusOK)})
+}
+
+// Version handles GET /version
+func (h *HealthHandler) Version(w http.ResponseWriter, r *http.Request) {
+	response.JSON(w, http.StatusOK, buildinfo.Get())
+}
+
+func (h *HealthHandler) write(w http.ResponseWriter, report health.Report) {
+	body := healthBody{
+		Status:    report.Status,
+		Version:   buildinfo.Get().Version,
+		Timestamp: time.Now().UTC(),
+		Services:  make(map[string]health.Status, len(report.Services)),
+	}
+	for name, res := range report.Services {
+		body.Services[name] = res.Status
+		if res.Message != "" {
+			if body.Details == nil {
+				body.Details = make(map[string]health.Result)
+			}
+			body.Details[name] = res
+		}
+	}
+	status := http.StatusOK
+	if report.Status == health.StatusDown {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	response.JSON(w, status, body)
 }
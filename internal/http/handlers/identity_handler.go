@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"robohub-inventory/internal/http/response"
+	"robohub-inventory/pkg/auth"
+	"robohub-inventory/pkg/identity"
+	"robohub-inventory/pkg/query"
+)
+
+type IdentityHandler struct {
+	service *identity.Service
+}
+
+func NewIdentityHandler(service *identity.Service) *IdentityHandler {
+	return &IdentityHandler{service: service}
+}
+
+// currentUser is the body of GET /users/me
+type currentUser struct {
+	*identity.User
+	Memberships []*identity.Membership `json:"memberships"`
+}
+
+// memberRequest is the body of PUT /organizations/{id}/members/{userId}
+type memberRequest struct {
+	Role auth.Role `json:"role"`
+}
+
+func (h *IdentityHandler) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
+	user, memberships, err := h.service.CurrentUser(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if memberships == nil {
+		memberships = []*identity.Membership{}
+	}
+
+	response.JSON(w, http.StatusOK, currentUser{User: user, Memberships: memberships})
+}
+
+func (h *IdentityHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		writeInvalidID(w, r)
+		return
+	}
+
+	user, err := h.service.GetUser(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, user)
+}
+
+func (h *IdentityHandler) CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	var org identity.Organization
+	if err := json.NewDecoder(r.Body).Decode(&org); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	if err := h.service.CreateOrganization(r.Context(), &org); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, org)
+}
+
+func (h *IdentityHandler) GetOrganization(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		writeInvalidID(w, r)
+		return
+	}
+
+	org, err := h.service.GetOrganization(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, org)
+}
+
+func (h *IdentityHandler) ListOrganizations(w http.ResponseWriter, r *http.Request) {
+	spec, err := query.Parse(r.URL.Query(), identity.ListSchema)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	orgs, total, err := h.service.ListOrganizations(r.Context(), spec)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, newPageResponse(r, orgs, total, spec.Page, organizationCursor))
+}
+
+func (h *IdentityHandler) UpdateOrganization(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		writeInvalidID(w, r)
+		return
+	}
+
+	var org identity.Organization
+	if err := json.NewDecoder(r.Body).Decode(&org); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	org.ID = id
+	if err := h.service.UpdateOrganization(r.Context(), &org); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, org)
+}
+
+func (h *IdentityHandler) DeleteOrganization(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		writeInvalidID(w, r)
+		return
+	}
+
+	if err := h.service.DeleteOrganization(r.Context(), id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *IdentityHandler) ListMembers(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		writeInvalidID(w, r)
+		return
+	}
+
+	members, err := h.service.ListMembers(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	page := query.Page{Limit: max(len(members), 1)}
+	response.JSON(w, http.StatusOK, newPageResponse(r, members, int64(len(members)), page, membershipCursor))
+}
+
+func (h *IdentityHandler) SetMember(w http.ResponseWriter, r *http.Request) {
+	orgID, userID := chi.URLParam(r, "id"), chi.URLParam(r, "userId")
+	if orgID == "" || userID == "" {
+		writeInvalidID(w, r)
+		return
+	}
+
+	var req memberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	m, err := h.service.SetMemberRole(r.Context(), orgID, userID, req.Role)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, m)
+}
+
+func (h *IdentityHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	orgID, userID := chi.URLParam(r, "id"), chi.URLParam(r, "userId")
+	if orgID == "" || userID == "" {
+		writeInvalidID(w, r)
+		return
+	}
+
+	if err := h.service.RemoveMember(r.Context(), orgID, userID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func organizationCursor(o *identity.Organization) query.Cursor {
+	return query.Cursor{CreatedAt: o.CreatedAt, ID: o.ID}
+}
+
+func membershipCursor(m *identity.Membership) query.Cursor {
+	return query.Cursor{CreatedAt: m.CreatedAt, ID: m.UserID}
+}
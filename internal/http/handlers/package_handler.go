@@ -3,10 +3,11 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
-	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"robohub-inventory/internal/http/response"
 	pkg "robohub-inventory/pkg/package"
+	"robohub-inventory/pkg/query"
 )
 
 type PackageHandler struct {
@@ -20,89 +21,180 @@ func NewPackageHandler(service *pkg.Service) *PackageHandler {
 func (h *PackageHandler) CreatePackage(w http.ResponseWriter, r *http.Request) {
 	var p pkg.Package
 	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeDecodeError(w, r, err)
 		return
 	}
 
 	if err := h.service.CreatePackage(r.Context(), &p); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(p)
+	writeEntity(w, r, http.StatusCreated, p.Revision, p)
 }
 
 func (h *PackageHandler) GetPackage(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		writeInvalidID(w, r)
 		return
 	}
 
 	p, err := h.service.GetPackage(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		writeError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(p)
+	writeEntity(w, r, http.StatusOK, p.Revision, p)
 }
 
-func (h *PackageHandler) ListPackages(w http.ResponseWriter, r *http.Request) {
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+func (h *PackageHandler) BatchGetPackages(w http.ResponseWriter, r *http.Request) {
+	var req batchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	items, notFound, err := h.service.GetPackagesBatch(r.Context(), req.IDs)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, batchResponse[*pkg.Package]{Items: items, NotFound: notFound})
+}
 
-	if limit <= 0 {
-		limit = 100
+func (h *PackageHandler) ListPackages(w http.ResponseWriter, r *http.Request) {
+	spec, err := query.Parse(r.URL.Query(), pkg.ListSchema)
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
 
-	packages, err := h.service.ListPackages(r.Context(), limit, offset)
+	packages, total, err := h.service.ListPackages(r.Context(), spec)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(packages)
+	response.JSON(w, http.StatusOK, newPageResponse(r, packages, total, spec.Page, packageCursor))
 }
 
 func (h *PackageHandler) UpdatePackage(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		writeInvalidID(w, r)
 		return
 	}
 
 	var p pkg.Package
 	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeDecodeError(w, r, err)
 		return
 	}
 
+	revision, err := ifMatch(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if revision != 0 {
+		p.Revision = revision
+	}
+
 	p.ID = id
 	if err := h.service.UpdatePackage(r.Context(), &p); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
+		return
+	}
+
+	writeEntity(w, r, http.StatusOK, p.Revision, p)
+}
+
+// PatchPackage handles PATCH /packages/{id} with a merge patch or JSON Patch body
+func (h *PackageHandler) PatchPackage(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		writeInvalidID(w, r)
+		return
+	}
+
+	revision, err := ifMatch(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	p, ok := readPatch(w, r)
+	if !ok {
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(p)
+	patched, err := h.service.PatchPackage(r.Context(), id, revision, p)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeEntity(w, r, http.StatusOK, patched.Revision, patched)
 }
 
 func (h *PackageHandler) DeletePackage(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		writeInvalidID(w, r)
+		return
+	}
+
+	revision, err := ifMatch(r)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	if err := h.service.DeletePackage(r.Context(), id); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.service.DeletePackage(r.Context(), id, revision); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// ListDeletedPackages handles GET /packages/trash
+func (h *PackageHandler) ListDeletedPackages(w http.ResponseWriter, r *http.Request) {
+	spec, err := query.Parse(r.URL.Query(), pkg.ListSchema)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	packages, total, err := h.service.ListDeletedPackages(r.Context(), spec)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, newPageResponse(r, packages, total, spec.Page, packageCursor))
+}
+
+// RestorePackage handles POST /packages/{id}/restore
+func (h *PackageHandler) RestorePackage(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		writeInvalidID(w, r)
+		return
+	}
+
+	p, err := h.service.RestorePackage(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeEntity(w, r, http.StatusOK, p.Revision, p)
+}
+
+func packageCursor(p *pkg.Package) query.Cursor {
+	return query.Cursor{CreatedAt: p.CreatedAt, ID: p.ID}
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"robohub-inventory/pkg/query"
+)
+
+// pageLinks holds the HATEOAS navigation links of a list response
+type pageLinks struct {
+	Next  string `json:"next,omitempty"`
+	Prev  string `json:"prev,omitempty"`
+	First string `json:"first,omitempty"`
+	Last  string `json:"last,omitempty"`
+	Self  string `json:"self"`
+}
+
+// pageResponse is the list envelope from API_CONTRACT.md §8
+type pageResponse[T any] struct {
+	Items      []T       `json:"items"`
+	Total      int64     `json:"total"`
+	Limit      int       `json:"limit"`
+	Offset     int       `json:"offset"`
+	NextCursor string    `json:"nextCursor,omitempty"`
+	Links      pageLinks `json:"_links"`
+}
+
+// newPageResponse wraps a page of items and builds its navigation links.
+// cursorOf extracts the keyset cursor of an item for cursor-based paging.
+func newPageResponse[T any](r *http.Request, items []T, total int64, page query.Page, cursorOf func(T) query.Cursor) pageResponse[T] {
+	if items == nil {
+		items = []T{}
+	}
+	resp := pageResponse[T]{
+		Items:  items,
+		Total:  total,
+		Limit:  page.Limit,
+		Offset: page.Offset,
+		Links: pageLinks{
+			Self:  r.URL.RequestURI(),
+			First: pageURL(r, page.Limit, 0, ""),
+		},
+	}
+
+	if page.After != nil {
+		// Keyset paging only moves forward; a full page means there may be more
+		if len(items) == page.Limit {
+			resp.NextCursor = cursorOf(items[len(items)-1]).Encode()
+			resp.Links.Next = pageURL(r, page.Limit, 0, resp.NextCursor)
+		}
+		return resp
+	}
+
+	if int64(page.Offset+page.Limit) < total {
+		resp.Links.Next = pageURL(r, page.Limit, page.Offset+page.Limit, "")
+		if len(items) > 0 {
+			resp.NextCursor = cursorOf(items[len(items)-1]).Encode()
+		}
+	}
+	if page.Offset > 0 {
+		prev := page.Offset - page.Limit
+		if prev < 0 {
+			prev = 0
+		}
+		resp.Links.Prev = pageURL(r, page.Limit, prev, "")
+	}
+	if total > 0 {
+		last := int((total - 1) / int64(page.Limit) * int64(page.Limit))
+		resp.Links.Last = pageURL(r, page.Limit, last, "")
+	}
+	return resp
+}
+
+// pageURL rewrites the paging parameters of the request URL, keeping filters
+func pageURL(r *http.Request, limit, offset int, cursor string) string {
+	values := url.Values{}
+	for k, v := range r.URL.Query() {
+		values[k] = v
+	}
+	values.Set("limit", strconv.Itoa(limit))
+	values.Del("offset")
+	values.Del("cursor")
+	if cursor != "" {
+		values.Set("cursor", cursor)
+	} else if offset > 0 {
+		values.Set("offset", strconv.Itoa(offset))
+	}
+	u := url.URL{Path: r.URL.Path, RawQuery: values.Encode()}
+	return u.String()
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+
+	"robohub-inventory/pkg/patch"
+)
+
+// maxPatchBytes bounds the body of a PATCH request
+const maxPatchBytes = 1 << 20
+
+// readPatch parses the request body as the patch format named by its
+// Content-Type, writing the error response if it cannot
+func readPatch(w http.ResponseWriter, r *http.Request) (*patch.Patch, bool) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPatchBytes))
+	if err != nil {
+		writeDecodeError(w, r, err)
+		return nil, false
+	}
+	p, err := patch.Parse(r.Header.Get("Content-Type"), body)
+	if err != nil {
+		writeError(w, r, err)
+		return nil, false
+	}
+	return p, true
+}
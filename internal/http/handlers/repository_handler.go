@@ -3,9 +3,10 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
-	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"robohub-inventory/internal/http/response"
+	"robohub-inventory/pkg/query"
 	"robohub-inventory/pkg/repository"
 )
 
@@ -20,89 +21,180 @@ func NewRepositoryHandler(service *repository.Service) *RepositoryHandler {
 func (h *RepositoryHandler) CreateRepository(w http.ResponseWriter, r *http.Request) {
 	var repo repository.Repository
 	if err := json.NewDecoder(r.Body).Decode(&repo); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeDecodeError(w, r, err)
 		return
 	}
 
 	if err := h.service.CreateRepository(r.Context(), &repo); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(repo)
+	writeEntity(w, r, http.StatusCreated, repo.Revision, repo)
 }
 
 func (h *RepositoryHandler) GetRepository(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		writeInvalidID(w, r)
 		return
 	}
 
 	repo, err := h.service.GetRepository(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		writeError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(repo)
+	writeEntity(w, r, http.StatusOK, repo.Revision, repo)
 }
 
-func (h *RepositoryHandler) ListRepositories(w http.ResponseWriter, r *http.Request) {
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+func (h *RepositoryHandler) BatchGetRepositories(w http.ResponseWriter, r *http.Request) {
+	var req batchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	items, notFound, err := h.service.GetRepositoriesBatch(r.Context(), req.IDs)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, batchResponse[*repository.Repository]{Items: items, NotFound: notFound})
+}
 
-	if limit <= 0 {
-		limit = 100
+func (h *RepositoryHandler) ListRepositories(w http.ResponseWriter, r *http.Request) {
+	spec, err := query.Parse(r.URL.Query(), repository.ListSchema)
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
 
-	repos, err := h.service.ListRepositories(r.Context(), limit, offset)
+	repos, total, err := h.service.ListRepositories(r.Context(), spec)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(repos)
+	response.JSON(w, http.StatusOK, newPageResponse(r, repos, total, spec.Page, repositoryCursor))
 }
 
 func (h *RepositoryHandler) UpdateRepository(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		writeInvalidID(w, r)
 		return
 	}
 
 	var repo repository.Repository
 	if err := json.NewDecoder(r.Body).Decode(&repo); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeDecodeError(w, r, err)
 		return
 	}
 
+	revision, err := ifMatch(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if revision != 0 {
+		repo.Revision = revision
+	}
+
 	repo.ID = id
 	if err := h.service.UpdateRepository(r.Context(), &repo); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
+		return
+	}
+
+	writeEntity(w, r, http.StatusOK, repo.Revision, repo)
+}
+
+// PatchRepository handles PATCH /repositories/{id} with a merge patch or JSON Patch body
+func (h *RepositoryHandler) PatchRepository(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		writeInvalidID(w, r)
+		return
+	}
+
+	revision, err := ifMatch(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	p, ok := readPatch(w, r)
+	if !ok {
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(repo)
+	repo, err := h.service.PatchRepository(r.Context(), id, revision, p)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeEntity(w, r, http.StatusOK, repo.Revision, repo)
 }
 
 func (h *RepositoryHandler) DeleteRepository(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		writeInvalidID(w, r)
+		return
+	}
+
+	revision, err := ifMatch(r)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	if err := h.service.DeleteRepository(r.Context(), id); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.service.DeleteRepository(r.Context(), id, revision); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// ListDeletedRepositories handles GET /repositories/trash
+func (h *RepositoryHandler) ListDeletedRepositories(w http.ResponseWriter, r *http.Request) {
+	spec, err := query.Parse(r.URL.Query(), repository.ListSchema)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	repos, total, err := h.service.ListDeletedRepositories(r.Context(), spec)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, newPageResponse(r, repos, total, spec.Page, repositoryCursor))
+}
+
+// RestoreRepository handles POST /repositories/{id}/restore
+func (h *RepositoryHandler) RestoreRepository(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		writeInvalidID(w, r)
+		return
+	}
+
+	repo, err := h.service.RestoreRepository(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeEntity(w, r, http.StatusOK, repo.Revision, repo)
+}
+
+func repositoryCursor(repo *repository.Repository) query.Cursor {
+	return query.Cursor{CreatedAt: repo.CreatedAt, ID: repo.ID}
+}
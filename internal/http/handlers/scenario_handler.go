@@ -3,9 +3,10 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
-	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"robohub-inventory/internal/http/response"
+	"robohub-inventory/pkg/query"
 	"robohub-inventory/pkg/scenario"
 )
 
@@ -20,89 +21,180 @@ func NewScenarioHandler(service *scenario.Service) *ScenarioHandler {
 func (h *ScenarioHandler) CreateScenario(w http.ResponseWriter, r *http.Request) {
 	var s scenario.Scenario
 	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeDecodeError(w, r, err)
 		return
 	}
 
 	if err := h.service.CreateScenario(r.Context(), &s); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(s)
+	writeEntity(w, r, http.StatusCreated, s.Revision, s)
 }
 
 func (h *ScenarioHandler) GetScenario(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		writeInvalidID(w, r)
 		return
 	}
 
 	s, err := h.service.GetScenario(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		writeError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(s)
+	writeEntity(w, r, http.StatusOK, s.Revision, s)
 }
 
-func (h *ScenarioHandler) ListScenarios(w http.ResponseWriter, r *http.Request) {
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+func (h *ScenarioHandler) BatchGetScenarios(w http.ResponseWriter, r *http.Request) {
+	var req batchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	items, notFound, err := h.service.GetScenariosBatch(r.Context(), req.IDs)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, batchResponse[*scenario.Scenario]{Items: items, NotFound: notFound})
+}
 
-	if limit <= 0 {
-		limit = 100
+func (h *ScenarioHandler) ListScenarios(w http.ResponseWriter, r *http.Request) {
+	spec, err := query.Parse(r.URL.Query(), scenario.ListSchema)
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
 
-	scenarios, err := h.service.ListScenarios(r.Context(), limit, offset)
+	scenarios, total, err := h.service.ListScenarios(r.Context(), spec)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(scenarios)
+	response.JSON(w, http.StatusOK, newPageResponse(r, scenarios, total, spec.Page, scenarioCursor))
 }
 
 func (h *ScenarioHandler) UpdateScenario(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		writeInvalidID(w, r)
 		return
 	}
 
 	var s scenario.Scenario
 	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeDecodeError(w, r, err)
 		return
 	}
 
+	revision, err := ifMatch(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if revision != 0 {
+		s.Revision = revision
+	}
+
 	s.ID = id
 	if err := h.service.UpdateScenario(r.Context(), &s); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
+		return
+	}
+
+	writeEntity(w, r, http.StatusOK, s.Revision, s)
+}
+
+// PatchScenario handles PATCH /scenarios/{id} with a merge patch or JSON Patch body
+func (h *ScenarioHandler) PatchScenario(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		writeInvalidID(w, r)
+		return
+	}
+
+	revision, err := ifMatch(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	p, ok := readPatch(w, r)
+	if !ok {
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(s)
+	s, err := h.service.PatchScenario(r.Context(), id, revision, p)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeEntity(w, r, http.StatusOK, s.Revision, s)
 }
 
 func (h *ScenarioHandler) DeleteScenario(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		writeInvalidID(w, r)
+		return
+	}
+
+	revision, err := ifMatch(r)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	if err := h.service.DeleteScenario(r.Context(), id); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.service.DeleteScenario(r.Context(), id, revision); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// ListDeletedScenarios handles GET /scenarios/trash
+func (h *ScenarioHandler) ListDeletedScenarios(w http.ResponseWriter, r *http.Request) {
+	spec, err := query.Parse(r.URL.Query(), scenario.ListSchema)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	scenarios, total, err := h.service.ListDeletedScenarios(r.Context(), spec)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, newPageResponse(r, scenarios, total, spec.Page, scenarioCursor))
+}
+
+// RestoreScenario handles POST /scenarios/{id}/restore
+func (h *ScenarioHandler) RestoreScenario(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		writeInvalidID(w, r)
+		return
+	}
+
+	s, err := h.service.RestoreScenario(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeEntity(w, r, http.StatusOK, s.Revision, s)
+}
+
+func scenarioCursor(s *scenario.Scenario) query.Cursor {
+	return query.Cursor{CreatedAt: s.CreatedAt, ID: s.ID}
+}
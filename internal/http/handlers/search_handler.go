@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"robohub-inventory/internal/http/response"
+	"robohub-inventory/pkg/query"
+	"robohub-inventory/pkg/search"
+)
+
+type SearchHandler struct {
+	service *search.Service
+}
+
+func NewSearchHandler(service *search.Service) *SearchHandler {
+	return &SearchHandler{service: service}
+}
+
+// Search serves GET /search across packages, scenarios, datasets and repositories
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	page, err := query.ParsePage(params)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	q := search.Query{
+		Text:   params.Get("q"),
+		Limit:  page.Limit,
+		Offset: page.Offset,
+	}
+	for _, t := range query.Values(params, "types") {
+		q.Types = append(q.Types, search.Type(t))
+	}
+
+	results, err := h.service.Search(r.Context(), q)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, results)
+}
+
+// SearchPackages serves GET /packages/search with package-specific filters
+func (h *SearchHandler) SearchPackages(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	params := r.URL.Query()
+	page, err := query.ParsePage(params)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	q := search.PackageQuery{
+		Text:   params.Get("q"),
+		Types:  query.Values(params, "types"),
+		Limit:  page.Limit,
+		Offset: page.Offset,
+	}
+	if v := params.Get("minValidationRate"); v != "" {
+		rate, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			writeError(w, r, fmt.Errorf("%w: minValidationRate must be a number", search.ErrInvalidSearch))
+			return
+		}
+		q.MinValidationRate = &rate
+	}
+	if v := params.Get("hasDocumentation"); v != "" {
+		hasDocs, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, r, fmt.Errorf("%w: hasDocumentation must be true or false", search.ErrInvalidSearch))
+			return
+		}
+		q.HasDocumentation = &hasDocs
+	}
+
+	results, err := h.service.SearchPackages(r.Context(), q)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, struct {
+		*search.PackageResults
+		ExecutionTimeMs int64 `json:"executionTimeMs"`
+	}{results, time.Since(start).Milliseconds()})
+}
@@ -3,9 +3,10 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
-	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"robohub-inventory/internal/http/response"
+	"robohub-inventory/pkg/query"
 	"robohub-inventory/pkg/simulator"
 )
 
@@ -20,89 +21,180 @@ func NewSimulatorHandler(service *simulator.Service) *SimulatorHandler {
 func (h *SimulatorHandler) CreateSimulator(w http.ResponseWriter, r *http.Request) {
 	var s simulator.Simulator
 	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeDecodeError(w, r, err)
 		return
 	}
 
 	if err := h.service.CreateSimulator(r.Context(), &s); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(s)
+	writeEntity(w, r, http.StatusCreated, s.Revision, s)
 }
 
 func (h *SimulatorHandler) GetSimulator(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		writeInvalidID(w, r)
 		return
 	}
 
 	s, err := h.service.GetSimulator(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		writeError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(s)
+	writeEntity(w, r, http.StatusOK, s.Revision, s)
 }
 
-func (h *SimulatorHandler) ListSimulators(w http.ResponseWriter, r *http.Request) {
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+func (h *SimulatorHandler) BatchGetSimulators(w http.ResponseWriter, r *http.Request) {
+	var req batchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	items, notFound, err := h.service.GetSimulatorsBatch(r.Context(), req.IDs)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, batchResponse[*simulator.Simulator]{Items: items, NotFound: notFound})
+}
 
-	if limit <= 0 {
-		limit = 100
+func (h *SimulatorHandler) ListSimulators(w http.ResponseWriter, r *http.Request) {
+	spec, err := query.Parse(r.URL.Query(), simulator.ListSchema)
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
 
-	simulators, err := h.service.ListSimulators(r.Context(), limit, offset)
+	simulators, total, err := h.service.ListSimulators(r.Context(), spec)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(simulators)
+	response.JSON(w, http.StatusOK, newPageResponse(r, simulators, total, spec.Page, simulatorCursor))
 }
 
 func (h *SimulatorHandler) UpdateSimulator(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		writeInvalidID(w, r)
 		return
 	}
 
 	var s simulator.Simulator
 	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeDecodeError(w, r, err)
 		return
 	}
 
+	revision, err := ifMatch(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if revision != 0 {
+		s.Revision = revision
+	}
+
 	s.ID = id
 	if err := h.service.UpdateSimulator(r.Context(), &s); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
+		return
+	}
+
+	writeEntity(w, r, http.StatusOK, s.Revision, s)
+}
+
+// PatchSimulator handles PATCH /simulators/{id} with a merge patch or JSON Patch body
+func (h *SimulatorHandler) PatchSimulator(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		writeInvalidID(w, r)
+		return
+	}
+
+	revision, err := ifMatch(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	p, ok := readPatch(w, r)
+	if !ok {
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(s)
+	s, err := h.service.PatchSimulator(r.Context(), id, revision, p)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeEntity(w, r, http.StatusOK, s.Revision, s)
 }
 
 func (h *SimulatorHandler) DeleteSimulator(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
-		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		writeInvalidID(w, r)
+		return
+	}
+
+	revision, err := ifMatch(r)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	if err := h.service.DeleteSimulator(r.Context(), id); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.service.DeleteSimulator(r.Context(), id, revision); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// ListDeletedSimulators handles GET /simulators/trash
+func (h *SimulatorHandler) ListDeletedSimulators(w http.ResponseWriter, r *http.Request) {
+	spec, err := query.Parse(r.URL.Query(), simulator.ListSchema)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	simulators, total, err := h.service.ListDeletedSimulators(r.Context(), spec)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, newPageResponse(r, simulators, total, spec.Page, simulatorCursor))
+}
+
+// RestoreSimulator handles POST /simulators/{id}/restore
+func (h *SimulatorHandler) RestoreSimulator(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		writeInvalidID(w, r)
+		return
+	}
+
+	s, err := h.service.RestoreSimulator(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeEntity(w, r, http.StatusOK, s.Revision, s)
+}
+
+func simulatorCursor(s *simulator.Simulator) query.Cursor {
+	return query.Cursor{CreatedAt: s.CreatedAt, ID: s.ID}
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"robohub-inventory/internal/http/response"
+	"robohub-inventory/pkg/query"
+	"robohub-inventory/pkg/webhook"
+)
+
+type WebhookHandler struct {
+	service *webhook.Service
+}
+
+func NewWebhookHandler(service *webhook.Service) *WebhookHandler {
+	return &WebhookHandler{service: service}
+}
+
+// webhookRequest is the body of POST /webhooks and PUT /webhooks/{id}
+type webhookRequest struct {
+	URL         string   `json:"url"`
+	Description string   `json:"description,omitempty"`
+	Events      []string `json:"events,omitempty"` // Empty subscribes to every event type
+	Secret      string   `json:"secret,omitempty"` // Generated on create if empty; kept on update if empty
+	Active      *bool    `json:"active,omitempty"` // Defaults to true; true re-enables a disabled subscription
+}
+
+func (req webhookRequest) subscription(id string) *webhook.Subscription {
+	return &webhook.Subscription{
+		ID:          id,
+		URL:         req.URL,
+		Description: req.Description,
+		Events:      req.Events,
+		Secret:      req.Secret,
+		Active:      req.Active == nil || *req.Active,
+	}
+}
+
+// createdWebhook includes the signing secret, which is only shown once
+type createdWebhook struct {
+	*webhook.Subscription
+	Secret string `json:"secret"`
+}
+
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	sub := req.subscription("")
+	secret, err := h.service.CreateSubscription(r.Context(), sub)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, createdWebhook{Subscription: sub, Secret: secret})
+}
+
+func (h *WebhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	subs, err := h.service.ListSubscriptions(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	page := query.Page{Limit: max(len(subs), 1)}
+	response.JSON(w, http.StatusOK, newPageResponse(r, subs, int64(len(subs)), page, webhookCursor))
+}
+
+func (h *WebhookHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		writeInvalidID(w, r)
+		return
+	}
+
+	sub, err := h.service.GetSubscription(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, sub)
+}
+
+func (h *WebhookHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		writeInvalidID(w, r)
+		return
+	}
+
+	var req webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	sub := req.subscription(id)
+	if err := h.service.UpdateSubscription(r.Context(), sub); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, sub)
+}
+
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		writeInvalidID(w, r)
+		return
+	}
+
+	if err := h.service.DeleteSubscription(r.Context(), id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveries handles GET /webhooks/{id}/deliveries, the delivery log of a subscription
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		writeInvalidID(w, r)
+		return
+	}
+
+	page, err := query.ParsePage(r.URL.Query())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	deliveries, total, err := h.service.ListDeliveries(r.Context(), id, page)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, newPageResponse(r, deliveries, total, page, deliveryCursor))
+}
+
+// Redeliver handles POST /webhooks/{id}/deliveries/{deliveryId}/redeliver
+func (h *WebhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	id, deliveryID := chi.URLParam(r, "id"), chi.URLParam(r, "deliveryId")
+	if id == "" || deliveryID == "" {
+		writeInvalidID(w, r)
+		return
+	}
+
+	d, err := h.service.Redeliver(r.Context(), id, deliveryID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response.JSON(w, http.StatusAccepted, d)
+}
+
+func webhookCursor(s *webhook.Subscription) query.Cursor {
+	return query.Cursor{CreatedAt: s.CreatedAt, ID: s.ID}
+}
+
+func deliveryCursor(d *webhook.Delivery) query.Cursor {
+	return query.Cursor{CreatedAt: d.CreatedAt, ID: d.ID}
+}
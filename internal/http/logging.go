@@ -0,0 +1,63 @@
+package http
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+
+	"robohub-inventory/internal/logger"
+)
+
+// RequestLogger attaches the request ID, method, path and route to every
+// record logged while serving the request, and logs the request once served.
+// It must run after middleware.RequestID and ClientIP.Resolve.
+func RequestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ctx := logger.WithFields(r.Context(),
+			"request_id", middleware.GetReqID(r.Context()),
+			"method", r.Method,
+			"path", r.URL.Path,
+			"route", route{chi.RouteContext(r.Context())},
+		)
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		slog.Log(ctx, level, "Request served",
+			"status", status,
+			"bytes", ww.BytesWritten(),
+			"duration_ms", float64(time.Since(start).Microseconds())/1000,
+			"remote_addr", r.RemoteAddr,
+			"client_ip", ClientIPFrom(r.Context()),
+		)
+	})
+}
+
+// route logs the chi route pattern of a request, which is only complete once
+// routing is done
+type route struct {
+	rctx *chi.Context
+}
+
+func (r route) LogValue() slog.Value {
+	return slog.StringValue(routePattern(r.rctx))
+}
+
+// routePattern returns the matched route pattern, or "unmatched"
+func routePattern(rctx *chi.Context) string {
+	if rctx == nil || rctx.RoutePattern() == "" {
+		return "unmatched"
+	}
+	return rctx.RoutePattern()
+}
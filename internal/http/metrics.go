@@ -0,0 +1,27 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+
+	"robohub-inventory/internal/metrics"
+)
+
+// Metrics records the count and latency of every request, labeled by the chi
+// route pattern rather than the path so that IDs do not become labels
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		metrics.ObserveRequest(r.Method, routePattern(chi.RouteContext(r.Context())), status, time.Since(start))
+	})
+}
@@ -0,0 +1,204 @@
+package http
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"robohub-inventory/internal/http/response"
+	"robohub-inventory/pkg/auth"
+)
+
+// RateLimitStore keeps the token buckets of rate-limited clients
+type RateLimitStore interface {
+	// Take removes one token from the bucket of key, which holds up to limit
+	// tokens and refills completely over window
+	Take(ctx context.Context, key string, limit int, window time.Duration) (RateLimitResult, error)
+}
+
+// RateLimitResult is the state of a bucket after a Take
+type RateLimitResult struct {
+	Allowed   bool
+	Remaining int
+	Reset     time.Time // When the bucket is full again
+	Retry     time.Time // When the next token is available, for rejected requests
+}
+
+// RateLimits are the per-window request limits of API_CONTRACT.md §10
+type RateLimits struct {
+	Public        int // Anonymous callers, keyed by IP
+	Authenticated int // Users and API keys
+	Webhook       int // Internal agents delivering events
+	Window        time.Duration
+}
+
+// RateLimiter throttles API requests per client with token buckets
+type RateLimiter struct {
+	store  RateLimitStore
+	limits RateLimits
+}
+
+// NewRateLimiter creates a rate limiter backed by store
+func NewRateLimiter(store RateLimitStore, limits RateLimits) *RateLimiter {
+	return &RateLimiter{store: store, limits: limits}
+}
+
+// Limit rejects requests over the caller's limit with 429 and reports the
+// bucket state in X-RateLimit-* headers. It must run after Authenticate.
+// Store failures are logged and let the request through.
+func (l *RateLimiter) Limit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, limit := l.client(r)
+		if l.take(w, r, key, limit) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// ChargeFailure takes a token from the bucket of the client IP for a request
+// whose credentials were rejected, so that guessing API keys, agent IDs or
+// tokens is limited like anonymous requests. Such requests never reach Limit.
+// It reports false, having answered 429, if the client is over the limit.
+func (l *RateLimiter) ChargeFailure(w http.ResponseWriter, r *http.Request) bool {
+	return l.take(w, r, "ip:"+clientHost(r), l.limits.Public)
+}
+
+// take removes a token from the bucket of key and reports the bucket state in
+// X-RateLimit-* headers. It reports false, having answered 429, if the bucket
+// is empty. Store failures are logged and let the request through.
+func (l *RateLimiter) take(w http.ResponseWriter, r *http.Request, key string, limit int) bool {
+	res, err := l.store.Take(r.Context(), key, limit, l.limits.Window)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Rate limit store failed", "client", key, "error", err)
+		return true
+	}
+
+	h := w.Header()
+	h.Set("X-RateLimit-Limit", strconv.Itoa(limit))
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("X-RateLimit-Reset", strconv.FormatInt(res.Reset.Unix(), 10))
+
+	if !res.Allowed {
+		retryAfter := int(math.Ceil(time.Until(res.Retry).Seconds()))
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+		h.Set("Retry-After", strconv.Itoa(retryAfter))
+		response.Error(w, r, http.StatusTooManyRequests, response.CodeRateLimited, "rate limit exceeded",
+			map[string]interface{}{"limit": limit, "retryAfter": retryAfter})
+		return false
+	}
+	return true
+}
+
+// client returns the bucket key and limit of the caller: the API key, the
+// bearer subject or agent ID, or the client IP for anonymous requests. The
+// client IP is the socket peer unless ClientIP resolved it from the
+// forwarding headers of a trusted proxy.
+func (l *RateLimiter) client(r *http.Request) (string, int) {
+	if p, ok := auth.FromContext(r.Context()); ok {
+		switch p.Kind {
+		case auth.KindAPIKey:
+			return "key:" + p.KeyID, l.limits.Authenticated
+		case auth.KindAgent:
+			return "agent:" + p.Subject, l.limits.Webhook
+		default:
+			return "sub:" + p.Subject, l.limits.Authenticated
+		}
+	}
+	return "ip:" + clientHost(r), l.limits.Public
+}
+
+// clientHost returns the client IP of the request, falling back to the socket
+// peer when ClientIP did not run
+func clientHost(r *http.Request) string {
+	if host := ClientIPFrom(r.Context()); host != "" {
+		return host
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// MemoryRateLimitStore keeps buckets in process memory. Each replica of the
+// service limits independently.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time // When the bucket refills completely
+}
+
+// NewMemoryRateLimitStore creates an empty in-memory store
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: make(map[string]*bucket), now: time.Now}
+}
+
+func (s *MemoryRateLimitStore) Take(_ context.Context, key string, limit int, window time.Duration) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now, window)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit), updated: now}
+		s.buckets[key] = b
+	}
+
+	rate := float64(limit) / window.Seconds()
+	b.tokens = math.Min(float64(limit), b.tokens+now.Sub(b.updated).Seconds()*rate)
+	b.updated = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	b.full = now.Add(secondsToDuration((float64(limit) - b.tokens) / rate))
+
+	return bucketResult(allowed, b.tokens, rate, now, b.full), nil
+}
+
+// sweep drops full buckets once per window so idle clients do not accumulate
+func (s *MemoryRateLimitStore) sweep(now time.Time, window time.Duration) {
+	if now.Sub(s.lastSweep) < window {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// bucketResult describes a bucket holding tokens after a Take
+func bucketResult(allowed bool, tokens, rate float64, now, full time.Time) RateLimitResult {
+	res := RateLimitResult{
+		Allowed:   allowed,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     full,
+	}
+	if !allowed {
+		res.Retry = now.Add(secondsToDuration((1 - tokens) / rate))
+	}
+	return res
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
@@ -0,0 +1,71 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// refilledTokens is the content of an existing bucket after refilling it for
+// the time since its last update
+const refilledTokens = "LEAST(@limit::float8, b.tokens + EXTRACT(EPOCH FROM now() - b.updated_at)::float8 * @rate::float8)"
+
+// takeTokenSQL refills and takes from a bucket in one atomic upsert, using
+// the database clock so that all replicas agree on elapsed time. SET
+// expressions all see the row as it was before the update.
+var takeTokenSQL = fmt.Sprintf(`
+	INSERT INTO rate_limit_buckets AS b (key, tokens, allowed, updated_at)
+	VALUES (@key, @limit::float8 - 1, true, now())
+	ON CONFLICT (key) DO UPDATE SET
+		tokens = CASE WHEN %[1]s >= 1 THEN %[1]s - 1 ELSE %[1]s END,
+		allowed = %[1]s >= 1,
+		updated_at = now()
+	RETURNING tokens, allowed, updated_at`, refilledTokens)
+
+// PostgresRateLimitStore shares buckets between replicas through PostgreSQL
+type PostgresRateLimitStore struct {
+	db        *gorm.DB
+	lastPurge atomic.Int64
+}
+
+// NewPostgresRateLimitStore creates a store in the rate_limit_buckets table,
+// which is created by migration 0003
+func NewPostgresRateLimitStore(db *gorm.DB) *PostgresRateLimitStore {
+	return &PostgresRateLimitStore{db: db}
+}
+
+func (s *PostgresRateLimitStore) Take(ctx context.Context, key string, limit int, window time.Duration) (RateLimitResult, error) {
+	s.purge(ctx, window)
+
+	rate := float64(limit) / window.Seconds()
+	var row struct {
+		Tokens    float64
+		Allowed   bool
+		UpdatedAt time.Time
+	}
+	err := s.db.WithContext(ctx).Raw(takeTokenSQL, map[string]interface{}{
+		"key":   key,
+		"limit": limit,
+		"rate":  rate,
+	}).Scan(&row).Error
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+
+	full := row.UpdatedAt.Add(secondsToDuration((float64(limit) - row.Tokens) / rate))
+	return bucketResult(row.Allowed, row.Tokens, rate, row.UpdatedAt, full), nil
+}
+
+// purge deletes buckets idle for longer than a window, at most once per window
+func (s *PostgresRateLimitStore) purge(ctx context.Context, window time.Duration) {
+	now := time.Now().UnixNano()
+	last := s.lastPurge.Load()
+	if now-last < int64(window) || !s.lastPurge.CompareAndSwap(last, now) {
+		return
+	}
+	s.db.WithContext(ctx).Exec("DELETE FROM rate_limit_buckets WHERE updated_at < now() - ?::interval",
+		fmt.Sprintf("%d seconds", int(window.Seconds())))
+}
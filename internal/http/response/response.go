@@ -0,0 +1,57 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"robohub-inventory/internal/tracing"
+)
+
+// Machine-readable error codes (API_CONTRACT.md §6)
+const (
+	CodeValidation           = "VALIDATION_ERROR"
+	CodeUnauthorized         = "UNAUTHORIZED"
+	CodeForbidden            = "FORBIDDEN"
+	CodeNotFound             = "NOT_FOUND"
+	CodeConflict             = "CONFLICT"
+	CodeNameConflict         = "NAME_CONFLICT"
+	CodePreconditionFailed   = "PRECONDITION_FAILED"
+	CodeUnsupportedMediaType = "UNSUPPORTED_MEDIA_TYPE"
+	CodeInvalidReference     = "INVALID_REFERENCE"
+	CodeRateLimited          = "RATE_LIMITED"
+	CodeInternal             = "INTERNAL_ERROR"
+	CodeServiceUnavailable   = "SERVICE_UNAVAILABLE"
+)
+
+// ErrorBody is the error envelope returned by every endpoint
+type ErrorBody struct {
+	Status    int                    `json:"status"`
+	Error     string                 `json:"error"`
+	Message   string                 `json:"message"`
+	Code      string                 `json:"code,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	RequestID string                 `json:"requestId,omitempty"`
+	TraceID   string                 `json:"traceId,omitempty"`
+}
+
+// JSON writes v as a JSON response with the given status code
+func JSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// Error writes an error envelope, tagging it with the chi request ID and the trace ID
+func Error(w http.ResponseWriter, r *http.Request, status int, code, message string, details map[string]interface{}) {
+	JSON(w, status, ErrorBody{
+		Status:    status,
+		Error:     http.StatusText(status),
+		Message:   message,
+		Code:      code,
+		Details:   details,
+		RequestID: middleware.GetReqID(r.Context()),
+		TraceID:   tracing.TraceID(r.Context()),
+	})
+}
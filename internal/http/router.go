@@ -4,12 +4,22 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"robohub-inventory/internal/buildinfo"
+	"robohub-inventory/internal/health"
 	"robohub-inventory/internal/http/handlers"
+	"robohub-inventory/internal/metrics"
+	"robohub-inventory/pkg/apikey"
+	"robohub-inventory/pkg/bulk"
+	"robohub-inventory/pkg/counter"
 	"robohub-inventory/pkg/dataset"
+	"robohub-inventory/pkg/event"
+	"robohub-inventory/pkg/identity"
 	pkg "robohub-inventory/pkg/package"
 	"robohub-inventory/pkg/repository"
 	"robohub-inventory/pkg/scenario"
+	"robohub-inventory/pkg/search"
 	"robohub-inventory/pkg/simulator"
+	"robohub-inventory/pkg/webhook"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -21,79 +31,213 @@ func NewRouter(
 	scenarioService *scenario.Service,
 	datasetService *dataset.Service,
 	simulatorService *simulator.Service,
+	searchService *search.Service,
+	bulkService *bulk.Service,
+	counterService *counter.Service,
+	apiKeyService *apikey.Service,
+	identityService *identity.Service,
+	webhookService *webhook.Service,
+	feed *event.Feed,
+	clientIP *ClientIP,
+	authenticator *Authenticator,
+	rateLimiter *RateLimiter,
+	checks *health.Health,
 ) *chi.Mux {
 	r := chi.NewRouter()
 
 	// Middleware
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
-	r.Use(middleware.Logger)
+	r.Use(clientIP.Resolve)
+	r.Use(Tracing)
+	r.Use(RequestLogger)
+	r.Use(Metrics)
 	r.Use(middleware.Recoverer)
 
 	// Handlers
-	healthHandler := handlers.NewHealthHandler()
+	healthHandler := handlers.NewHealthHandler(checks)
 	packageHandler := handlers.NewPackageHandler(pkgService)
 	repositoryHandler := handlers.NewRepositoryHandler(repoService)
 	scenarioHandler := handlers.NewScenarioHandler(scenarioService)
 	datasetHandler := handlers.NewDatasetHandler(datasetService)
 	simulatorHandler := handlers.NewSimulatorHandler(simulatorService)
+	searchHandler := handlers.NewSearchHandler(searchService)
+	bulkHandler := handlers.NewBulkHandler(bulkService)
+	adminHandler := handlers.NewAdminHandler(counterService)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
+	identityHandler := handlers.NewIdentityHandler(identityService)
+	webhookHandler := handlers.NewWebhookHandler(webhookService)
+	eventHandler := handlers.NewEventHandler(feed)
 
 	// Routes
 	r.Get("/health", healthHandler.Health)
+	r.Get("/livez", healthHandler.Live)
+	r.Get("/readyz", healthHandler.Ready)
+	r.Get("/version", healthHandler.Version)
+	r.Handle("/metrics", metrics.Handler())
 	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(map[string]string{
 			"message": "Welcome to RoboHub Inventory Service",
-			"version": "1.0.0",
+			"version": buildinfo.Get().Version,
 		})
 	})
 
-	// API routes
+	// API routes. Reads are public; writes and key management need credentials.
+	// Requests are rate limited per client once the caller is known, and
+	// rejected credentials are charged to the client IP by Authenticate.
 	r.Route("/api/v1", func(r chi.Router) {
+		r.Use(authenticator.Authenticate)
+		if rateLimiter != nil {
+			r.Use(rateLimiter.Limit)
+		}
+
+		// Search
+		r.Get("/search", searchHandler.Search)
+
+		// Change stream of the entities visible to the caller
+		r.Get("/events/stream", eventHandler.Stream)
+
+		// Mixed-type bulk import
+		r.With(authenticator.RequireAuth).Post("/bulk", bulkHandler.Import)
+
 		// Packages
 		r.Route("/packages", func(r chi.Router) {
-			r.Post("/", packageHandler.CreatePackage)
 			r.Get("/", packageHandler.ListPackages)
+			r.Get("/search", searchHandler.SearchPackages)
 			r.Get("/{id}", packageHandler.GetPackage)
-			r.Put("/{id}", packageHandler.UpdatePackage)
-			r.Delete("/{id}", packageHandler.DeletePackage)
+			r.Post("/batch", packageHandler.BatchGetPackages)
+
+			r.Group(func(r chi.Router) {
+				r.Use(authenticator.RequireAuth)
+				r.Post("/", packageHandler.CreatePackage)
+				r.Post("/bulk", bulkHandler.ImportPackages)
+				r.Put("/{id}", packageHandler.UpdatePackage)
+				r.Patch("/{id}", packageHandler.PatchPackage)
+				r.Delete("/{id}", packageHandler.DeletePackage)
+				r.Get("/trash", packageHandler.ListDeletedPackages)
+				r.Post("/{id}/restore", packageHandler.RestorePackage)
+			})
 		})
 
 		// Repositories
 		r.Route("/repositories", func(r chi.Router) {
-			r.Post("/", repositoryHandler.CreateRepository)
 			r.Get("/", repositoryHandler.ListRepositories)
 			r.Get("/{id}", repositoryHandler.GetRepository)
-			r.Put("/{id}", repositoryHandler.UpdateRepository)
-			r.Delete("/{id}", repositoryHandler.DeleteRepository)
+			r.Post("/batch", repositoryHandler.BatchGetRepositories)
+
+			r.Group(func(r chi.Router) {
+				r.Use(authenticator.RequireAuth)
+				r.Post("/", repositoryHandler.CreateRepository)
+				r.Post("/bulk", bulkHandler.ImportRepositories)
+				r.Put("/{id}", repositoryHandler.UpdateRepository)
+				r.Patch("/{id}", repositoryHandler.PatchRepository)
+				r.Delete("/{id}", repositoryHandler.DeleteRepository)
+				r.Get("/trash", repositoryHandler.ListDeletedRepositories)
+				r.Post("/{id}/restore", repositoryHandler.RestoreRepository)
+			})
 		})
 
 		// Scenarios
 		r.Route("/scenarios", func(r chi.Router) {
-			r.Post("/", scenarioHandler.CreateScenario)
 			r.Get("/", scenarioHandler.ListScenarios)
 			r.Get("/{id}", scenarioHandler.GetScenario)
-			r.Put("/{id}", scenarioHandler.UpdateScenario)
-			r.Delete("/{id}", scenarioHandler.DeleteScenario)
+			r.Post("/batch", scenarioHandler.BatchGetScenarios)
+
+			r.Group(func(r chi.Router) {
+				r.Use(authenticator.RequireAuth)
+				r.Post("/", scenarioHandler.CreateScenario)
+				r.Post("/bulk", bulkHandler.ImportScenarios)
+				r.Put("/{id}", scenarioHandler.UpdateScenario)
+				r.Patch("/{id}", scenarioHandler.PatchScenario)
+				r.Delete("/{id}", scenarioHandler.DeleteScenario)
+				r.Get("/trash", scenarioHandler.ListDeletedScenarios)
+				r.Post("/{id}/restore", scenarioHandler.RestoreScenario)
+			})
 		})
 
 		// Datasets
 		r.Route("/datasets", func(r chi.Router) {
-			r.Post("/", datasetHandler.CreateDataset)
 			r.Get("/", datasetHandler.ListDatasets)
 			r.Get("/{id}", datasetHandler.GetDataset)
-			r.Put("/{id}", datasetHandler.UpdateDataset)
-			r.Delete("/{id}", datasetHandler.DeleteDataset)
+			r.Post("/batch", datasetHandler.BatchGetDatasets)
+
+			r.Group(func(r chi.Router) {
+				r.Use(authenticator.RequireAuth)
+				r.Post("/", datasetHandler.CreateDataset)
+				r.Post("/bulk", bulkHandler.ImportDatasets)
+				r.Put("/{id}", datasetHandler.UpdateDataset)
+				r.Patch("/{id}", datasetHandler.PatchDataset)
+				r.Delete("/{id}", datasetHandler.DeleteDataset)
+				r.Get("/trash", datasetHandler.ListDeletedDatasets)
+				r.Post("/{id}/restore", datasetHandler.RestoreDataset)
+			})
 		})
 
 		// Simulators
 		r.Route("/simulators", func(r chi.Router) {
-			r.Post("/", simulatorHandler.CreateSimulator)
 			r.Get("/", simulatorHandler.ListSimulators)
 			r.Get("/{id}", simulatorHandler.GetSimulator)
-			r.Put("/{id}", simulatorHandler.UpdateSimulator)
-			r.Delete("/{id}", simulatorHandler.DeleteSimulator)
+			r.Post("/batch", simulatorHandler.BatchGetSimulators)
+
+			r.Group(func(r chi.Router) {
+				r.Use(authenticator.RequireAuth)
+				r.Post("/", simulatorHandler.CreateSimulator)
+				r.Post("/bulk", bulkHandler.ImportSimulators)
+				r.Put("/{id}", simulatorHandler.UpdateSimulator)
+				r.Patch("/{id}", simulatorHandler.PatchSimulator)
+				r.Delete("/{id}", simulatorHandler.DeleteSimulator)
+				r.Get("/trash", simulatorHandler.ListDeletedSimulators)
+				r.Post("/{id}/restore", simulatorHandler.RestoreSimulator)
+			})
+		})
+
+		// Users
+		r.Route("/users", func(r chi.Router) {
+			r.With(authenticator.RequireAuth).Get("/me", identityHandler.GetCurrentUser)
+			r.Get("/{id}", identityHandler.GetUser)
+		})
+
+		// Organizations and their members
+		r.Route("/organizations", func(r chi.Router) {
+			r.Get("/", identityHandler.ListOrganizations)
+			r.Get("/{id}", identityHandler.GetOrganization)
+
+			r.Group(func(r chi.Router) {
+				r.Use(authenticator.RequireAuth)
+				r.Post("/", identityHandler.CreateOrganization)
+				r.Put("/{id}", identityHandler.UpdateOrganization)
+				r.Delete("/{id}", identityHandler.DeleteOrganization)
+				r.Get("/{id}/members", identityHandler.ListMembers)
+				r.Put("/{id}/members/{userId}", identityHandler.SetMember)
+				r.Delete("/{id}/members/{userId}", identityHandler.RemoveMember)
+			})
+		})
+
+		// Platform maintenance, limited to admins by the services
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(authenticator.RequireAuth)
+			r.Post("/reconcile", adminHandler.ReconcileCounters)
+		})
+
+		// API keys of the calling principal
+		r.Route("/api-keys", func(r chi.Router) {
+			r.Use(authenticator.RequireAuth)
+			r.Post("/", apiKeyHandler.CreateAPIKey)
+			r.Get("/", apiKeyHandler.ListAPIKeys)
+			r.Delete("/{id}", apiKeyHandler.RevokeAPIKey)
+		})
+
+		// Webhook subscriptions, limited to admins by the service
+		r.Route("/webhooks", func(r chi.Router) {
+			r.Use(authenticator.RequireAuth)
+			r.Post("/", webhookHandler.CreateWebhook)
+			r.Get("/", webhookHandler.ListWebhooks)
+			r.Get("/{id}", webhookHandler.GetWebhook)
+			r.Put("/{id}", webhookHandler.UpdateWebhook)
+			r.Delete("/{id}", webhookHandler.DeleteWebhook)
+			r.Get("/{id}/deliveries", webhookHandler.ListDeliveries)
+			r.Post("/{id}/deliveries/{deliveryId}/redeliver", webhookHandler.Redeliver)
 		})
 	})
 
@@ -2,31 +2,51 @@ package http
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"net/http"
-	"time"
 
 	"robohub-inventory/internal/config"
 )
 
 type Server struct {
 	httpServer *http.Server
+	tls        config.TLSConfig
 }
 
 func NewServer(cfg *config.ServerConfig, handler http.Handler) *Server {
+	minVersion := uint16(tls.VersionTLS12)
+	if cfg.TLS.MinVersion == "1.3" {
+		minVersion = tls.VersionTLS13
+	}
 	return &Server{
 		httpServer: &http.Server{
-			Addr:         fmt.Sprintf("%s:%s", cfg.Host, cfg.Port),
-			Handler:      handler,
-			ReadTimeout:  15 * time.Second,
-			WriteTimeout: 15 * time.Second,
-			IdleTimeout:  60 * time.Second,
+			Addr:              fmt.Sprintf("%s:%s", cfg.Host, cfg.Port),
+			Handler:           handler,
+			ReadTimeout:       cfg.ReadTimeout,
+			ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+			WriteTimeout:      cfg.WriteTimeout,
+			IdleTimeout:       cfg.IdleTimeout,
+			TLSConfig:         &tls.Config{MinVersion: minVersion},
 		},
+		tls: cfg.TLS,
 	}
 }
 
+// Start serves HTTPS when a certificate is configured, HTTP otherwise. It
+// returns nil once Shutdown is called.
 func (s *Server) Start() error {
-	return s.httpServer.ListenAndServe()
+	var err error
+	if s.tls.Enabled() {
+		err = s.httpServer.ListenAndServeTLS(s.tls.CertFile, s.tls.KeyFile)
+	} else {
+		err = s.httpServer.ListenAndServe()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
 }
 
 func (s *Server) Shutdown(ctx context.Context) error {
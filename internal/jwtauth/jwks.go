@@ -0,0 +1,186 @@
+package jwtauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// jwksTTL is how long a fetched key set is trusted
+	jwksTTL = 10 * time.Minute
+	// jwksMinRefresh throttles refetches triggered by unknown key IDs
+	jwksMinRefresh = 30 * time.Second
+)
+
+// jwk is a single JSON Web Key (RFC 7517); only public RSA and EC keys are used
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// jwks fetches and caches the signing keys published at a JWKS URL. The
+// set is fetched without holding the lock, once for all concurrent callers,
+// and swapped in when complete.
+type jwks struct {
+	url    string
+	client *http.Client
+	fetch  singleflight.Group
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+func newJWKS(url string) *jwks {
+	return &jwks{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// key returns the public key for kid, refreshing the set when it is stale
+// or the key ID is unknown
+func (j *jwks) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	key, ok, age := j.lookup(kid)
+	if ok && age <= jwksTTL {
+		return key, nil
+	}
+	if age > jwksMinRefresh {
+		if err := j.refresh(ctx); err != nil {
+			if ok {
+				// Keep serving a known key if the endpoint is temporarily down
+				return key, nil
+			}
+			return nil, err
+		}
+		if key, ok, _ := j.lookup(kid); ok {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookup returns the cached key for kid and the age of the cached set
+func (j *jwks) lookup(kid string) (crypto.PublicKey, bool, time.Duration) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	key, ok := j.keys[kid]
+	return key, ok, time.Since(j.fetchedAt)
+}
+
+// refresh fetches the key set, joining a fetch already in flight. The fetch
+// outlives the cancellation of the request that started it, since other
+// requests may be waiting for it.
+func (j *jwks) refresh(ctx context.Context) error {
+	_, err, _ := j.fetch.Do(j.url, func() (interface{}, error) {
+		keys, err := j.download(context.WithoutCancel(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrKeysUnavailable, err)
+		}
+		j.mu.Lock()
+		j.keys = keys
+		j.fetchedAt = time.Now()
+		j.mu.Unlock()
+		return nil, nil
+	})
+	return err
+}
+
+// download fetches and decodes the key set
+func (j *jwks) download(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch jwks: unexpected status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("failed to decode jwks: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = pub
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,115 @@
+package jwtauth
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"robohub-inventory/internal/config"
+	"robohub-inventory/pkg/auth"
+)
+
+var (
+	// ErrNotConfigured is returned when bearer tokens are presented but no key is configured
+	ErrNotConfigured = errors.New("bearer authentication is not configured")
+	ErrInvalidToken  = errors.New("invalid bearer token")
+	// ErrKeysUnavailable is returned when the JWKS endpoint cannot be
+	// reached, so a token cannot be checked either way
+	ErrKeysUnavailable = errors.New("jwt signing keys are unavailable")
+)
+
+// Claims are the JWT claims read from bearer tokens
+type Claims struct {
+	jwt.RegisteredClaims
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+}
+
+// Verifier validates bearer JWTs against a static key or a JWKS endpoint
+type Verifier struct {
+	hmacSecret []byte
+	publicKey  crypto.PublicKey
+	jwks       *jwks
+	parser     *jwt.Parser
+}
+
+// NewVerifier builds a verifier from the auth configuration. A verifier
+// without any key source rejects every token with ErrNotConfigured.
+func NewVerifier(cfg *config.AuthConfig) (*Verifier, error) {
+	v := &Verifier{}
+
+	var methods []string
+	switch {
+	case cfg.JWKSURL != "":
+		v.jwks = newJWKS(cfg.JWKSURL)
+		methods = []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "PS256", "PS384", "PS512"}
+	case cfg.JWTPublicKeyFile != "":
+		pemBytes, err := os.ReadFile(cfg.JWTPublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read jwt public key: %w", err)
+		}
+		if key, err := jwt.ParseRSAPublicKeyFromPEM(pemBytes); err == nil {
+			v.publicKey = key
+			methods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512"}
+		} else if key, err := jwt.ParseECPublicKeyFromPEM(pemBytes); err == nil {
+			v.publicKey = key
+			methods = []string{"ES256", "ES384", "ES512"}
+		} else {
+			return nil, fmt.Errorf("jwt public key is neither RSA nor EC")
+		}
+	case cfg.JWTSecret != "":
+		v.hmacSecret = []byte(cfg.JWTSecret)
+		methods = []string{"HS256", "HS384", "HS512"}
+	}
+
+	opts := []jwt.ParserOption{jwt.WithValidMethods(methods), jwt.WithExpirationRequired()}
+	if cfg.JWTIssuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.JWTIssuer))
+	}
+	if cfg.JWTAudience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.JWTAudience))
+	}
+	v.parser = jwt.NewParser(opts...)
+
+	return v, nil
+}
+
+// Verify validates a raw token and returns the principal it identifies
+func (v *Verifier) Verify(ctx context.Context, raw string) (*auth.Principal, error) {
+	if v.hmacSecret == nil && v.publicKey == nil && v.jwks == nil {
+		return nil, ErrNotConfigured
+	}
+
+	var claims Claims
+	_, err := v.parser.ParseWithClaims(raw, &claims, func(t *jwt.Token) (interface{}, error) {
+		switch {
+		case v.jwks != nil:
+			kid, _ := t.Header["kid"].(string)
+			return v.jwks.key(ctx, kid)
+		case v.publicKey != nil:
+			return v.publicKey, nil
+		default:
+			return v.hmacSecret, nil
+		}
+	})
+	if errors.Is(err, ErrKeysUnavailable) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidToken)
+	}
+
+	return &auth.Principal{
+		Kind:    auth.KindUser,
+		Subject: claims.Subject,
+		Name:    claims.Name,
+		Email:   claims.Email,
+	}, nil
+}
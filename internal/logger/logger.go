@@ -1,42 +1,159 @@
+// Package logger configures structured logging with log/slog. Records carry
+// the fields attached to their context, such as the request ID, user and
+// route of the request being served, and its trace ID. Sensitive values are
+// redacted.
 package logger
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
-type Logger struct {
-	info  *log.Logger
-	error *log.Logger
-	warn  *log.Logger
-	debug *log.Logger
+// Redacted replaces sensitive values in log records
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys are attribute keys whose values are never logged
+var sensitiveKeys = map[string]bool{
+	"password":      true,
+	"secret":        true,
+	"token":         true,
+	"authorization": true,
+	"api_key":       true,
+	"x-api-key":     true,
+}
+
+// sensitiveValues match credentials embedded in strings, such as a DSN
+// password or the user info of a connection URL
+var sensitiveValues = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`(?i)(password=)('(?:[^'\\]|\\.)*'|\S+)`), "${1}" + Redacted},
+	{regexp.MustCompile(`(://[^:/@\s]+:)[^@\s]+@`), "${1}" + Redacted + "@"},
+}
+
+// New creates a logger writing to w at level, in "json" or "text" format
+func New(w io.Writer, level slog.Leveler, format string) (*slog.Logger, error) {
+	opts := &slog.HandlerOptions{Level: level, ReplaceAttr: redact}
+	var handler slog.Handler
+	switch format {
+	case "json":
+		handler = slog.NewJSONHandler(w, opts)
+	case "text":
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+	return slog.New(contextHandler{handler}), nil
+}
+
+// ParseLevel parses debug, info, warn or error
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(s))
+	return level, err
+}
+
+// Fatal logs msg at error level and exits
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// Redact masks the credentials embedded in s
+func Redact(s string) string {
+	for _, v := range sensitiveValues {
+		s = v.pattern.ReplaceAllString(s, v.replacement)
+	}
+	return s
+}
+
+func redact(_ []string, a slog.Attr) slog.Attr {
+	if sensitiveKeys[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, Redacted)
+	}
+	switch v := a.Value.Any().(type) {
+	case string:
+		a.Value = slog.StringValue(Redact(v))
+	case error:
+		a.Value = slog.StringValue(Redact(v.Error()))
+	}
+	return a
+}
+
+// fields collects the attributes of a context. They are shared by reference,
+// so that middleware further down the chain can add to the fields logged by
+// middleware that came before.
+type fields struct {
+	mu    sync.Mutex
+	attrs []slog.Attr
+}
+
+type fieldsKey struct{}
+
+// WithFields starts a set of fields attached to every record logged with ctx
+// or a context derived from it
+func WithFields(ctx context.Context, args ...any) context.Context {
+	f := &fields{}
+	if parent, ok := ctx.Value(fieldsKey{}).(*fields); ok {
+		f.attrs = parent.snapshot()
+	}
+	f.add(args)
+	return context.WithValue(ctx, fieldsKey{}, f)
 }
 
-func New() *Logger {
-	return &Logger{
-		info:  log.New(os.Stdout, "[INFO] ", log.LstdFlags|log.Lshortfile),
-		error: log.New(os.Stderr, "[ERROR] ", log.LstdFlags|log.Lshortfile),
-		warn:  log.New(os.Stdout, "[WARN] ", log.LstdFlags|log.Lshortfile),
-		debug: log.New(os.Stdout, "[DEBUG] ", log.LstdFlags|log.Lshortfile),
+// AddFields adds fields to the set started by WithFields, if any
+func AddFields(ctx context.Context, args ...any) {
+	if f, ok := ctx.Value(fieldsKey{}).(*fields); ok {
+		f.add(args)
 	}
 }
 
-func (l *Logger) Info(format string, v ...interface{}) {
-	l.info.Printf(format, v...)
+func (f *fields) add(args []any) {
+	record := slog.Record{}
+	record.Add(args...)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	record.Attrs(func(a slog.Attr) bool {
+		f.attrs = append(f.attrs, a)
+		return true
+	})
 }
 
-func (l *Logger) Error(format string, v ...interface{}) {
-	l.error.Printf(format, v...)
+func (f *fields) snapshot() []slog.Attr {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]slog.Attr(nil), f.attrs...)
 }
 
-func (l *Logger) Warn(format string, v ...interface{}) {
-	l.warn.Printf(format, v...)
+// contextHandler adds the fields of the record context, and the IDs of its
+// trace and span
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if f, ok := ctx.Value(fieldsKey{}).(*fields); ok {
+		r.AddAttrs(f.snapshot()...)
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
 }
 
-func (l *Logger) Debug(format string, v ...interface{}) {
-	l.debug.Printf(format, v...)
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
 }
 
-func (l *Logger) Fatal(format string, v ...interface{}) {
-	l.error.Fatalf(format, v...)
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
 }
@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// scrapeTimeout bounds the catalog queries of one scrape
+const scrapeTimeout = 5 * time.Second
+
+// validationStatuses are always reported, so that a status with no packages reads 0
+var validationStatuses = []string{"pass", "fail", "pending"}
+
+var (
+	packagesDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "packages"),
+		"Packages by validation status, excluding the trash.", []string{"validation_status"}, nil)
+
+	queuedRunsDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "queued_runs"),
+		"Packages whose last scenario run is still pending.", nil, nil)
+
+	syncFailuresDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "repository_sync_failures"),
+		"Repositories whose last sync failed, excluding the trash.", nil, nil)
+)
+
+// catalogCollector reads the domain gauges from the database on every scrape,
+// so that they are shared by all replicas and never drift
+type catalogCollector struct {
+	db *gorm.DB
+}
+
+func newCatalogCollector(db *gorm.DB) *catalogCollector {
+	// Scrapes would otherwise flood the SQL log
+	return &catalogCollector{db: db.Session(&gorm.Session{Logger: db.Logger.LogMode(logger.Silent)})}
+}
+
+func (c *catalogCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- packagesDesc
+	ch <- queuedRunsDesc
+	ch <- syncFailuresDesc
+}
+
+func (c *catalogCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), scrapeTimeout)
+	defer cancel()
+	db := c.db.WithContext(ctx)
+
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	err := db.Raw(`
+		SELECT COALESCE(NULLIF(validation_status->>'status', ''), 'unknown') AS status, COUNT(*) AS count
+		FROM packages
+		WHERE deleted_at IS NULL
+		GROUP BY 1`).Scan(&rows).Error
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(packagesDesc, err)
+	} else {
+		counts := map[string]int64{}
+		for _, status := range validationStatuses {
+			counts[status] = 0
+		}
+		for _, row := range rows {
+			counts[row.Status] = row.Count
+		}
+		for status, count := range counts {
+			ch <- prometheus.MustNewConstMetric(packagesDesc, prometheus.GaugeValue, float64(count), status)
+		}
+	}
+
+	// Runs are not stored yet; the last run of each package is
+	c.count(ch, queuedRunsDesc, db.Table("packages").
+		Where("deleted_at IS NULL AND last_run->>'status' = ?", "pending"))
+	c.count(ch, syncFailuresDesc, db.Table("repositories").
+		Where("deleted_at IS NULL AND sync_status = ?", "error"))
+}
+
+// count reports the number of rows matched by stmt as the gauge desc
+func (c *catalogCollector) count(ch chan<- prometheus.Metric, desc *prometheus.Desc, stmt *gorm.DB) {
+	var count int64
+	if err := stmt.Count(&count).Error; err != nil {
+		ch <- prometheus.NewInvalidMetric(desc, err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(count))
+}
@@ -1,43 +1,71 @@
+// Package metrics exports Prometheus metrics of the service: HTTP requests,
+// database queries and connection pool, and gauges of the catalog.
 package metrics
 
 import (
+	"net/http"
+	"strconv"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-// Metrics tracks application metrics
-type Metrics struct {
-	RequestCount    int64
-	RequestDuration time.Duration
-	ErrorCount      int64
-}
+const namespace = "robohub"
 
-var globalMetrics *Metrics
+// Registry holds the metrics of the service, including the Go runtime and
+// process collectors
+var Registry = prometheus.NewRegistry()
 
-func Init() {
-	globalMetrics = &Metrics{}
-}
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, chi route pattern and status code.",
+	}, []string{"method", "route", "status"})
 
-func Get() *Metrics {
-	if globalMetrics == nil {
-		Init()
-	}
-	return globalMetrics
-}
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, chi route pattern and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
 
-func (m *Metrics) IncrementRequestCount() {
-	m.RequestCount++
-}
+	dbDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Database query latency by GORM operation and table.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table"})
+
+	dbErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_query_errors_total",
+		Help:      "Failed database queries by GORM operation and table; missing records are not errors.",
+	}, []string{"operation", "table"})
+)
 
-func (m *Metrics) IncrementErrorCount() {
-	m.ErrorCount++
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		dbDuration,
+		dbErrors,
+	)
 }
 
-func (m *Metrics) AddRequestDuration(duration time.Duration) {
-	m.RequestDuration += duration
+// Handler serves the registry in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
 }
 
-func (m *Metrics) Reset() {
-	m.RequestCount = 0
-	m.RequestDuration = 0
-	m.ErrorCount = 0
+// ObserveRequest records a served HTTP request. route is the chi route
+// pattern, which keeps the label set bounded.
+func ObserveRequest(method, route string, status int, duration time.Duration) {
+	code := strconv.Itoa(status)
+	httpRequests.WithLabelValues(method, route, code).Inc()
+	httpDuration.WithLabelValues(method, route, code).Observe(duration.Seconds())
 }
@@ -0,0 +1,93 @@
+package tracing
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const spanKey = "tracing:span"
+
+var tracer = otel.Tracer("robohub-inventory/internal/tracing")
+
+// Instrument records a client span for every statement run by db, as a
+// child of the span of the statement context. Bind variables are not
+// recorded.
+func Instrument(db *gorm.DB) error {
+	return db.Use(plugin{})
+}
+
+// plugin registers GORM callbacks around every kind of statement
+type plugin struct{}
+
+func (plugin) Name() string {
+	return "tracing"
+}
+
+func (plugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("tracing:before_create", start("create")),
+		cb.Create().After("gorm:create").Register("tracing:after_create", end),
+		cb.Query().Before("gorm:query").Register("tracing:before_query", start("query")),
+		cb.Query().After("gorm:query").Register("tracing:after_query", end),
+		cb.Update().Before("gorm:update").Register("tracing:before_update", start("update")),
+		cb.Update().After("gorm:update").Register("tracing:after_update", end),
+		cb.Delete().Before("gorm:delete").Register("tracing:before_delete", start("delete")),
+		cb.Delete().After("gorm:delete").Register("tracing:after_delete", end),
+		cb.Row().Before("gorm:row").Register("tracing:before_row", start("row")),
+		cb.Row().After("gorm:row").Register("tracing:after_row", end),
+		cb.Raw().Before("gorm:raw").Register("tracing:before_raw", start("raw")),
+		cb.Raw().After("gorm:raw").Register("tracing:after_raw", end),
+	)
+}
+
+// start opens the span of a statement
+func start(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		if ctx == nil || !trace.SpanFromContext(ctx).IsRecording() {
+			// Statements outside a sampled trace, such as metrics scrapes, are not traced
+			return
+		}
+		name := "db." + operation
+		if db.Statement.Table != "" {
+			name += " " + db.Statement.Table
+		}
+		_, span := tracer.Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(semconv.DBSystemPostgreSQL, semconv.DBOperation(operation)),
+		)
+		db.InstanceSet(spanKey, span)
+	}
+}
+
+// end closes the span opened by start with the statement and its outcome
+func end(db *gorm.DB) {
+	value, ok := db.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+
+	span.SetAttributes(
+		semconv.DBStatement(db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	)
+	if db.Statement.Table != "" {
+		span.SetAttributes(semconv.DBSQLTable(db.Statement.Table))
+	}
+	if err := db.Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
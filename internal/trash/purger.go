@@ -0,0 +1,71 @@
+// Package trash permanently deletes catalog entities once they have stayed
+// in the trash for longer than the retention period.
+package trash
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("robohub-inventory/internal/trash")
+
+// Target purges one entity type
+type Target struct {
+	Name  string
+	Purge func(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Purger periodically purges the trash of each target
+type Purger struct {
+	retention time.Duration
+	interval  time.Duration
+	targets   []Target
+	log       *slog.Logger
+}
+
+func NewPurger(retention, interval time.Duration, log *slog.Logger, targets ...Target) *Purger {
+	return &Purger{retention: retention, interval: interval, targets: targets, log: log}
+}
+
+// Run purges once right away and then every interval until ctx is done
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.Purge(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge deletes the entities moved to the trash more than the retention
+// period before now. A failing target is logged and does not stop the others.
+// Each purge starts a new trace.
+func (p *Purger) Purge(ctx context.Context, now time.Time) {
+	ctx, span := tracer.Start(ctx, "trash.Purge", trace.WithNewRoot())
+	defer span.End()
+
+	before := now.Add(-p.retention)
+	for _, t := range p.targets {
+		n, err := t.Purge(ctx, before)
+		if err != nil {
+			p.log.ErrorContext(ctx, "Failed to purge trash", "type", t.Name, "error", err)
+			span.RecordError(err, trace.WithAttributes(attribute.String("type", t.Name)))
+			span.SetStatus(codes.Error, "failed to purge trash")
+			continue
+		}
+		if n > 0 {
+			p.log.InfoContext(ctx, "Purged trash", "type", t.Name, "count", n)
+		}
+	}
+}
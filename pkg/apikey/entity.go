@@ -0,0 +1,34 @@
+package apikey
+
+import (
+	"time"
+)
+
+// APIKey is a hashed credential accepted via the X-API-Key header.
+// The plaintext key is only returned once, when the key is created.
+type APIKey struct {
+	ID         string     `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	Name       string     `gorm:"not null" json:"name"`
+	Prefix     string     `gorm:"uniqueIndex;not null" json:"prefix"` // Public lookup part of the key
+	Hash       string     `gorm:"not null" json:"-"`                  // SHA-256 of the full key, hex encoded
+	Subject    string     `gorm:"not null;index" json:"subject"`      // Principal the key acts as
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+
+	// Timestamps
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Active reports whether the key may be used at the given time
+func (k *APIKey) Active(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
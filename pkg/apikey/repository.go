@@ -0,0 +1,15 @@
+package apikey
+
+import (
+	"context"
+	"time"
+)
+
+// Repository defines the interface for API key persistence
+type Repository interface {
+	Create(ctx context.Context, key *APIKey) error
+	GetByPrefix(ctx context.Context, prefix string) (*APIKey, error)
+	ListBySubject(ctx context.Context, subject string) ([]*APIKey, error)
+	Revoke(ctx context.Context, id, subject string, at time.Time) error
+	TouchLastUsed(ctx context.Context, id string, at time.Time) error
+}
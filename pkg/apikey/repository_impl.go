@@ -0,0 +1,57 @@
+package apikey
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"robohub-inventory/pkg/store"
+)
+
+// gormRepository implements the Repository interface using GORM
+type gormRepository struct {
+	db *gorm.DB
+}
+
+// NewRepository creates a new GORM-based API key repository
+func NewRepository(db *gorm.DB) Repository {
+	return &gormRepository{db: db}
+}
+
+func (r *gormRepository) Create(ctx context.Context, key *APIKey) error {
+	return store.Conn(ctx, r.db).Create(key).Error
+}
+
+func (r *gormRepository) GetByPrefix(ctx context.Context, prefix string) (*APIKey, error) {
+	var key APIKey
+	err := store.Conn(ctx, r.db).Where("prefix = ?", prefix).First(&key).Error
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *gormRepository) ListBySubject(ctx context.Context, subject string) ([]*APIKey, error) {
+	var keys []*APIKey
+	err := store.Conn(ctx, r.db).Where("subject = ?", subject).Order("created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+func (r *gormRepository) Revoke(ctx context.Context, id, subject string, at time.Time) error {
+	result := store.Conn(ctx, r.db).Model(&APIKey{}).
+		Where("id = ? AND subject = ? AND revoked_at IS NULL", id, subject).
+		Update("revoked_at", at)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *gormRepository) TouchLastUsed(ctx context.Context, id string, at time.Time) error {
+	return store.Conn(ctx, r.db).Model(&APIKey{}).Where("id = ?", id).
+		UpdateColumn("last_used_at", at).Error
+}
@@ -0,0 +1,154 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"gorm.io/gorm"
+	"robohub-inventory/pkg/auth"
+)
+
+var (
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	ErrInvalidAPIKey  = errors.New("invalid api key data")
+	// ErrUnauthenticated is returned when a presented key is unknown, expired or revoked
+	ErrUnauthenticated = errors.New("invalid or expired api key")
+)
+
+const (
+	keyScheme = "rhk"
+	// lastUsedResolution limits how often usage timestamps are written
+	lastUsedResolution = time.Minute
+)
+
+var tracer = otel.Tracer("robohub-inventory/pkg/apikey")
+
+// Service handles business logic for API keys
+type Service struct {
+	repo Repository
+	now  func() time.Time
+}
+
+func NewService(repo Repository) *Service {
+	return &Service{repo: repo, now: time.Now}
+}
+
+// CreateKey issues a key for subject and returns it with its plaintext secret.
+// The secret is not stored and cannot be recovered afterwards. Only users
+// may create keys, so a key or an agent cannot mint further credentials.
+func (s *Service) CreateKey(ctx context.Context, subject, name string, expiresAt *time.Time) (*APIKey, string, error) {
+	ctx, span := tracer.Start(ctx, "apikey.Service.CreateKey")
+	defer span.End()
+	p, ok := auth.FromContext(ctx)
+	if !ok {
+		return nil, "", auth.ErrUnauthenticated
+	}
+	if p.Kind != auth.KindUser {
+		return nil, "", fmt.Errorf("%w: api keys must be created by a user", auth.ErrForbidden)
+	}
+	if subject == "" {
+		return nil, "", fmt.Errorf("%w: subject is required", ErrInvalidAPIKey)
+	}
+	if strings.TrimSpace(name) == "" {
+		return nil, "", fmt.Errorf("%w: name is required", ErrInvalidAPIKey)
+	}
+	if expiresAt != nil && !expiresAt.After(s.now()) {
+		return nil, "", fmt.Errorf("%w: expiresAt must be in the future", ErrInvalidAPIKey)
+	}
+
+	prefix, err := randomString(6)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := randomString(24)
+	if err != nil {
+		return nil, "", err
+	}
+	plaintext := fmt.Sprintf("%s_%s_%s", keyScheme, prefix, secret)
+
+	key := &APIKey{
+		Name:      name,
+		Prefix:    prefix,
+		Hash:      hashKey(plaintext),
+		Subject:   subject,
+		ExpiresAt: expiresAt,
+	}
+	if err := s.repo.Create(ctx, key); err != nil {
+		return nil, "", err
+	}
+	return key, plaintext, nil
+}
+
+// Authenticate resolves a plaintext key presented by a client
+func (s *Service) Authenticate(ctx context.Context, plaintext string) (*APIKey, error) {
+	ctx, span := tracer.Start(ctx, "apikey.Service.Authenticate")
+	defer span.End()
+	parts := strings.Split(plaintext, "_")
+	if len(parts) != 3 || parts[0] != keyScheme {
+		return nil, ErrUnauthenticated
+	}
+
+	key, err := s.repo.GetByPrefix(ctx, parts[1])
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUnauthenticated
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hashKey(plaintext))) != 1 {
+		return nil, ErrUnauthenticated
+	}
+
+	now := s.now()
+	if !key.Active(now) {
+		return nil, ErrUnauthenticated
+	}
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedResolution {
+		if err := s.repo.TouchLastUsed(ctx, key.ID, now); err != nil {
+			return nil, err
+		}
+		key.LastUsedAt = &now
+	}
+	return key, nil
+}
+
+// ListKeys returns the keys owned by subject
+func (s *Service) ListKeys(ctx context.Context, subject string) ([]*APIKey, error) {
+	ctx, span := tracer.Start(ctx, "apikey.Service.ListKeys")
+	defer span.End()
+	return s.repo.ListBySubject(ctx, subject)
+}
+
+// RevokeKey disables a key owned by subject
+func (s *Service) RevokeKey(ctx context.Context, subject, id string) error {
+	ctx, span := tracer.Start(ctx, "apikey.Service.RevokeKey")
+	defer span.End()
+	err := s.repo.Revoke(ctx, id, subject, s.now())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrAPIKeyNotFound
+	}
+	return err
+}
+
+func hashKey(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomString returns n random bytes, base64url encoded without underscores
+func randomString(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	return strings.ReplaceAll(base64.RawURLEncoding.EncodeToString(buf), "_", "-"), nil
+}
@@ -0,0 +1,69 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"robohub-inventory/pkg/auth"
+)
+
+// memoryRepository records created keys
+type memoryRepository struct {
+	Repository
+	created []*APIKey
+}
+
+func (r *memoryRepository) Create(ctx context.Context, key *APIKey) error {
+	r.created = append(r.created, key)
+	return nil
+}
+
+func TestCreateKeyRequiresUser(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		wantErr   error
+	}{
+		{name: "user", principal: &auth.Principal{Kind: auth.KindUser, Subject: "user-1"}},
+		{name: "api key", principal: &auth.Principal{Kind: auth.KindAPIKey, Subject: "user-1", KeyID: "key-1"}, wantErr: auth.ErrForbidden},
+		{name: "agent", principal: &auth.Principal{Kind: auth.KindAgent, Subject: "agent-1"}, wantErr: auth.ErrForbidden},
+		{name: "anonymous", wantErr: auth.ErrUnauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &memoryRepository{}
+			ctx := context.Background()
+			if tt.principal != nil {
+				ctx = auth.WithPrincipal(ctx, tt.principal)
+			}
+
+			key, plaintext, err := NewService(repo).CreateKey(ctx, "user-1", "ci", nil)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				if len(repo.created) != 0 {
+					t.Errorf("created %d keys, want none", len(repo.created))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateKey: %v", err)
+			}
+			if len(repo.created) != 1 || key.Subject != "user-1" || plaintext == "" {
+				t.Errorf("key = %+v, plaintext = %q, created %d", key, plaintext, len(repo.created))
+			}
+		})
+	}
+}
+
+func TestCreateKeyRejectsPastExpiry(t *testing.T) {
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Kind: auth.KindUser, Subject: "user-1"})
+	past := time.Now().Add(-time.Hour)
+
+	if _, _, err := NewService(&memoryRepository{}).CreateKey(ctx, "user-1", "ci", &past); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Fatalf("err = %v, want %v", err, ErrInvalidAPIKey)
+	}
+}
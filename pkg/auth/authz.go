@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrUnauthenticated = errors.New("authentication required")
+	ErrForbidden       = errors.New("permission denied")
+	ErrInvalidOwner    = errors.New("invalid owner")
+)
+
+// Owner types of catalog entities
+const (
+	OwnerUser         = "user"
+	OwnerOrganization = "organization"
+)
+
+// Role is a member's role within an organization
+type Role string
+
+const (
+	RoleViewer     Role = "viewer"
+	RoleMaintainer Role = "maintainer"
+	RoleAdmin      Role = "admin"
+)
+
+var roleRank = map[Role]int{
+	RoleViewer:     1,
+	RoleMaintainer: 2,
+	RoleAdmin:      3,
+}
+
+// Valid reports whether r is a known role
+func (r Role) Valid() bool {
+	_, ok := roleRank[r]
+	return ok
+}
+
+// AtLeast reports whether r grants at least the permissions of min
+func (r Role) AtLeast(min Role) bool {
+	return roleRank[r] >= roleRank[min]
+}
+
+// OwnerRef references the user or organization owning an entity
+type OwnerRef struct {
+	Type string
+	ID   string
+}
+
+// RoleIn returns the principal's role in an organization, if a member
+func (p *Principal) RoleIn(orgID string) (Role, bool) {
+	role, ok := p.Orgs[orgID]
+	return role, ok
+}
+
+// CanModify checks that the caller may update or delete an entity owned by
+// owner: platform admins, the owning user, or maintainers of the owning org.
+func CanModify(ctx context.Context, owner OwnerRef) error {
+	p, ok := FromContext(ctx)
+	if !ok {
+		return ErrUnauthenticated
+	}
+	if p.Admin {
+		return nil
+	}
+	switch owner.Type {
+	case OwnerUser:
+		if owner.ID != "" && owner.ID == p.UserID {
+			return nil
+		}
+	case OwnerOrganization:
+		if role, ok := p.RoleIn(owner.ID); ok && role.AtLeast(RoleMaintainer) {
+			return nil
+		}
+	}
+	return ErrForbidden
+}
+
+// AssignOwner validates the owner requested for a new entity, defaulting to
+// the calling user. Only admins may create entities for other users.
+func AssignOwner(ctx context.Context, requested OwnerRef) (OwnerRef, error) {
+	p, ok := FromContext(ctx)
+	if !ok {
+		return OwnerRef{}, ErrUnauthenticated
+	}
+
+	if requested.ID == "" {
+		if p.UserID == "" {
+			// Agents act for the platform; their entities stay admin-managed
+			return OwnerRef{}, nil
+		}
+		return OwnerRef{Type: OwnerUser, ID: p.UserID}, nil
+	}
+
+	switch requested.Type {
+	case OwnerUser, OwnerOrganization:
+	default:
+		return OwnerRef{}, fmt.Errorf("%w: ownerType must be %q or %q", ErrInvalidOwner, OwnerUser, OwnerOrganization)
+	}
+	if err := CanModify(ctx, requested); err != nil {
+		return OwnerRef{}, err
+	}
+	return requested, nil
+}
+
+// RequireAdmin checks that the caller is a platform admin
+func RequireAdmin(ctx context.Context) error {
+	p, ok := FromContext(ctx)
+	if !ok {
+		return ErrUnauthenticated
+	}
+	if !p.Admin {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// Reassign checks that the caller may modify an entity owned by current and
+// returns the owner to store. An empty requested owner keeps the current one;
+// a different owner must also be one the caller may assign.
+func Reassign(ctx context.Context, current, requested OwnerRef) (OwnerRef, error) {
+	if err := CanModify(ctx, current); err != nil {
+		return OwnerRef{}, err
+	}
+	if requested.ID == "" || requested == current {
+		return current, nil
+	}
+	return AssignOwner(ctx, requested)
+}
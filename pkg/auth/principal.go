@@ -0,0 +1,39 @@
+package auth
+
+import "context"
+
+// Kind identifies how a caller authenticated
+type Kind string
+
+const (
+	KindUser   Kind = "user"    // Bearer JWT
+	KindAPIKey Kind = "api_key" // X-API-Key
+	KindAgent  Kind = "agent"   // X-Agent-ID, internal integrations
+)
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	Kind    Kind   `json:"kind"`
+	Subject string `json:"subject"`         // JWT subject, key owner subject or agent ID
+	Name    string `json:"name,omitempty"`  // Display name, when known
+	Email   string `json:"email,omitempty"` // From JWT claims, when present
+	KeyID   string `json:"keyId,omitempty"` // API key ID for KindAPIKey
+
+	// Resolved by the identity service after authentication
+	UserID string          `json:"userId,omitempty"` // Local user backing the subject
+	Orgs   map[string]Role `json:"orgs,omitempty"`   // Organization ID -> role
+	Admin  bool            `json:"admin,omitempty"`  // Platform admin (agents and configured subjects)
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a context carrying the authenticated principal
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the authenticated principal, if any
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
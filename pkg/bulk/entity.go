@@ -0,0 +1,75 @@
+package bulk
+
+import "encoding/json"
+
+// Type is the entity type of an import item
+type Type string
+
+const (
+	TypeRepository Type = "repository"
+	TypeSimulator  Type = "simulator"
+	TypeDataset    Type = "dataset"
+	TypeScenario   Type = "scenario"
+	TypePackage    Type = "package"
+)
+
+// importOrder imports referenced entities first, so that e.g. a package can
+// be imported together with its repository
+var importOrder = map[Type]int{
+	TypeRepository: 0,
+	TypeSimulator:  1,
+	TypeDataset:    2,
+	TypeScenario:   3,
+	TypePackage:    4,
+}
+
+// Action is the outcome of importing one item
+type Action string
+
+const (
+	ActionCreated  Action = "created"
+	ActionUpdated  Action = "updated"
+	ActionRejected Action = "rejected"
+)
+
+// Item is one entity of an import, in its JSON form
+type Item struct {
+	Index int // Position in the request
+	Type  Type
+	Data  json.RawMessage
+}
+
+// Options control how an import is applied
+type Options struct {
+	DryRun bool // Report the outcome, then roll everything back
+	Atomic bool // Roll back every item if any item is rejected
+}
+
+// ItemResult reports the outcome of one item
+type ItemResult struct {
+	Index  int        `json:"index"`
+	Type   Type       `json:"type"`
+	Name   string     `json:"name,omitempty"`
+	ID     string     `json:"id,omitempty"` // Only for committed items
+	Action Action     `json:"action"`
+	Err    error      `json:"-"`
+	Error  *ItemError `json:"error,omitempty"` // Err as reported to the client
+}
+
+// ItemError describes why an item was rejected
+type ItemError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Result reports the outcome of an import, with one result per item in
+// request order
+type Result struct {
+	DryRun    bool         `json:"dryRun"`
+	Atomic    bool         `json:"atomic"`
+	Committed bool         `json:"committed"`
+	Created   int          `json:"created"`
+	Updated   int          `json:"updated"`
+	Rejected  int          `json:"rejected"`
+	Items     []ItemResult `json:"items"`
+}
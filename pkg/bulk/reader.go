@@ -0,0 +1,102 @@
+package bulk
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"unicode"
+)
+
+// MaxItems is the largest number of items a single import accepts
+const MaxItems = 1000
+
+var ErrInvalidImport = errors.New("invalid import")
+
+// envelope wraps an item of a mixed-type import
+type envelope struct {
+	Type Type            `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// ReadItems reads import items from a JSON array or an NDJSON stream. Items
+// are entities of typ, or envelopes {"type": ..., "data": {...}} when typ is
+// empty.
+func ReadItems(r io.Reader, typ Type) ([]Item, error) {
+	raws, err := readValues(bufio.NewReader(r))
+	if err != nil {
+		return nil, err
+	}
+	if len(raws) == 0 {
+		return nil, fmt.Errorf("%w: no items", ErrInvalidImport)
+	}
+	if len(raws) > MaxItems {
+		return nil, fmt.Errorf("%w: at most %d items are allowed, got %d", ErrInvalidImport, MaxItems, len(raws))
+	}
+
+	items := make([]Item, len(raws))
+	for i, raw := range raws {
+		item := Item{Index: i, Type: typ, Data: raw}
+		if typ == "" {
+			var env envelope
+			if err := json.Unmarshal(raw, &env); err != nil {
+				return nil, fmt.Errorf("%w: item %d: %v", ErrInvalidImport, i, err)
+			}
+			if _, ok := importOrder[env.Type]; !ok {
+				return nil, fmt.Errorf("%w: item %d: unknown type %q", ErrInvalidImport, i, env.Type)
+			}
+			if len(env.Data) == 0 {
+				return nil, fmt.Errorf("%w: item %d: data is required", ErrInvalidImport, i)
+			}
+			item.Type, item.Data = env.Type, env.Data
+		}
+		items[i] = item
+	}
+	return items, nil
+}
+
+// readValues reads a JSON array, or whitespace-separated JSON values
+func readValues(r *bufio.Reader) ([]json.RawMessage, error) {
+	first, err := peekNonSpace(r)
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+
+	dec := json.NewDecoder(r)
+	if first == '[' {
+		var raws []json.RawMessage
+		if err := dec.Decode(&raws); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+		}
+		return raws, nil
+	}
+
+	var raws []json.RawMessage
+	for {
+		var raw json.RawMessage
+		err := dec.Decode(&raw)
+		if err == io.EOF {
+			return raws, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidImport, len(raws)+1, err)
+		}
+		raws = append(raws, raw)
+	}
+}
+
+func peekNonSpace(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if !unicode.IsSpace(rune(b)) {
+			return b, r.UnreadByte()
+		}
+	}
+}
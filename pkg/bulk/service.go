@@ -0,0 +1,165 @@
+package bulk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"go.opentelemetry.io/otel"
+
+	"robohub-inventory/pkg/dataset"
+	pkg "robohub-inventory/pkg/package"
+	"robohub-inventory/pkg/repository"
+	"robohub-inventory/pkg/scenario"
+	"robohub-inventory/pkg/simulator"
+	"robohub-inventory/pkg/store"
+)
+
+// errRollback discards the import transaction after a dry run or a rejected atomic import
+var errRollback = errors.New("import rolled back")
+
+var tracer = otel.Tracer("robohub-inventory/pkg/bulk")
+
+// Service imports entities in bulk, upserting them by name through the
+// domain services so that validation and authorization still apply
+type Service struct {
+	tx           *store.Transactor
+	packages     *pkg.Service
+	repositories *repository.Service
+	scenarios    *scenario.Service
+	datasets     *dataset.Service
+	simulators   *simulator.Service
+}
+
+func NewService(
+	tx *store.Transactor,
+	packages *pkg.Service,
+	repositories *repository.Service,
+	scenarios *scenario.Service,
+	datasets *dataset.Service,
+	simulators *simulator.Service,
+) *Service {
+	return &Service{
+		tx:           tx,
+		packages:     packages,
+		repositories: repositories,
+		scenarios:    scenarios,
+		datasets:     datasets,
+		simulators:   simulators,
+	}
+}
+
+// Import upserts items in one transaction, each under its own savepoint so
+// that a rejected item does not abort the others. Atomic imports commit only
+// if no item was rejected; dry runs never commit.
+func (s *Service) Import(ctx context.Context, items []Item, opts Options) (*Result, error) {
+	ctx, span := tracer.Start(ctx, "bulk.Service.Import")
+	defer span.End()
+	res := &Result{DryRun: opts.DryRun, Atomic: opts.Atomic, Items: make([]ItemResult, len(items))}
+
+	ordered := make([]Item, len(items))
+	copy(ordered, items)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return importOrder[ordered[i].Type] < importOrder[ordered[j].Type]
+	})
+
+	err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		for _, item := range ordered {
+			r := ItemResult{Index: item.Index, Type: item.Type}
+			var created bool
+			err := s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+				var err error
+				r.Name, r.ID, created, err = s.upsert(ctx, item)
+				return err
+			})
+			switch {
+			case err != nil:
+				r.Action, r.Err, r.ID = ActionRejected, err, ""
+				res.Rejected++
+			case created:
+				r.Action = ActionCreated
+				res.Created++
+			default:
+				r.Action = ActionUpdated
+				res.Updated++
+			}
+			res.Items[item.Index] = r
+		}
+		if opts.DryRun || (opts.Atomic && res.Rejected > 0) {
+			return errRollback
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errRollback) {
+		return nil, err
+	}
+
+	res.Committed = err == nil
+	if !res.Committed {
+		// IDs assigned inside the rolled back transaction do not exist
+		for i := range res.Items {
+			if res.Items[i].Action == ActionCreated {
+				res.Items[i].ID = ""
+			}
+		}
+	}
+	return res, nil
+}
+
+// upsert decodes and stores one item. Items are matched to existing
+// entities by name, so any ID in the payload is ignored.
+func (s *Service) upsert(ctx context.Context, item Item) (name, id string, created bool, err error) {
+	switch item.Type {
+	case TypePackage:
+		var p pkg.Package
+		if err := decode(item, &p); err != nil {
+			return "", "", false, err
+		}
+		p.ID = ""
+		created, err = s.packages.UpsertPackage(ctx, &p)
+		return p.Name, p.ID, created, err
+	case TypeRepository:
+		var r repository.Repository
+		if err := decode(item, &r); err != nil {
+			return "", "", false, err
+		}
+		r.ID = ""
+		created, err = s.repositories.UpsertRepository(ctx, &r)
+		return r.Name, r.ID, created, err
+	case TypeScenario:
+		var sc scenario.Scenario
+		if err := decode(item, &sc); err != nil {
+			return "", "", false, err
+		}
+		sc.ID = ""
+		created, err = s.scenarios.UpsertScenario(ctx, &sc)
+		return sc.Name, sc.ID, created, err
+	case TypeDataset:
+		var d dataset.Dataset
+		if err := decode(item, &d); err != nil {
+			return "", "", false, err
+		}
+		d.ID = ""
+		created, err = s.datasets.UpsertDataset(ctx, &d)
+		return d.Name, d.ID, created, err
+	case TypeSimulator:
+		var sim simulator.Simulator
+		if err := decode(item, &sim); err != nil {
+			return "", "", false, err
+		}
+		sim.ID = ""
+		created, err = s.simulators.UpsertSimulator(ctx, &sim)
+		return sim.Name, sim.ID, created, err
+	}
+	return "", "", false, fmt.Errorf("%w: unknown type %q", ErrInvalidImport, item.Type)
+}
+
+// decode unmarshals the JSON of an item into its entity
+func decode(item Item, v interface{}) error {
+	if err := json.Unmarshal(item.Data, v); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+	return nil
+}
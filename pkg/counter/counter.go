@@ -0,0 +1,108 @@
+// Package counter maintains the relationship counters of catalog entities.
+// Counters are recomputed from the relations they summarize rather than
+// incremented, so that every refresh also repairs earlier drift. Only live
+// rows are counted:
+//
+//   - repositories.package_count: packages of the repository
+//   - scenarios.used_by_packages_count: packages whose last run used the scenario
+//   - packages.linked_scenarios_count: the scenario of the last run, if any
+//   - packages.linked_datasets_count: datasets recommended by that scenario
+//   - packages.used_in_collections_count: always 0 until collections exist
+package counter
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Read-only counter columns of each table, left out of entity updates
+var (
+	RepositoryColumns = []string{"package_count"}
+	PackageColumns    = []string{"linked_scenarios_count", "linked_datasets_count", "used_in_collections_count"}
+	ScenarioColumns   = []string{"used_by_packages_count"}
+)
+
+const repositoriesSQL = `
+UPDATE repositories SET package_count = c.packages
+FROM (
+	SELECT repositories.id, COUNT(packages.id) AS packages
+	FROM repositories
+	LEFT JOIN packages ON packages.repo_id = repositories.id AND packages.deleted_at IS NULL
+	WHERE %s
+	GROUP BY repositories.id
+) c
+WHERE repositories.id = c.id AND repositories.package_count IS DISTINCT FROM c.packages`
+
+const scenariosSQL = `
+UPDATE scenarios SET used_by_packages_count = c.packages
+FROM (
+	SELECT scenarios.id, COUNT(packages.id) AS packages
+	FROM scenarios
+	LEFT JOIN packages ON packages.last_run_scenario_id = scenarios.id AND packages.deleted_at IS NULL
+	WHERE %s
+	GROUP BY scenarios.id
+) c
+WHERE scenarios.id = c.id AND scenarios.used_by_packages_count IS DISTINCT FROM c.packages`
+
+const packagesSQL = `
+UPDATE packages SET
+	linked_scenarios_count = c.scenarios,
+	linked_datasets_count = c.datasets,
+	used_in_collections_count = 0
+FROM (
+	SELECT packages.id, COUNT(DISTINCT scenarios.id) AS scenarios, COUNT(datasets.id) AS datasets
+	FROM packages
+	LEFT JOIN scenarios ON scenarios.id = packages.last_run_scenario_id AND scenarios.deleted_at IS NULL
+	LEFT JOIN scenario_recommended_datasets links ON links.scenario_id = scenarios.id
+	LEFT JOIN datasets ON datasets.id = links.dataset_id AND datasets.deleted_at IS NULL
+	WHERE %s
+	GROUP BY packages.id
+) c
+WHERE packages.id = c.id
+AND (packages.linked_scenarios_count, packages.linked_datasets_count, packages.used_in_collections_count)
+	IS DISTINCT FROM (c.scenarios, c.datasets, 0)`
+
+// Repositories recomputes the package count of the repositories with the given IDs
+func Repositories(db *gorm.DB, ids ...string) error {
+	return refresh(db, repositoriesSQL, "repositories.id", ids)
+}
+
+// Scenarios recomputes the package count of the scenarios with the given IDs
+func Scenarios(db *gorm.DB, ids ...string) error {
+	return refresh(db, scenariosSQL, "scenarios.id", ids)
+}
+
+// Packages recomputes the link counters of the packages with the given IDs
+func Packages(db *gorm.DB, ids ...string) error {
+	return refresh(db, packagesSQL, "packages.id", ids)
+}
+
+// PackagesOfScenarios recomputes the link counters of the packages whose last
+// run used one of the given scenarios
+func PackagesOfScenarios(db *gorm.DB, scenarioIDs ...string) error {
+	return refresh(db, packagesSQL, "packages.last_run_scenario_id", scenarioIDs)
+}
+
+// PackagesOfDataset recomputes the link counters of the packages whose last
+// run used a scenario recommending the dataset
+func PackagesOfDataset(db *gorm.DB, datasetID string) error {
+	return db.Exec(fmt.Sprintf(packagesSQL,
+		"packages.last_run_scenario_id IN (SELECT scenario_id FROM scenario_recommended_datasets WHERE dataset_id = ?)"),
+		datasetID).Error
+}
+
+// refresh runs stmt for the rows whose column is one of ids. Empty IDs stand
+// for absent references and are skipped.
+func refresh(db *gorm.DB, stmt, column string, ids []string) error {
+	var present []string
+	for _, id := range ids {
+		if id != "" {
+			present = append(present, id)
+		}
+	}
+	if len(present) == 0 {
+		return nil
+	}
+	return db.Exec(fmt.Sprintf(stmt, column+" IN ?"), present).Error
+}
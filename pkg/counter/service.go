@@ -0,0 +1,66 @@
+package counter
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"gorm.io/gorm"
+
+	"robohub-inventory/pkg/auth"
+	"robohub-inventory/pkg/store"
+)
+
+// Result reports how many rows of each table had a wrong counter
+type Result struct {
+	Repositories int64 `json:"repositories"`
+	Packages     int64 `json:"packages"`
+	Scenarios    int64 `json:"scenarios"`
+}
+
+var tracer = otel.Tracer("robohub-inventory/pkg/counter")
+
+// Service recomputes counters on demand
+type Service struct {
+	db *gorm.DB
+}
+
+func NewService(db *gorm.DB) *Service {
+	return &Service{db: db}
+}
+
+// Reconcile recomputes every counter in one transaction; only platform admins may run it
+func (s *Service) Reconcile(ctx context.Context) (*Result, error) {
+	ctx, span := tracer.Start(ctx, "counter.Service.Reconcile")
+	defer span.End()
+	if err := auth.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	return Reconcile(store.Conn(ctx, s.db))
+}
+
+// Reconcile recomputes every counter in one transaction
+func Reconcile(db *gorm.DB) (*Result, error) {
+	res := &Result{}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, step := range []struct {
+			stmt  string
+			fixed *int64
+		}{
+			{packagesSQL, &res.Packages},
+			{repositoriesSQL, &res.Repositories},
+			{scenariosSQL, &res.Scenarios},
+		} {
+			result := tx.Exec(fmt.Sprintf(step.stmt, "TRUE"))
+			if result.Error != nil {
+				return result.Error
+			}
+			*step.fixed = result.RowsAffected
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
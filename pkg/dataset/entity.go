@@ -4,13 +4,18 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"time"
+
+	"gorm.io/gorm"
+
+	"robohub-inventory/pkg/auth"
+	"robohub-inventory/pkg/identity"
 )
 
 // Dataset represents a dataset in the robotics platform
 // Matches API_CONTRACT.md Dataset schema
 type Dataset struct {
 	ID                  string    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
-	Name                string    `gorm:"uniqueIndex;not null" json:"name"`
+	Name                string    `gorm:"uniqueIndex:idx_datasets_name_active,where:deleted_at IS NULL;not null" json:"name"`
 	Slug                string    `gorm:"index" json:"slug,omitempty"`
 	Description         string    `json:"description"`
 	DetailedDescription string    `gorm:"type:text" json:"detailedDescription,omitempty"` // Markdown
@@ -33,14 +38,15 @@ type Dataset struct {
 	Duration       int     `json:"duration,omitempty"` // Seconds
 	
 	// Compatibility
-	SupportedScenarios []string `gorm:"type:text[]" json:"supportedScenarios,omitempty"` // Scenario IDs
+	SupportedScenarios []string `gorm:"-" json:"supportedScenarios,omitempty"` // Scenario IDs, stored in dataset_supported_scenarios
 	RoboticsPlatforms  []string `gorm:"type:text[]" json:"roboticsPlatforms,omitempty"`
 	
 	// Metadata
 	Source     string `gorm:"not null" json:"source"`     // "uploaded" | "external_link" | "partner"
 	OwnerType  string `gorm:"not null" json:"ownerType"`  // "user" | "organization"
 	OwnerID    string `gorm:"not null;index" json:"ownerId"`
-	OwnerName  string `json:"ownerName"`
+	OwnerName  string `gorm:"-" json:"ownerName"`                       // Resolved from OwnerType/OwnerID
+	Owner      *identity.Owner `gorm:"-" json:"owner,omitempty"`
 	Visibility string `gorm:"not null;default:'public'" json:"visibility"` // "public" | "private"
 	
 	// Preview
@@ -55,9 +61,13 @@ type Dataset struct {
 	AvgRating     float64 `json:"avgRating,omitempty"`
 	RatingCount   int     `json:"ratingCount,omitempty"`
 	
+	// Concurrency
+	Revision int64 `gorm:"not null;default:1" json:"revision"` // Incremented on every update; exposed as the ETag
+	
 	// Timestamps
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deletedAt,omitempty"` // Set while the dataset is in the trash
 }
 
 // PreviewAssets represents preview assets for the dataset
@@ -125,3 +135,28 @@ func (s DatasetSchema) Value() (driver.Value, error) {
 func (Dataset) TableName() string {
 	return "datasets"
 }
+
+// SupportedScenario links a dataset to a scenario it supports
+type SupportedScenario struct {
+	DatasetID  string `gorm:"type:uuid;primaryKey"`
+	ScenarioID string `gorm:"type:uuid;primaryKey;index"`
+	Position   int    `gorm:"not null"` // Order of the scenario in SupportedScenarios
+}
+
+func (SupportedScenario) TableName() string {
+	return "dataset_supported_scenarios"
+}
+
+// OwnerRef returns the reference to the dataset owner
+func (d *Dataset) OwnerRef() auth.OwnerRef {
+	return auth.OwnerRef{Type: d.OwnerType, ID: d.OwnerID}
+}
+
+// SetOwner sets the resolved owner details
+func (d *Dataset) SetOwner(owner *identity.Owner) {
+	d.Owner = owner
+	d.OwnerName = ""
+	if owner != nil {
+		d.OwnerName = owner.Name
+	}
+}
@@ -0,0 +1,28 @@
+package dataset
+
+import (
+	"robohub-inventory/pkg/event"
+)
+
+// changeEvents returns the events of saving dataset over current, or of
+// creating dataset when current is nil. Uploaded datasets are reported when
+// created, and datasets are published when they become public.
+func changeEvents(current, dataset *Dataset) ([]event.Event, error) {
+	var types []string
+	if current == nil && dataset.Source == "uploaded" {
+		types = append(types, event.DatasetUploaded)
+	}
+	if dataset.Visibility == "public" && (current == nil || current.Visibility != "public") {
+		types = append(types, event.DatasetPublished)
+	}
+
+	events := make([]event.Event, 0, len(types))
+	for _, typ := range types {
+		e, err := event.New(typ, "dataset", dataset.ID, dataset)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
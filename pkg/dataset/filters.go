@@ -0,0 +1,47 @@
+package dataset
+
+import "robohub-inventory/pkg/query"
+
+// ListSchema whitelists the filters and sort orders of the dataset list (API_CONTRACT.md §4.1)
+var ListSchema = query.Schema{
+	Fields: []query.Field{
+		{
+			Param:  "type",
+			Column: "type",
+			Kind:   query.Equals,
+			Values: []string{"autonomous-driving", "robotics", "indoor-mapping", "synthetic"},
+		},
+		{
+			Param:  "modality",
+			Column: "modality",
+			Kind:   query.Equals,
+			Values: []string{"camera", "lidar", "radar", "imu", "gps", "multimodal"},
+		},
+		{Param: "format", Column: "format", Kind: query.Equals, Values: []string{"rosbag2", "bag", "parquet", "custom"}},
+		{
+			Param:  "license",
+			Column: "license",
+			Kind:   query.Equals,
+			Values: []string{"MIT", "Apache-2.0", "CC-BY", "CC-BY-NC", "proprietary"},
+		},
+		{
+			Param: "sizeFilter",
+			Kind:  query.Bucket,
+			Buckets: map[string]string{
+				"small":  "size_gb < 10",
+				"medium": "size_gb >= 10 AND size_gb < 100",
+				"large":  "size_gb >= 100",
+			},
+		},
+		{Param: "visibility", Column: "visibility", Kind: query.Equals, Values: []string{"public", "private"}},
+		{Param: "owner", Column: "owner_id", Kind: query.Equals},
+		{Param: "tag", Column: "tags", Kind: query.ArrayContains},
+	},
+	Search: []string{"name", "description"},
+	Sorts: map[string]string{
+		"newest":     "created_at DESC",
+		"size":       "size_gb DESC",
+		"popularity": "download_count DESC",
+		"name":       "name ASC",
+	},
+}
@@ -1,13 +1,26 @@
 package dataset
 
-import "context"
+import (
+	"context"
+	"time"
+
+	"robohub-inventory/pkg/query"
+)
 
 // Repository defines the interface for dataset persistence
 type Repository interface {
 	Create(ctx context.Context, dataset *Dataset) error
 	GetByID(ctx context.Context, id string) (*Dataset, error)
 	GetByName(ctx context.Context, name string) (*Dataset, error)
-	List(ctx context.Context, limit, offset int) ([]*Dataset, error)
+	ListByIDs(ctx context.Context, ids []string) ([]*Dataset, error)
+	List(ctx context.Context, spec query.Spec) ([]*Dataset, error)
+	Count(ctx context.Context, spec query.Spec) (int64, error)
 	Update(ctx context.Context, dataset *Dataset) error
-	Delete(ctx context.Context, id string) error
+	Delete(ctx context.Context, id string, revision int64) error
+	GetDeleted(ctx context.Context, id string) (*Dataset, error)
+	ListDeleted(ctx context.Context, spec query.Spec) ([]*Dataset, error)
+	CountDeleted(ctx context.Context, spec query.Spec) (int64, error)
+	Restore(ctx context.Context, dataset *Dataset) error
+	Purge(ctx context.Context, before time.Time) (int64, error)
+	MissingScenarios(ctx context.Context, ids []string) ([]string, error)
 }
@@ -2,7 +2,13 @@ package dataset
 
 import (
 	"context"
+	"time"
+
 	"gorm.io/gorm"
+
+	"robohub-inventory/pkg/counter"
+	"robohub-inventory/pkg/query"
+	"robohub-inventory/pkg/store"
 )
 
 // gormRepository implements the Repository interface using GORM
@@ -15,45 +21,219 @@ func NewRepository(db *gorm.DB) Repository {
 	return &gormRepository{db: db}
 }
 
+// visible starts a query limited to the datasets the caller may read
+func (r *gormRepository) visible(ctx context.Context) *gorm.DB {
+	v := query.VisibilityFor(ctx)
+	return store.Conn(ctx, r.db).Scopes(v.Scope(v.Condition("datasets")))
+}
+
 func (r *gormRepository) Create(ctx context.Context, dataset *Dataset) error {
-	return r.db.WithContext(ctx).Create(dataset).Error
+	return store.Conn(ctx, r.db).Transaction(func(db *gorm.DB) error {
+		if err := db.Create(dataset).Error; err != nil {
+			return err
+		}
+		return replaceScenarios(ctx, db, dataset)
+	})
 }
 
 func (r *gormRepository) GetByID(ctx context.Context, id string) (*Dataset, error) {
 	var dataset Dataset
-	err := r.db.WithContext(ctx).Where("id = ?", id).First(&dataset).Error
+	err := r.visible(ctx).Where("id = ?", id).First(&dataset).Error
 	if err != nil {
 		return nil, err
 	}
-	return &dataset, nil
+	return &dataset, LoadScenarios(ctx, r.db, &dataset)
 }
 
 func (r *gormRepository) GetByName(ctx context.Context, name string) (*Dataset, error) {
 	var dataset Dataset
-	err := r.db.WithContext(ctx).Where("name = ?", name).First(&dataset).Error
+	err := r.visible(ctx).Where("name = ?", name).First(&dataset).Error
 	if err != nil {
 		return nil, err
 	}
-	return &dataset, nil
+	return &dataset, LoadScenarios(ctx, r.db, &dataset)
 }
 
-func (r *gormRepository) List(ctx context.Context, limit, offset int) ([]*Dataset, error) {
+func (r *gormRepository) ListByIDs(ctx context.Context, ids []string) ([]*Dataset, error) {
 	var datasets []*Dataset
-	query := r.db.WithContext(ctx)
-	if limit > 0 {
-		query = query.Limit(limit)
+	if err := r.visible(ctx).Where("id IN ?", ids).Find(&datasets).Error; err != nil {
+		return nil, err
 	}
-	if offset > 0 {
-		query = query.Offset(offset)
+	return datasets, LoadScenarios(ctx, r.db, datasets...)
+}
+
+func (r *gormRepository) List(ctx context.Context, spec query.Spec) ([]*Dataset, error) {
+	var datasets []*Dataset
+	if err := spec.Apply(r.visible(ctx)).Find(&datasets).Error; err != nil {
+		return nil, err
 	}
-	err := query.Order("created_at DESC").Find(&datasets).Error
-	return datasets, err
+	return datasets, LoadScenarios(ctx, r.db, datasets...)
+}
+
+func (r *gormRepository) Count(ctx context.Context, spec query.Spec) (int64, error) {
+	var count int64
+	err := spec.Where(r.visible(ctx).Model(&Dataset{})).Count(&count).Error
+	return count, err
 }
 
+// Update saves dataset if its stored revision still equals dataset.Revision and bumps the revision
 func (r *gormRepository) Update(ctx context.Context, dataset *Dataset) error {
-	return r.db.WithContext(ctx).Save(dataset).Error
+	revision := dataset.Revision
+	err := store.Conn(ctx, r.db).Transaction(func(db *gorm.DB) error {
+		dataset.Revision = revision + 1
+		result := db.Model(dataset).Where("id = ? AND revision = ?", dataset.ID, revision).
+			Select("*").Omit("id", "created_at", "deleted_at").Updates(dataset)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return store.ErrRevisionMismatch
+		}
+		return replaceScenarios(ctx, db, dataset)
+	})
+	if err != nil {
+		dataset.Revision = revision
+		return err
+	}
+	if err := store.Conn(ctx, r.db).Where("id = ?", dataset.ID).First(dataset).Error; err != nil {
+		return err
+	}
+	return LoadScenarios(ctx, r.db, dataset)
 }
 
-func (r *gormRepository) Delete(ctx context.Context, id string) error {
-	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&Dataset{}).Error
+// Delete moves the dataset to the trash, bumping its revision; a non-zero
+// revision must match the stored one
+func (r *gormRepository) Delete(ctx context.Context, id string, revision int64) error {
+	return store.Conn(ctx, r.db).Transaction(func(db *gorm.DB) error {
+		stmt := db.Where("id = ?", id)
+		if revision != 0 {
+			stmt = stmt.Where("revision = ?", revision)
+		}
+		result := stmt.Model(&Dataset{}).UpdateColumns(store.Trashed())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			if revision != 0 {
+				return store.ErrRevisionMismatch
+			}
+			return gorm.ErrRecordNotFound
+		}
+		return counter.PackagesOfDataset(db, id)
+	})
+}
+
+// trash starts a query limited to the deleted datasets the caller may restore
+func (r *gormRepository) trash(ctx context.Context) *gorm.DB {
+	v := query.VisibilityFor(ctx)
+	return store.Conn(ctx, r.db).Unscoped().Where("datasets.deleted_at IS NOT NULL").
+		Scopes(v.Scope(v.ManageCondition("datasets")))
+}
+
+func (r *gormRepository) GetDeleted(ctx context.Context, id string) (*Dataset, error) {
+	var dataset Dataset
+	err := r.visible(ctx).Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&dataset).Error
+	if err != nil {
+		return nil, err
+	}
+	return &dataset, LoadScenarios(ctx, r.db, &dataset)
+}
+
+func (r *gormRepository) ListDeleted(ctx context.Context, spec query.Spec) ([]*Dataset, error) {
+	var datasets []*Dataset
+	if err := spec.Apply(r.trash(ctx)).Find(&datasets).Error; err != nil {
+		return nil, err
+	}
+	return datasets, LoadScenarios(ctx, r.db, datasets...)
+}
+
+func (r *gormRepository) CountDeleted(ctx context.Context, spec query.Spec) (int64, error) {
+	var count int64
+	err := spec.Where(r.trash(ctx).Model(&Dataset{})).Count(&count).Error
+	return count, err
+}
+
+// Restore takes the dataset out of the trash, bumping its revision.
+func (r *gormRepository) Restore(ctx context.Context, dataset *Dataset) error {
+	err := store.Conn(ctx, r.db).Transaction(func(db *gorm.DB) error {
+		result := db.Unscoped().Model(&Dataset{}).Where("id = ? AND deleted_at IS NOT NULL", dataset.ID).
+			Updates(map[string]interface{}{"deleted_at": nil, "revision": gorm.Expr("revision + 1")})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return counter.PackagesOfDataset(db, dataset.ID)
+	})
+	if err != nil {
+		return err
+	}
+	if err := store.Conn(ctx, r.db).Where("id = ?", dataset.ID).First(dataset).Error; err != nil {
+		return err
+	}
+	return LoadScenarios(ctx, r.db, dataset)
+}
+
+// Purge permanently deletes the datasets moved to the trash before the given time
+func (r *gormRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	result := store.Conn(ctx, r.db).Unscoped().Where("deleted_at < ?", before).Delete(&Dataset{})
+	return result.RowsAffected, result.Error
+}
+
+// MissingScenarios returns the ids that match no live scenario
+func (r *gormRepository) MissingScenarios(ctx context.Context, ids []string) ([]string, error) {
+	return store.MissingIDs(store.Conn(ctx, r.db).Table("scenarios").Where("deleted_at IS NULL"), ids)
+}
+
+// LoadScenarios fills in the supported scenarios of datasets with a single
+// query, skipping scenarios in the trash
+func LoadScenarios(ctx context.Context, db *gorm.DB, datasets ...*Dataset) error {
+	byID := make(map[string]*Dataset, len(datasets))
+	for _, dataset := range datasets {
+		dataset.SupportedScenarios = nil
+		byID[dataset.ID] = dataset
+	}
+	if len(byID) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(byID))
+	for id := range byID {
+		ids = append(ids, id)
+	}
+
+	var links []SupportedScenario
+	err := store.Conn(ctx, db).
+		Joins("JOIN scenarios ON scenarios.id = dataset_supported_scenarios.scenario_id").
+		Where("dataset_supported_scenarios.dataset_id IN ? AND scenarios.deleted_at IS NULL", ids).
+		Order("dataset_supported_scenarios.position").
+		Find(&links).Error
+	if err != nil {
+		return err
+	}
+	for _, link := range links {
+		dataset := byID[link.DatasetID]
+		dataset.SupportedScenarios = append(dataset.SupportedScenarios, link.ScenarioID)
+	}
+	return nil
+}
+
+// replaceScenarios stores the supported scenarios of dataset in the given order. Links to
+// scenarios in the trash are kept, as the caller could not have listed them.
+func replaceScenarios(ctx context.Context, db *gorm.DB, dataset *Dataset) error {
+	replaceable := db.Session(&gorm.Session{NewDB: true}).Table("scenarios").Select("id").
+		Where("deleted_at IS NULL")
+	if err := db.Where("dataset_id = ? AND scenario_id IN (?)", dataset.ID, replaceable).Delete(&SupportedScenario{}).Error; err != nil {
+		return err
+	}
+
+	dataset.SupportedScenarios = store.DistinctIDs(dataset.SupportedScenarios)
+	if len(dataset.SupportedScenarios) == 0 {
+		return nil
+	}
+	links := make([]SupportedScenario, len(dataset.SupportedScenarios))
+	for i, id := range dataset.SupportedScenarios {
+		links[i] = SupportedScenario{DatasetID: dataset.ID, ScenarioID: id, Position: i}
+	}
+	return db.Create(&links).Error
 }
@@ -3,56 +3,341 @@ package dataset
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"gorm.io/gorm"
+
+	"robohub-inventory/pkg/auth"
+	"robohub-inventory/pkg/event"
+	"robohub-inventory/pkg/identity"
+	"robohub-inventory/pkg/patch"
+	"robohub-inventory/pkg/query"
+	"robohub-inventory/pkg/store"
 )
 
 var (
-	ErrDatasetNotFound = errors.New("dataset not found")
-	ErrInvalidDataset  = errors.New("invalid dataset data")
+	ErrDatasetNotFound      = errors.New("dataset not found")
+	ErrInvalidDataset       = errors.New("invalid dataset data")
+	ErrDatasetAlreadyExists = errors.New("dataset already exists")
+	ErrDatasetNameTaken     = errors.New("dataset name is taken by another dataset; rename one of them before restoring")
 )
 
+var tracer = otel.Tracer("robohub-inventory/pkg/dataset")
+
 // Service handles business logic for datasets
 type Service struct {
-	repo Repository
+	repo   Repository
+	owners identity.OwnerResolver
+	tx     *store.Transactor
+	events event.Publisher
 }
 
-func NewService(repo Repository) *Service {
-	return &Service{repo: repo}
+func NewService(repo Repository, owners identity.OwnerResolver, tx *store.Transactor, events event.Publisher) *Service {
+	return &Service{repo: repo, owners: owners, tx: tx, events: events}
 }
 
+// CreateDataset stores a new dataset owned by the requested owner, defaulting to the caller
 func (s *Service) CreateDataset(ctx context.Context, dataset *Dataset) error {
-	if dataset.Name == "" {
-		return ErrInvalidDataset
+	ctx, span := tracer.Start(ctx, "dataset.Service.CreateDataset")
+	defer span.End()
+	if err := validateDataset(dataset); err != nil {
+		return err
+	}
+	owner, err := auth.AssignOwner(ctx, dataset.OwnerRef())
+	if err != nil {
+		return err
+	}
+	dataset.OwnerType, dataset.OwnerID = owner.Type, owner.ID
+	if err := s.checkReferences(ctx, dataset); err != nil {
+		return err
 	}
-	return s.repo.Create(ctx, dataset)
+	dataset.Revision, dataset.DeletedAt = 1, gorm.DeletedAt{}
+	return s.save(ctx, nil, dataset)
 }
 
 func (s *Service) GetDataset(ctx context.Context, id string) (*Dataset, error) {
+	ctx, span := tracer.Start(ctx, "dataset.Service.GetDataset")
+	defer span.End()
 	dataset, err := s.repo.GetByID(ctx, id)
 	if err != nil {
-		return nil, ErrDatasetNotFound
+		return nil, translateError(err)
+	}
+	if err := identity.AttachOwners(ctx, s.owners, dataset); err != nil {
+		return nil, err
 	}
 	return dataset, nil
 }
 
 func (s *Service) GetDatasetByName(ctx context.Context, name string) (*Dataset, error) {
+	ctx, span := tracer.Start(ctx, "dataset.Service.GetDatasetByName")
+	defer span.End()
 	dataset, err := s.repo.GetByName(ctx, name)
 	if err != nil {
-		return nil, ErrDatasetNotFound
+		return nil, translateError(err)
+	}
+	if err := identity.AttachOwners(ctx, s.owners, dataset); err != nil {
+		return nil, err
 	}
 	return dataset, nil
 }
 
-func (s *Service) ListDatasets(ctx context.Context, limit, offset int) ([]*Dataset, error) {
-	return s.repo.List(ctx, limit, offset)
+// GetDatasetsBatch loads the datasets with the given IDs in one query. It returns them
+// in request order together with the IDs that were not found.
+func (s *Service) GetDatasetsBatch(ctx context.Context, ids []string) ([]*Dataset, []string, error) {
+	ctx, span := tracer.Start(ctx, "dataset.Service.GetDatasetsBatch")
+	defer span.End()
+	valid, err := query.BatchIDs(ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	var datasets []*Dataset
+	if len(valid) > 0 {
+		if datasets, err = s.repo.ListByIDs(ctx, valid); err != nil {
+			return nil, nil, err
+		}
+	}
+	found, notFound := query.OrderBatch(ids, datasets, func(x *Dataset) string { return x.ID })
+	if err := identity.AttachOwners(ctx, s.owners, found...); err != nil {
+		return nil, nil, err
+	}
+	return found, notFound, nil
+}
+
+// ListDatasets returns one page of datasets matching spec together with the total count
+func (s *Service) ListDatasets(ctx context.Context, spec query.Spec) ([]*Dataset, int64, error) {
+	ctx, span := tracer.Start(ctx, "dataset.Service.ListDatasets")
+	defer span.End()
+	datasets, err := s.repo.List(ctx, spec)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := s.repo.Count(ctx, spec)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := identity.AttachOwners(ctx, s.owners, datasets...); err != nil {
+		return nil, 0, err
+	}
+	return datasets, total, nil
 }
 
+// UpdateDataset replaces a dataset; only its owner or the owning org's maintainers may
+// update it. An empty owner in the update keeps the current one, and a
+// non-zero revision must match the stored revision.
 func (s *Service) UpdateDataset(ctx context.Context, dataset *Dataset) error {
+	ctx, span := tracer.Start(ctx, "dataset.Service.UpdateDataset")
+	defer span.End()
+	if err := validateDataset(dataset); err != nil {
+		return err
+	}
+	current, err := s.repo.GetByID(ctx, dataset.ID)
+	if err != nil {
+		return translateError(err)
+	}
+	owner, err := auth.Reassign(ctx, current.OwnerRef(), dataset.OwnerRef())
+	if err != nil {
+		return err
+	}
+	if err := store.CheckRevision(dataset.Revision, current.Revision); err != nil {
+		return err
+	}
+	if err := s.checkReferences(ctx, dataset); err != nil {
+		return err
+	}
+	dataset.OwnerType, dataset.OwnerID, dataset.Revision = owner.Type, owner.ID, current.Revision
+	return s.save(ctx, current, dataset)
+}
+
+// save creates dataset, or updates current to dataset, and publishes the events of
+// the change in the same transaction
+func (s *Service) save(ctx context.Context, current, dataset *Dataset) error {
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if current == nil {
+			err = s.repo.Create(ctx, dataset)
+		} else {
+			err = s.repo.Update(ctx, dataset)
+		}
+		if err != nil {
+			return translateError(err)
+		}
+		if err := identity.AttachOwners(ctx, s.owners, dataset); err != nil {
+			return err
+		}
+		events, err := changeEvents(current, dataset)
+		if err != nil {
+			return err
+		}
+		return s.events.Publish(ctx, events...)
+	})
+}
+
+// UpsertDataset creates the dataset or, if one with the same name exists, updates it
+// in place. It reports whether the dataset was created.
+func (s *Service) UpsertDataset(ctx context.Context, dataset *Dataset) (bool, error) {
+	ctx, span := tracer.Start(ctx, "dataset.Service.UpsertDataset")
+	defer span.End()
+	existing, err := s.repo.GetByName(ctx, dataset.Name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return true, s.CreateDataset(ctx, dataset)
+	}
+	if err != nil {
+		return false, err
+	}
+	dataset.ID, dataset.Revision = existing.ID, 0
+	return false, s.UpdateDataset(ctx, dataset)
+}
+
+// PatchDataset applies a merge patch or JSON Patch to the stored dataset and saves the result,
+// validating the merged dataset as a whole.
+func (s *Service) PatchDataset(ctx context.Context, id string, revision int64, p *patch.Patch) (*Dataset, error) {
+	ctx, span := tracer.Start(ctx, "dataset.Service.PatchDataset")
+	defer span.End()
+	current, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	if err := store.CheckRevision(revision, current.Revision); err != nil {
+		return nil, err
+	}
+	var dataset Dataset
+	if err := p.Apply(current, &dataset); err != nil {
+		return nil, err
+	}
+	// Saving against the revision the patch was applied to keeps concurrent writes from being lost
+	dataset.ID, dataset.CreatedAt, dataset.Revision = current.ID, current.CreatedAt, current.Revision
+	if err := s.UpdateDataset(ctx, &dataset); err != nil {
+		return nil, err
+	}
+	return &dataset, nil
+}
+
+// DeleteDataset moves a dataset to the trash; only its owner or the owning org's
+// maintainers may delete it. A non-zero revision must match the stored revision.
+func (s *Service) DeleteDataset(ctx context.Context, id string, revision int64) error {
+	ctx, span := tracer.Start(ctx, "dataset.Service.DeleteDataset")
+	defer span.End()
+	current, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return translateError(err)
+	}
+	if err := auth.CanModify(ctx, current.OwnerRef()); err != nil {
+		return err
+	}
+	if err := store.CheckRevision(revision, current.Revision); err != nil {
+		return err
+	}
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.repo.Delete(ctx, id, current.Revision); err != nil {
+			return translateError(err)
+		}
+		dataset, err := s.repo.GetDeleted(ctx, id)
+		if err != nil {
+			return err
+		}
+		return s.publishTrashEvent(ctx, event.DatasetDeleted, dataset)
+	})
+}
+
+// ListDeletedDatasets returns one page of the deleted datasets the caller may restore, together with the total count
+func (s *Service) ListDeletedDatasets(ctx context.Context, spec query.Spec) ([]*Dataset, int64, error) {
+	ctx, span := tracer.Start(ctx, "dataset.Service.ListDeletedDatasets")
+	defer span.End()
+	datasets, err := s.repo.ListDeleted(ctx, spec)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := s.repo.CountDeleted(ctx, spec)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := identity.AttachOwners(ctx, s.owners, datasets...); err != nil {
+		return nil, 0, err
+	}
+	return datasets, total, nil
+}
+
+// RestoreDataset takes a dataset out of the trash; only its owner or the owning org's maintainers may restore it.
+func (s *Service) RestoreDataset(ctx context.Context, id string) (*Dataset, error) {
+	ctx, span := tracer.Start(ctx, "dataset.Service.RestoreDataset")
+	defer span.End()
+	current, err := s.repo.GetDeleted(ctx, id)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	if err := auth.CanModify(ctx, current.OwnerRef()); err != nil {
+		return nil, err
+	}
+	err = s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.repo.Restore(ctx, current); err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return ErrDatasetNameTaken
+			}
+			return translateError(err)
+		}
+		return s.publishTrashEvent(ctx, event.DatasetRestored, current)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return current, nil
+}
+
+// publishTrashEvent publishes an event of type typ about dataset, which was
+// moved to or out of the trash
+func (s *Service) publishTrashEvent(ctx context.Context, typ string, dataset *Dataset) error {
+	if err := identity.AttachOwners(ctx, s.owners, dataset); err != nil {
+		return err
+	}
+	e, err := event.New(typ, "dataset", dataset.ID, dataset)
+	if err != nil {
+		return err
+	}
+	return s.events.Publish(ctx, e)
+}
+
+// PurgeDeletedDatasets permanently deletes the datasets moved to the trash before the given time
+func (s *Service) PurgeDeletedDatasets(ctx context.Context, before time.Time) (int64, error) {
+	ctx, span := tracer.Start(ctx, "dataset.Service.PurgeDeletedDatasets")
+	defer span.End()
+	return s.repo.Purge(ctx, before)
+}
+
+// checkReferences rejects supportedScenarios entries that do not match a scenario
+func (s *Service) checkReferences(ctx context.Context, dataset *Dataset) error {
+	missing, err := s.repo.MissingScenarios(ctx, dataset.SupportedScenarios)
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: supportedScenarios %q do not match a scenario", store.ErrInvalidReference, missing)
+	}
+	return nil
+}
+
+func validateDataset(dataset *Dataset) error {
 	if dataset.Name == "" {
-		return ErrInvalidDataset
+		return fmt.Errorf("%w: name is required", ErrInvalidDataset)
 	}
-	return s.repo.Update(ctx, dataset)
+	switch dataset.Visibility {
+	case "":
+		dataset.Visibility = query.VisibilityPublic
+	case query.VisibilityPublic, query.VisibilityPrivate:
+	default:
+		return fmt.Errorf("%w: visibility must be %q or %q", ErrInvalidDataset, query.VisibilityPublic, query.VisibilityPrivate)
+	}
+	return nil
 }
 
-func (s *Service) DeleteDataset(ctx context.Context, id string) error {
-	return s.repo.Delete(ctx, id)
+// translateError maps persistence errors onto the dataset service errors
+func translateError(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrDatasetNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return ErrDatasetAlreadyExists
+	}
+	return err
 }
@@ -0,0 +1,180 @@
+package event
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"robohub-inventory/pkg/store"
+)
+
+var tracer = otel.Tracer("robohub-inventory/pkg/event")
+
+// pruneInterval is how often the events past retention are deleted
+const pruneInterval = time.Hour
+
+// Handler processes an event for a subscriber. It runs in the transaction
+// that records the progress of the subscriber, so writes made through
+// store.Conn commit together with it. An event is handled again if the
+// handler fails or the process stops before the progress is committed.
+type Handler func(ctx context.Context, e Event) error
+
+type subscriber struct {
+	name   string
+	types  []string
+	handle Handler
+}
+
+func (s subscriber) wants(typ string) bool {
+	return len(s.types) == 0 || contains(s.types, typ)
+}
+
+// Options configure the dispatch of events
+type Options struct {
+	PollInterval time.Duration // How often the log is read for new events
+	BatchSize    int           // Events handled per subscriber and transaction
+	Retention    time.Duration // Processed events older than this are deleted
+}
+
+// Bus records events in the outbox table, in the transaction of the change
+// they describe, and dispatches them to its subscribers in log order. Each
+// subscriber keeps its own position, so a failing one only delays itself.
+type Bus struct {
+	repo        Repository
+	tx          *store.Transactor
+	opts        Options
+	log         *slog.Logger
+	subscribers []subscriber
+	lastPruned  time.Time
+}
+
+func NewBus(repo Repository, tx *store.Transactor, opts Options, log *slog.Logger) *Bus {
+	return &Bus{repo: repo, tx: tx, opts: opts, log: log}
+}
+
+// Subscribe registers a handler for the events of the given types, or of
+// every type if none is given. The name identifies the position of the
+// subscriber in the log and must stay the same across releases. Subscribe
+// must be called before Run.
+func (b *Bus) Subscribe(name string, handle Handler, types ...string) {
+	b.subscribers = append(b.subscribers, subscriber{name: name, types: types, handle: handle})
+}
+
+// Publish implements Publisher, appending the events to the log
+func (b *Bus) Publish(ctx context.Context, events ...Event) error {
+	return b.repo.Append(ctx, events)
+}
+
+// Run dispatches new events every poll interval until ctx is done, and
+// deletes the processed events past retention every hour
+func (b *Bus) Run(ctx context.Context) {
+	ticker := time.NewTicker(b.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		b.Dispatch(ctx)
+		if time.Since(b.lastPruned) >= pruneInterval {
+			b.Prune(ctx, time.Now())
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Dispatch hands the events recorded since the last dispatch to each
+// subscriber, a batch at a time. A subscriber whose lock is held by another
+// replica is skipped.
+func (b *Bus) Dispatch(ctx context.Context) {
+	for _, s := range b.subscribers {
+		for {
+			n, err := b.dispatch(ctx, s)
+			if err != nil {
+				b.log.ErrorContext(ctx, "Failed to dispatch events", "subscriber", s.name, "error", err)
+				break
+			}
+			if n < b.opts.BatchSize || ctx.Err() != nil {
+				break
+			}
+		}
+	}
+}
+
+// dispatch hands one batch to a subscriber and returns the number of events
+// read. It stops at the first event the subscriber fails to handle, which is
+// retried on the next dispatch. Each batch starts a new trace.
+func (b *Bus) dispatch(ctx context.Context, s subscriber) (n int, err error) {
+	ctx, span := tracer.Start(ctx, "event.Dispatch", trace.WithNewRoot(),
+		trace.WithAttributes(attribute.String("event.subscriber", s.name)))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.SetAttributes(attribute.Int("event.count", n))
+		span.End()
+	}()
+
+	err = b.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		pos, locked, err := b.repo.LockConsumer(ctx, s.name)
+		if err != nil || !locked {
+			return err
+		}
+		records, err := b.repo.After(ctx, pos, b.opts.BatchSize)
+		if err != nil {
+			return err
+		}
+		n = len(records)
+		start := pos
+		for _, r := range records {
+			if s.wants(r.Type) {
+				// A savepoint undoes the writes of a failed handler only
+				err := b.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+					return s.handle(ctx, r.Event)
+				})
+				if err != nil {
+					b.log.WarnContext(ctx, "Event subscriber failed", "subscriber", s.name,
+						"event", r.ID, "type", r.Type, "error", err)
+					n = 0
+					break
+				}
+			}
+			pos = r.Position
+		}
+		if pos == start {
+			return nil
+		}
+		return b.repo.SaveConsumer(ctx, s.name, pos)
+	})
+	return n, err
+}
+
+// Prune deletes the events older than the retention period that every
+// subscriber has processed
+func (b *Bus) Prune(ctx context.Context, now time.Time) {
+	ctx, span := tracer.Start(ctx, "event.Prune", trace.WithNewRoot())
+	defer span.End()
+
+	b.lastPruned = now
+	names := make([]string, len(b.subscribers))
+	for i, s := range b.subscribers {
+		names[i] = s.name
+	}
+	n, err := b.repo.Prune(ctx, now.Add(-b.opts.Retention), names)
+	if err != nil {
+		b.log.ErrorContext(ctx, "Failed to prune events", "error", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "failed to prune events")
+		return
+	}
+	if n > 0 {
+		b.log.InfoContext(ctx, "Pruned events", "count", n)
+	}
+}
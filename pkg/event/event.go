@@ -0,0 +1,137 @@
+// Package event defines the domain events published when catalog entities
+// change (API_CONTRACT.md §9). Services publish them in the transaction of
+// the change, so that an event exists exactly when its change was committed.
+// The Bus records them in an outbox table and dispatches them to in-process
+// subscribers, such as webhooks, at least once.
+package event
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Event types
+const (
+	RepositoryConnected     = "repository.connected"
+	RepositorySyncStarted   = "repository.sync_started"
+	RepositorySyncCompleted = "repository.sync_completed"
+	RepositorySyncFailed    = "repository.sync_failed"
+	RepositoryDeleted       = "repository.deleted"
+	RepositoryRestored      = "repository.restored"
+	PackageCreated          = "package.created"
+	PackageUpdated          = "package.updated"
+	PackageDeleted          = "package.deleted"
+	PackageRestored         = "package.restored"
+	ScenarioRunStarted      = "scenario.run_started"
+	ScenarioRunCompleted    = "scenario.run_completed"
+	ScenarioRunFailed       = "scenario.run_failed"
+	ScenarioDeleted         = "scenario.deleted"
+	ScenarioRestored        = "scenario.restored"
+	DatasetUploaded         = "dataset.uploaded"
+	DatasetPublished        = "dataset.published"
+	DatasetDeleted          = "dataset.deleted"
+	DatasetRestored         = "dataset.restored"
+	SimulatorDeleted        = "simulator.deleted"
+	SimulatorRestored       = "simulator.restored"
+)
+
+// Types lists every event type
+var Types = []string{
+	RepositoryConnected,
+	RepositorySyncStarted,
+	RepositorySyncCompleted,
+	RepositorySyncFailed,
+	RepositoryDeleted,
+	RepositoryRestored,
+	PackageCreated,
+	PackageUpdated,
+	PackageDeleted,
+	PackageRestored,
+	ScenarioRunStarted,
+	ScenarioRunCompleted,
+	ScenarioRunFailed,
+	ScenarioDeleted,
+	ScenarioRestored,
+	DatasetUploaded,
+	DatasetPublished,
+	DatasetDeleted,
+	DatasetRestored,
+	SimulatorDeleted,
+	SimulatorRestored,
+}
+
+// EntityTypes lists the types of entity events are about
+var EntityTypes = []string{"repository", "package", "scenario", "dataset", "simulator"}
+
+// ValidType reports whether t is a known event type
+func ValidType(t string) bool {
+	return contains(Types, t)
+}
+
+// Event is a change to a catalog entity
+type Event struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	EntityType string          `json:"entityType"` // "repository" | "package" | "scenario" | "dataset" | "simulator"
+	EntityID   string          `json:"entityId"`
+	Timestamp  time.Time       `json:"timestamp"`
+	Data       json.RawMessage `json:"data"` // Event-specific data, e.g. the entity
+}
+
+// New creates an event of type typ about an entity, with data encoded as JSON
+func New(typ, entityType, entityID string, data any) (Event, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, fmt.Errorf("failed to encode %s event: %w", typ, err)
+	}
+	id, err := newID()
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{
+		ID:         id,
+		Type:       typ,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Timestamp:  time.Now().UTC(),
+		Data:       raw,
+	}, nil
+}
+
+// Scan implements sql.Scanner interface for JSONB
+func (e *Event) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(bytes, e)
+}
+
+// Value implements driver.Valuer interface for JSONB
+func (e Event) Value() (driver.Value, error) {
+	return json.Marshal(e)
+}
+
+// Publisher records events. Publish is called within the transaction of the
+// change the events describe, if any, and fails it on error.
+type Publisher interface {
+	Publish(ctx context.Context, events ...Event) error
+}
+
+// newID returns a random (version 4) UUID
+func newID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
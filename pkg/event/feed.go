@@ -0,0 +1,131 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"robohub-inventory/pkg/query"
+)
+
+// ErrInvalidFilter is returned for a stream filter naming unknown types
+var ErrInvalidFilter = errors.New("invalid event filter")
+
+// Feed follows the head of the event log for the change streams of this
+// process. It reads the head once per poll interval, however many streams
+// are open, and wakes the streams when it moves.
+type Feed struct {
+	repo     Repository
+	interval time.Duration
+	log      *slog.Logger
+
+	mu      sync.Mutex
+	head    Position
+	changed chan struct{}
+	done    chan struct{}
+}
+
+func NewFeed(repo Repository, interval time.Duration, log *slog.Logger) *Feed {
+	return &Feed{repo: repo, interval: interval, log: log, changed: make(chan struct{}), done: make(chan struct{})}
+}
+
+// Run follows the head every poll interval until ctx is done, which ends
+// the open streams
+func (f *Feed) Run(ctx context.Context) {
+	defer close(f.done)
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	for {
+		head, err := f.repo.Head(ctx)
+		if err != nil && ctx.Err() == nil {
+			f.log.ErrorContext(ctx, "Failed to read the event log head", "error", err)
+		}
+		if err == nil {
+			f.advance(head)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (f *Feed) advance(head Position) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if head.After(f.head) {
+		f.head = head
+		close(f.changed)
+		f.changed = make(chan struct{})
+	}
+}
+
+// Head returns the last known head, and a channel closed when it moves
+func (f *Feed) Head() (Position, <-chan struct{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.head, f.changed
+}
+
+// Done returns a channel closed when the feed stops
+func (f *Feed) Done() <-chan struct{} {
+	return f.done
+}
+
+// Start returns the position a stream starts from: after the event with ID
+// lastEventID, or at the head of the log if none is given. It reports false
+// if the event is not in the log, e.g. because it was pruned, in which case
+// the stream starts at the head and the client missed events.
+func (f *Feed) Start(ctx context.Context, lastEventID string) (Position, bool, error) {
+	ctx, span := tracer.Start(ctx, "event.Feed.Start")
+	defer span.End()
+	if lastEventID != "" && query.ValidID(lastEventID) {
+		pos, err := f.repo.PositionOf(ctx, lastEventID)
+		if err == nil {
+			return pos, true, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return Position{}, false, err
+		}
+	}
+	head, err := f.repo.Head(ctx)
+	return head, lastEventID == "", err
+}
+
+// Read returns up to limit events after pos and up to until that match
+// filter and are about entities the caller may read
+func (f *Feed) Read(ctx context.Context, pos, until Position, filter Filter, limit int) ([]Record, error) {
+	return f.repo.Stream(ctx, pos, until, filter, limit)
+}
+
+// ValidateFilter checks that a filter names known entity and event types only
+func ValidateFilter(filter Filter) error {
+	for _, t := range filter.EntityTypes {
+		if !contains(EntityTypes, t) {
+			return fmt.Errorf("%w: unsupported entityType %q (allowed: %s)", ErrInvalidFilter, t, strings.Join(EntityTypes, ", "))
+		}
+	}
+	for _, t := range filter.Types {
+		if !ValidType(t) {
+			return fmt.Errorf("%w: unsupported type %q (allowed: %s)", ErrInvalidFilter, t, strings.Join(Types, ", "))
+		}
+	}
+	return nil
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
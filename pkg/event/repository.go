@@ -0,0 +1,58 @@
+package event
+
+import (
+	"context"
+	"time"
+)
+
+// Position orders the event log: by the transaction that recorded an event,
+// then by the order of recording. Once a transaction with a lower ID can no
+// longer commit, no event can appear before a position already read.
+type Position struct {
+	TransactionID int64 `json:"-"`
+	Sequence      int64 `json:"-"`
+}
+
+// After reports whether p comes after q in the log
+func (p Position) After(q Position) bool {
+	if p.TransactionID != q.TransactionID {
+		return p.TransactionID > q.TransactionID
+	}
+	return p.Sequence > q.Sequence
+}
+
+// Filter selects events by entity type, entity ID and event type. An empty
+// list matches every value.
+type Filter struct {
+	EntityTypes []string
+	EntityIDs   []string
+	Types       []string
+}
+
+// Record is an event at its position in the log
+type Record struct {
+	Event
+	Position Position `json:"-"`
+}
+
+// Repository defines the interface for event log persistence
+type Repository interface {
+	Append(ctx context.Context, events []Event) error
+	// After returns up to limit events after pos, in log order. It only
+	// returns events that no transaction in progress can precede.
+	After(ctx context.Context, pos Position, limit int) ([]Record, error)
+	// Head returns the position of the last event After can return
+	Head(ctx context.Context) (Position, error)
+	// Stream returns up to limit events after pos and up to until that
+	// match filter and are about entities the caller in ctx may read
+	Stream(ctx context.Context, pos, until Position, filter Filter, limit int) ([]Record, error)
+	PositionOf(ctx context.Context, id string) (Position, error)
+	// LockConsumer takes the lock of a consumer until the end of the
+	// transaction in ctx and returns its position. It reports false if
+	// another transaction holds the lock.
+	LockConsumer(ctx context.Context, name string) (Position, bool, error)
+	SaveConsumer(ctx context.Context, name string, pos Position) error
+	// Prune deletes the events recorded before the given time that every
+	// named consumer has processed
+	Prune(ctx context.Context, before time.Time, consumers []string) (int64, error)
+}
@@ -0,0 +1,212 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"robohub-inventory/pkg/query"
+	"robohub-inventory/pkg/store"
+)
+
+// eventRow is an event as stored in the log. The transaction ID and sequence
+// are set by the database.
+type eventRow struct {
+	ID            string          `gorm:"type:uuid;primaryKey"`
+	Type          string          `gorm:"not null"`
+	EntityType    string          `gorm:"not null"`
+	EntityID      string          `gorm:"not null"`
+	OccurredAt    time.Time       `gorm:"not null"`
+	Data          json.RawMessage `gorm:"type:jsonb"`
+	TransactionID int64           `gorm:"->"`
+	Sequence      int64           `gorm:"->"`
+}
+
+func (eventRow) TableName() string {
+	return "events"
+}
+
+// consumerRow is the position of a consumer in the log
+type consumerRow struct {
+	Name          string `gorm:"primaryKey"`
+	TransactionID int64
+	Sequence      int64
+	UpdatedAt     time.Time
+}
+
+func (consumerRow) TableName() string {
+	return "event_consumers"
+}
+
+// gormRepository implements the Repository interface using GORM
+type gormRepository struct {
+	db *gorm.DB
+}
+
+// NewRepository creates a new GORM-based event log repository
+func NewRepository(db *gorm.DB) Repository {
+	return &gormRepository{db: db}
+}
+
+func (r *gormRepository) Append(ctx context.Context, events []Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	rows := make([]eventRow, len(events))
+	for i, e := range events {
+		rows[i] = eventRow{
+			ID:         e.ID,
+			Type:       e.Type,
+			EntityType: e.EntityType,
+			EntityID:   e.EntityID,
+			OccurredAt: e.Timestamp,
+			Data:       e.Data,
+		}
+	}
+	return store.Conn(ctx, r.db).Create(&rows).Error
+}
+
+// committed matches the events of transactions below the oldest one in
+// progress, whose events could still be committed before those of later
+// transactions
+const committed = "transaction_id < pg_snapshot_xmin(pg_current_snapshot())::text::bigint"
+
+func (r *gormRepository) After(ctx context.Context, pos Position, limit int) ([]Record, error) {
+	var rows []eventRow
+	err := store.Conn(ctx, r.db).
+		Where("(transaction_id, sequence) > (?, ?)", pos.TransactionID, pos.Sequence).
+		Where(committed).
+		Order("transaction_id").Order("sequence").
+		Limit(limit).
+		Find(&rows).Error
+	return toRecords(rows), err
+}
+
+func (r *gormRepository) Head(ctx context.Context) (Position, error) {
+	var rows []eventRow
+	err := store.Conn(ctx, r.db).Select("transaction_id", "sequence").Where(committed).
+		Order("transaction_id DESC").Order("sequence DESC").
+		Limit(1).
+		Find(&rows).Error
+	if err != nil || len(rows) == 0 {
+		return Position{}, err
+	}
+	return Position{TransactionID: rows[0].TransactionID, Sequence: rows[0].Sequence}, nil
+}
+
+func (r *gormRepository) Stream(ctx context.Context, pos, until Position, filter Filter, limit int) ([]Record, error) {
+	db := store.Conn(ctx, r.db).Table("events AS e").
+		Where("(e.transaction_id, e.sequence) > (?, ?)", pos.TransactionID, pos.Sequence).
+		Where("(e.transaction_id, e.sequence) <= (?, ?)", until.TransactionID, until.Sequence)
+	if len(filter.EntityTypes) > 0 {
+		db = db.Where("e.entity_type IN ?", filter.EntityTypes)
+	}
+	if len(filter.EntityIDs) > 0 {
+		db = db.Where("e.entity_id IN ?", filter.EntityIDs)
+	}
+	if len(filter.Types) > 0 {
+		db = db.Where("e.type IN ?", filter.Types)
+	}
+	v := query.VisibilityFor(ctx)
+	var rows []eventRow
+	err := db.Scopes(v.Scope(visibleCondition(v))).
+		Order("e.transaction_id").Order("e.sequence").
+		Limit(limit).
+		Find(&rows).Error
+	return toRecords(rows), err
+}
+
+// visibleCondition returns a predicate matching the events about entities
+// the caller may read now. Packages, and the runs of a package, are hidden
+// with their repository; other scenario events and simulator events are
+// public. It is empty when nothing is hidden.
+func visibleCondition(v query.Visibility) string {
+	repositories, datasets := v.Condition("r"), v.Condition("d")
+	if repositories == "" {
+		return ""
+	}
+	packages := v.RepoCondition("p")
+	return fmt.Sprintf(`(
+		(e.entity_type = 'repository' AND EXISTS (SELECT 1 FROM repositories r WHERE r.id = e.entity_id::uuid AND %s))
+		OR (e.entity_type = 'dataset' AND EXISTS (SELECT 1 FROM datasets d WHERE d.id = e.entity_id::uuid AND %s))
+		OR (e.entity_type = 'package' AND EXISTS (SELECT 1 FROM packages p WHERE p.id = e.entity_id::uuid AND %s))
+		OR (e.entity_type = 'scenario' AND (e.data->>'packageId' IS NULL
+			OR EXISTS (SELECT 1 FROM packages p WHERE p.id = (e.data->>'packageId')::uuid AND %s)))
+		OR e.entity_type = 'simulator'
+	)`, repositories, datasets, packages, packages)
+}
+
+func toRecords(rows []eventRow) []Record {
+	records := make([]Record, len(rows))
+	for i, row := range rows {
+		records[i] = Record{
+			Event: Event{
+				ID:         row.ID,
+				Type:       row.Type,
+				EntityType: row.EntityType,
+				EntityID:   row.EntityID,
+				Timestamp:  row.OccurredAt.UTC(),
+				Data:       row.Data,
+			},
+			Position: Position{TransactionID: row.TransactionID, Sequence: row.Sequence},
+		}
+	}
+	return records
+}
+
+func (r *gormRepository) PositionOf(ctx context.Context, id string) (Position, error) {
+	var row eventRow
+	err := store.Conn(ctx, r.db).Select("transaction_id", "sequence").Where("id = ?", id).First(&row).Error
+	if err != nil {
+		return Position{}, err
+	}
+	return Position{TransactionID: row.TransactionID, Sequence: row.Sequence}, nil
+}
+
+// LockConsumer uses an advisory lock, which unlike a row lock does not
+// assign a transaction ID; holding one would delay the events of every
+// later transaction until the consumer commits.
+func (r *gormRepository) LockConsumer(ctx context.Context, name string) (Position, bool, error) {
+	db := store.Conn(ctx, r.db)
+	var locked bool
+	if err := db.Raw("SELECT pg_try_advisory_xact_lock(hashtext(?))", "event_consumer:"+name).Scan(&locked).Error; err != nil {
+		return Position{}, false, err
+	}
+	if !locked {
+		return Position{}, false, nil
+	}
+	var row consumerRow
+	err := db.Where("name = ?", name).First(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Position{}, true, nil
+	}
+	if err != nil {
+		return Position{}, false, err
+	}
+	return Position{TransactionID: row.TransactionID, Sequence: row.Sequence}, true, nil
+}
+
+func (r *gormRepository) SaveConsumer(ctx context.Context, name string, pos Position) error {
+	return store.Conn(ctx, r.db).Save(&consumerRow{
+		Name:          name,
+		TransactionID: pos.TransactionID,
+		Sequence:      pos.Sequence,
+	}).Error
+}
+
+// Prune ignores the positions of consumers not named, such as subscribers
+// that were removed, so that they do not keep events forever
+func (r *gormRepository) Prune(ctx context.Context, before time.Time, consumers []string) (int64, error) {
+	result := store.Conn(ctx, r.db).Exec(`
+		DELETE FROM events e
+		WHERE e.occurred_at < ?
+		AND (
+			SELECT count(*) FROM event_consumers c
+			WHERE c.name = ANY(?) AND (c.transaction_id, c.sequence) >= (e.transaction_id, e.sequence)
+		) = ?`, before, query.TextArray(consumers), len(consumers))
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,51 @@
+package identity
+
+import (
+	"time"
+
+	"robohub-inventory/pkg/auth"
+)
+
+// User is a person known to the platform, keyed by the subject of the
+// credentials they authenticate with. Users are created on first sign-in.
+type User struct {
+	ID        string    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	Subject   string    `gorm:"uniqueIndex;not null" json:"subject"` // JWT subject or API key owner
+	Name      string    `json:"name"`
+	Email     string    `json:"email,omitempty"`
+	AvatarURL string    `json:"avatarUrl,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Organization groups users that own catalog entities together
+type Organization struct {
+	ID          string    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	Name        string    `gorm:"uniqueIndex;not null" json:"name"` // URL-friendly handle
+	DisplayName string    `json:"displayName"`
+	Description string    `json:"description,omitempty"`
+	AvatarURL   string    `json:"avatarUrl,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// Membership grants a user a role within an organization
+type Membership struct {
+	OrganizationID string    `gorm:"type:uuid;primaryKey" json:"organizationId"`
+	UserID         string    `gorm:"type:uuid;primaryKey;index" json:"userId"`
+	Role           auth.Role `gorm:"not null" json:"role"` // "viewer" | "maintainer" | "admin"
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+
+	Organization *Organization `gorm:"constraint:OnDelete:CASCADE" json:"organization,omitempty"`
+	User         *User         `gorm:"constraint:OnDelete:CASCADE" json:"user,omitempty"`
+}
+
+// Owner is the public view of the user or organization owning an entity.
+// It is resolved from the entity's owner reference when read.
+type Owner struct {
+	Type      string `json:"type"` // "user" | "organization"
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	AvatarURL string `json:"avatarUrl,omitempty"`
+}
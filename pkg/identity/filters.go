@@ -0,0 +1,12 @@
+package identity
+
+import "robohub-inventory/pkg/query"
+
+// ListSchema whitelists the search and sort orders of the organization list
+var ListSchema = query.Schema{
+	Search: []string{"name", "display_name", "description"},
+	Sorts: map[string]string{
+		"name":   "name ASC",
+		"newest": "created_at DESC",
+	},
+}
@@ -0,0 +1,32 @@
+package identity
+
+import (
+	"context"
+
+	"robohub-inventory/pkg/query"
+)
+
+// Repository defines the interface for user, organization and membership persistence
+type Repository interface {
+	CreateUser(ctx context.Context, user *User) error
+	GetUser(ctx context.Context, id string) (*User, error)
+	GetUserBySubject(ctx context.Context, subject string) (*User, error)
+	UpdateUser(ctx context.Context, user *User) error
+	ListUsersByID(ctx context.Context, ids []string) ([]*User, error)
+
+	// CreateOrganization stores org and makes adminID its first admin
+	CreateOrganization(ctx context.Context, org *Organization, adminID string) error
+	GetOrganization(ctx context.Context, id string) (*Organization, error)
+	ListOrganizations(ctx context.Context, spec query.Spec) ([]*Organization, error)
+	CountOrganizations(ctx context.Context, spec query.Spec) (int64, error)
+	ListOrganizationsByID(ctx context.Context, ids []string) ([]*Organization, error)
+	UpdateOrganization(ctx context.Context, org *Organization) error
+	DeleteOrganization(ctx context.Context, id string) error
+
+	GetMembership(ctx context.Context, orgID, userID string) (*Membership, error)
+	ListMembers(ctx context.Context, orgID string) ([]*Membership, error)
+	ListMemberships(ctx context.Context, userID string) ([]*Membership, error)
+	SaveMembership(ctx context.Context, m *Membership) error
+	DeleteMembership(ctx context.Context, orgID, userID string) error
+	CountAdmins(ctx context.Context, orgID string) (int64, error)
+}
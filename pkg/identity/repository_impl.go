@@ -0,0 +1,163 @@
+package identity
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"robohub-inventory/pkg/auth"
+	"robohub-inventory/pkg/query"
+	"robohub-inventory/pkg/store"
+)
+
+// gormRepository implements the Repository interface using GORM
+type gormRepository struct {
+	db *gorm.DB
+}
+
+// NewRepository creates a new GORM-based identity repository
+func NewRepository(db *gorm.DB) Repository {
+	return &gormRepository{db: db}
+}
+
+func (r *gormRepository) CreateUser(ctx context.Context, user *User) error {
+	return store.Conn(ctx, r.db).Create(user).Error
+}
+
+func (r *gormRepository) GetUser(ctx context.Context, id string) (*User, error) {
+	var user User
+	err := store.Conn(ctx, r.db).Where("id = ?", id).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *gormRepository) GetUserBySubject(ctx context.Context, subject string) (*User, error) {
+	var user User
+	err := store.Conn(ctx, r.db).Where("subject = ?", subject).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *gormRepository) UpdateUser(ctx context.Context, user *User) error {
+	return store.Conn(ctx, r.db).Model(user).Where("id = ?", user.ID).
+		Select("name", "email", "avatar_url").Updates(user).Error
+}
+
+func (r *gormRepository) ListUsersByID(ctx context.Context, ids []string) ([]*User, error) {
+	var users []*User
+	err := store.Conn(ctx, r.db).Where("id IN ?", ids).Find(&users).Error
+	return users, err
+}
+
+func (r *gormRepository) CreateOrganization(ctx context.Context, org *Organization, adminID string) error {
+	return store.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(org).Error; err != nil {
+			return err
+		}
+		return tx.Create(&Membership{OrganizationID: org.ID, UserID: adminID, Role: auth.RoleAdmin}).Error
+	})
+}
+
+func (r *gormRepository) GetOrganization(ctx context.Context, id string) (*Organization, error) {
+	var org Organization
+	err := store.Conn(ctx, r.db).Where("id = ?", id).First(&org).Error
+	if err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+func (r *gormRepository) ListOrganizations(ctx context.Context, spec query.Spec) ([]*Organization, error) {
+	var orgs []*Organization
+	err := spec.Apply(store.Conn(ctx, r.db)).Find(&orgs).Error
+	return orgs, err
+}
+
+func (r *gormRepository) CountOrganizations(ctx context.Context, spec query.Spec) (int64, error) {
+	var count int64
+	err := spec.Where(store.Conn(ctx, r.db).Model(&Organization{})).Count(&count).Error
+	return count, err
+}
+
+func (r *gormRepository) ListOrganizationsByID(ctx context.Context, ids []string) ([]*Organization, error) {
+	var orgs []*Organization
+	err := store.Conn(ctx, r.db).Where("id IN ?", ids).Find(&orgs).Error
+	return orgs, err
+}
+
+func (r *gormRepository) UpdateOrganization(ctx context.Context, org *Organization) error {
+	result := store.Conn(ctx, r.db).Model(org).Where("id = ?", org.ID).
+		Select("*").Omit("id", "created_at").Updates(org)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return store.Conn(ctx, r.db).Where("id = ?", org.ID).First(org).Error
+}
+
+func (r *gormRepository) DeleteOrganization(ctx context.Context, id string) error {
+	result := store.Conn(ctx, r.db).Where("id = ?", id).Delete(&Organization{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *gormRepository) GetMembership(ctx context.Context, orgID, userID string) (*Membership, error) {
+	var m Membership
+	err := store.Conn(ctx, r.db).Where("organization_id = ? AND user_id = ?", orgID, userID).First(&m).Error
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r *gormRepository) ListMembers(ctx context.Context, orgID string) ([]*Membership, error) {
+	var members []*Membership
+	err := store.Conn(ctx, r.db).Preload("User").
+		Where("organization_id = ?", orgID).Order("created_at").Find(&members).Error
+	return members, err
+}
+
+func (r *gormRepository) ListMemberships(ctx context.Context, userID string) ([]*Membership, error) {
+	var memberships []*Membership
+	err := store.Conn(ctx, r.db).Preload("Organization").
+		Where("user_id = ?", userID).Order("created_at").Find(&memberships).Error
+	return memberships, err
+}
+
+func (r *gormRepository) SaveMembership(ctx context.Context, m *Membership) error {
+	return store.Conn(ctx, r.db).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "organization_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role", "updated_at"}),
+	}).Create(m).Error
+}
+
+func (r *gormRepository) DeleteMembership(ctx context.Context, orgID, userID string) error {
+	result := store.Conn(ctx, r.db).
+		Where("organization_id = ? AND user_id = ?", orgID, userID).Delete(&Membership{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *gormRepository) CountAdmins(ctx context.Context, orgID string) (int64, error) {
+	var count int64
+	err := store.Conn(ctx, r.db).Model(&Membership{}).
+		Where("organization_id = ? AND role = ?", orgID, auth.RoleAdmin).Count(&count).Error
+	return count, err
+}
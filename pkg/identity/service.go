@@ -0,0 +1,404 @@
+package identity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"go.opentelemetry.io/otel"
+	"gorm.io/gorm"
+
+	"robohub-inventory/pkg/auth"
+	"robohub-inventory/pkg/query"
+)
+
+var (
+	ErrUserNotFound              = errors.New("user not found")
+	ErrOrganizationNotFound      = errors.New("organization not found")
+	ErrMembershipNotFound        = errors.New("membership not found")
+	ErrInvalidOrganization       = errors.New("invalid organization data")
+	ErrInvalidMembership         = errors.New("invalid membership data")
+	ErrOrganizationAlreadyExists = errors.New("organization already exists")
+	ErrLastAdmin                 = errors.New("organization must keep at least one admin")
+)
+
+var orgNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// OwnerResolver looks up the public details of entity owners
+type OwnerResolver interface {
+	Owners(ctx context.Context, refs []auth.OwnerRef) (map[auth.OwnerRef]*Owner, error)
+}
+
+var tracer = otel.Tracer("robohub-inventory/pkg/identity")
+
+// Service handles users, organizations and memberships
+type Service struct {
+	repo   Repository
+	admins map[string]bool
+}
+
+// NewService creates an identity service. Callers authenticating with one of
+// adminSubjects are platform admins.
+func NewService(repo Repository, adminSubjects []string) *Service {
+	admins := make(map[string]bool, len(adminSubjects))
+	for _, s := range adminSubjects {
+		admins[s] = true
+	}
+	return &Service{repo: repo, admins: admins}
+}
+
+// Resolve fills in the local user, organization roles and admin flag of an
+// authenticated principal, creating the user on first sign-in. Agents are
+// trusted integrations and act as platform admins.
+func (s *Service) Resolve(ctx context.Context, p *auth.Principal) error {
+	ctx, span := tracer.Start(ctx, "identity.Service.Resolve")
+	defer span.End()
+	if p.Kind == auth.KindAgent {
+		p.Admin = true
+		return nil
+	}
+
+	user, err := s.userForPrincipal(ctx, p)
+	if err != nil {
+		return err
+	}
+	memberships, err := s.repo.ListMemberships(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	p.UserID = user.ID
+	p.Admin = s.admins[p.Subject]
+	p.Orgs = make(map[string]auth.Role, len(memberships))
+	for _, m := range memberships {
+		p.Orgs[m.OrganizationID] = m.Role
+	}
+	return nil
+}
+
+func (s *Service) userForPrincipal(ctx context.Context, p *auth.Principal) (*User, error) {
+	user, err := s.repo.GetUserBySubject(ctx, p.Subject)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		user = &User{Subject: p.Subject, Name: p.Name, Email: p.Email}
+		if user.Name == "" || p.Kind == auth.KindAPIKey {
+			// API key principals carry the key name, not the user's
+			user.Name = p.Subject
+		}
+		err = s.repo.CreateUser(ctx, user)
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			// Created by a concurrent request
+			return s.repo.GetUserBySubject(ctx, p.Subject)
+		}
+		return user, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the profile in step with the identity provider's claims
+	if p.Kind == auth.KindUser && ((p.Name != "" && p.Name != user.Name) || (p.Email != "" && p.Email != user.Email)) {
+		if p.Name != "" {
+			user.Name = p.Name
+		}
+		if p.Email != "" {
+			user.Email = p.Email
+		}
+		if err := s.repo.UpdateUser(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// CurrentUser returns the calling user and their memberships
+func (s *Service) CurrentUser(ctx context.Context) (*User, []*Membership, error) {
+	ctx, span := tracer.Start(ctx, "identity.Service.CurrentUser")
+	defer span.End()
+	p, ok := auth.FromContext(ctx)
+	if !ok {
+		return nil, nil, auth.ErrUnauthenticated
+	}
+	if p.UserID == "" {
+		return nil, nil, ErrUserNotFound
+	}
+	user, err := s.repo.GetUser(ctx, p.UserID)
+	if err != nil {
+		return nil, nil, translateError(err, ErrUserNotFound)
+	}
+	memberships, err := s.repo.ListMemberships(ctx, user.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, memberships, nil
+}
+
+func (s *Service) GetUser(ctx context.Context, id string) (*User, error) {
+	ctx, span := tracer.Start(ctx, "identity.Service.GetUser")
+	defer span.End()
+	user, err := s.repo.GetUser(ctx, id)
+	if err != nil {
+		return nil, translateError(err, ErrUserNotFound)
+	}
+	return user, nil
+}
+
+// CreateOrganization creates an organization with the caller as its admin
+func (s *Service) CreateOrganization(ctx context.Context, org *Organization) error {
+	ctx, span := tracer.Start(ctx, "identity.Service.CreateOrganization")
+	defer span.End()
+	p, ok := auth.FromContext(ctx)
+	if !ok {
+		return auth.ErrUnauthenticated
+	}
+	if p.UserID == "" {
+		return fmt.Errorf("%w: organizations must be created by a user", auth.ErrForbidden)
+	}
+	if err := validateOrganization(org); err != nil {
+		return err
+	}
+	if err := s.repo.CreateOrganization(ctx, org, p.UserID); err != nil {
+		return translateError(err, ErrOrganizationNotFound)
+	}
+	if p.Orgs == nil {
+		p.Orgs = make(map[string]auth.Role)
+	}
+	p.Orgs[org.ID] = auth.RoleAdmin
+	return nil
+}
+
+func (s *Service) GetOrganization(ctx context.Context, id string) (*Organization, error) {
+	ctx, span := tracer.Start(ctx, "identity.Service.GetOrganization")
+	defer span.End()
+	org, err := s.repo.GetOrganization(ctx, id)
+	if err != nil {
+		return nil, translateError(err, ErrOrganizationNotFound)
+	}
+	return org, nil
+}
+
+// ListOrganizations returns one page of organizations matching spec together with the total count
+func (s *Service) ListOrganizations(ctx context.Context, spec query.Spec) ([]*Organization, int64, error) {
+	ctx, span := tracer.Start(ctx, "identity.Service.ListOrganizations")
+	defer span.End()
+	orgs, err := s.repo.ListOrganizations(ctx, spec)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := s.repo.CountOrganizations(ctx, spec)
+	if err != nil {
+		return nil, 0, err
+	}
+	return orgs, total, nil
+}
+
+// UpdateOrganization updates an organization's profile; org admins only
+func (s *Service) UpdateOrganization(ctx context.Context, org *Organization) error {
+	ctx, span := tracer.Start(ctx, "identity.Service.UpdateOrganization")
+	defer span.End()
+	if err := requireOrgRole(ctx, org.ID, auth.RoleAdmin); err != nil {
+		return err
+	}
+	if err := validateOrganization(org); err != nil {
+		return err
+	}
+	return translateError(s.repo.UpdateOrganization(ctx, org), ErrOrganizationNotFound)
+}
+
+// DeleteOrganization deletes an organization and its memberships; org admins only
+func (s *Service) DeleteOrganization(ctx context.Context, id string) error {
+	ctx, span := tracer.Start(ctx, "identity.Service.DeleteOrganization")
+	defer span.End()
+	if err := requireOrgRole(ctx, id, auth.RoleAdmin); err != nil {
+		return err
+	}
+	return translateError(s.repo.DeleteOrganization(ctx, id), ErrOrganizationNotFound)
+}
+
+// ListMembers returns the members of an organization; visible to members only
+func (s *Service) ListMembers(ctx context.Context, orgID string) ([]*Membership, error) {
+	ctx, span := tracer.Start(ctx, "identity.Service.ListMembers")
+	defer span.End()
+	if err := requireOrgRole(ctx, orgID, auth.RoleViewer); err != nil {
+		return nil, err
+	}
+	if _, err := s.GetOrganization(ctx, orgID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListMembers(ctx, orgID)
+}
+
+// SetMemberRole adds a user to an organization or changes their role; org admins only
+func (s *Service) SetMemberRole(ctx context.Context, orgID, userID string, role auth.Role) (*Membership, error) {
+	ctx, span := tracer.Start(ctx, "identity.Service.SetMemberRole")
+	defer span.End()
+	if err := requireOrgRole(ctx, orgID, auth.RoleAdmin); err != nil {
+		return nil, err
+	}
+	if !role.Valid() {
+		return nil, fmt.Errorf("%w: role must be %q, %q or %q", ErrInvalidMembership, auth.RoleViewer, auth.RoleMaintainer, auth.RoleAdmin)
+	}
+	if _, err := s.GetOrganization(ctx, orgID); err != nil {
+		return nil, err
+	}
+	if _, err := s.GetUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	if role != auth.RoleAdmin {
+		if err := s.ensureOtherAdmin(ctx, orgID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	m := &Membership{OrganizationID: orgID, UserID: userID, Role: role}
+	if err := s.repo.SaveMembership(ctx, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// RemoveMember removes a user from an organization. Admins may remove
+// anyone; members may remove themselves.
+func (s *Service) RemoveMember(ctx context.Context, orgID, userID string) error {
+	ctx, span := tracer.Start(ctx, "identity.Service.RemoveMember")
+	defer span.End()
+	p, ok := auth.FromContext(ctx)
+	if !ok {
+		return auth.ErrUnauthenticated
+	}
+	if p.UserID != userID {
+		if err := requireOrgRole(ctx, orgID, auth.RoleAdmin); err != nil {
+			return err
+		}
+	}
+	if err := s.ensureOtherAdmin(ctx, orgID, userID); err != nil {
+		return err
+	}
+	return translateError(s.repo.DeleteMembership(ctx, orgID, userID), ErrMembershipNotFound)
+}
+
+// ensureOtherAdmin rejects demoting or removing the last admin of an organization
+func (s *Service) ensureOtherAdmin(ctx context.Context, orgID, userID string) error {
+	m, err := s.repo.GetMembership(ctx, orgID, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if m.Role != auth.RoleAdmin {
+		return nil
+	}
+	admins, err := s.repo.CountAdmins(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	if admins <= 1 {
+		return ErrLastAdmin
+	}
+	return nil
+}
+
+// Owners resolves owner references to their public details. References to
+// users or organizations that no longer exist are left out of the result.
+func (s *Service) Owners(ctx context.Context, refs []auth.OwnerRef) (map[auth.OwnerRef]*Owner, error) {
+	ctx, span := tracer.Start(ctx, "identity.Service.Owners")
+	defer span.End()
+	var userIDs, orgIDs []string
+	seen := make(map[auth.OwnerRef]bool, len(refs))
+	for _, ref := range refs {
+		if ref.ID == "" || seen[ref] {
+			continue
+		}
+		seen[ref] = true
+		switch ref.Type {
+		case auth.OwnerUser:
+			userIDs = append(userIDs, ref.ID)
+		case auth.OwnerOrganization:
+			orgIDs = append(orgIDs, ref.ID)
+		}
+	}
+
+	owners := make(map[auth.OwnerRef]*Owner, len(seen))
+	if len(userIDs) > 0 {
+		users, err := s.repo.ListUsersByID(ctx, userIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range users {
+			ref := auth.OwnerRef{Type: auth.OwnerUser, ID: u.ID}
+			owners[ref] = &Owner{Type: ref.Type, ID: u.ID, Name: u.Name, AvatarURL: u.AvatarURL}
+		}
+	}
+	if len(orgIDs) > 0 {
+		orgs, err := s.repo.ListOrganizationsByID(ctx, orgIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range orgs {
+			ref := auth.OwnerRef{Type: auth.OwnerOrganization, ID: o.ID}
+			name := o.DisplayName
+			if name == "" {
+				name = o.Name
+			}
+			owners[ref] = &Owner{Type: ref.Type, ID: o.ID, Name: name, AvatarURL: o.AvatarURL}
+		}
+	}
+	return owners, nil
+}
+
+// requireOrgRole checks that the caller holds at least min in the
+// organization, or is a platform admin
+func requireOrgRole(ctx context.Context, orgID string, min auth.Role) error {
+	p, ok := auth.FromContext(ctx)
+	if !ok {
+		return auth.ErrUnauthenticated
+	}
+	if p.Admin {
+		return nil
+	}
+	if role, ok := p.RoleIn(orgID); ok && role.AtLeast(min) {
+		return nil
+	}
+	return auth.ErrForbidden
+}
+
+func validateOrganization(org *Organization) error {
+	if !orgNamePattern.MatchString(org.Name) {
+		return fmt.Errorf("%w: name must be lowercase letters, digits and dashes", ErrInvalidOrganization)
+	}
+	return nil
+}
+
+// translateError maps persistence errors onto the identity service errors
+func translateError(err, notFound error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return notFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return ErrOrganizationAlreadyExists
+	}
+	return err
+}
+
+// Owned is an entity that references its owner
+type Owned interface {
+	OwnerRef() auth.OwnerRef
+	SetOwner(owner *Owner)
+}
+
+// AttachOwners resolves and sets the owner details of each item
+func AttachOwners[T Owned](ctx context.Context, resolver OwnerResolver, items ...T) error {
+	refs := make([]auth.OwnerRef, len(items))
+	for i, item := range items {
+		refs[i] = item.OwnerRef()
+	}
+	owners, err := resolver.Owners(ctx, refs)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		item.SetOwner(owners[item.OwnerRef()])
+	}
+	return nil
+}
@@ -4,20 +4,26 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"time"
+
+	"gorm.io/gorm"
+
+	"robohub-inventory/pkg/auth"
+	"robohub-inventory/pkg/identity"
+	"robohub-inventory/pkg/store"
 )
 
 // Package represents a software package in the robotics platform
 // Matches API_CONTRACT.md Package schema
 type Package struct {
 	ID          string    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
-	Name        string    `gorm:"uniqueIndex;not null" json:"name"`                     // Package name (lowercase, no spaces)
+	Name        string    `gorm:"uniqueIndex:idx_packages_name_active,where:deleted_at IS NULL;not null" json:"name"`                     // Package name (lowercase, no spaces)
 	DisplayName string    `json:"displayName"`                                          // Human-readable name
 	Description string    `json:"description"`
 	Documentation string  `json:"documentation,omitempty"`                              // Markdown content or URL
 	
 	// Repository Information
-	RepoID   string `gorm:"index" json:"repoId"`
-	RepoName string `json:"repoName"`                                                  // org/repo format
+	RepoID   store.NullID `gorm:"type:uuid;index" json:"repoId"`                      // References repositories.id
+	RepoName string       `json:"repoName"`                                            // org/repo format; copied from the repository
 	Path     string `json:"path"`                                                      // Path within repo
 	
 	// Type Classification
@@ -34,24 +40,31 @@ type Package struct {
 	// Validation Status
 	ValidationStatus ValidationStatus `gorm:"type:jsonb" json:"validationStatus"`
 	
-	// Relationships
+	// Relationships (maintained by pkg/counter; read-only)
 	LinkedScenariosCount    int `gorm:"default:0" json:"linkedScenariosCount"`
 	LinkedDatasetsCount     int `gorm:"default:0" json:"linkedDatasetsCount"`
 	UsedInCollectionsCount  int `gorm:"default:0" json:"usedInCollectionsCount"`
 	
 	// Owner Information
-	Owner Owner `gorm:"type:jsonb" json:"owner"`
+	OwnerType string          `gorm:"index:idx_packages_owner" json:"ownerType,omitempty"` // "user" | "organization"
+	OwnerID   string          `gorm:"index:idx_packages_owner" json:"ownerId,omitempty"`
+	Owner     *identity.Owner `gorm:"-" json:"owner,omitempty"` // Resolved from OwnerType/OwnerID
 	
 	// Last Run
-	LastRun *LastRun `gorm:"type:jsonb" json:"lastRun,omitempty"`
+	LastRun           *LastRun     `gorm:"type:jsonb" json:"lastRun,omitempty"`
+	LastRunScenarioID store.NullID `gorm:"type:uuid;index" json:"-"` // References scenarios.id; mirrors LastRun.ScenarioID
 	
 	// License & Dependencies
 	License      string       `json:"license,omitempty"`
 	Dependencies Dependencies `gorm:"type:jsonb" json:"dependencies,omitempty"`
 	
+	// Concurrency
+	Revision int64 `gorm:"not null;default:1" json:"revision"` // Incremented on every update; exposed as the ETag
+	
 	// Timestamps
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deletedAt,omitempty"` // Set while the package is in the trash
 }
 
 // ValidationStatus represents package validation information
@@ -61,13 +74,6 @@ type ValidationStatus struct {
 	PassRate      float64   `json:"passRate"` // 0-100 percentage
 }
 
-// Owner represents the package owner
-type Owner struct {
-	ID        string `json:"id"`
-	Name      string `json:"name"`
-	AvatarURL string `json:"avatarUrl,omitempty"`
-}
-
 // LastRun represents the last run information
 type LastRun struct {
 	Status     string    `json:"status"`     // "pass" | "fail" | "pending"
@@ -122,23 +128,6 @@ func (v ValidationStatus) Value() (driver.Value, error) {
 	return json.Marshal(v)
 }
 
-// Scan implements sql.Scanner interface for JSONB
-func (o *Owner) Scan(value interface{}) error {
-	if value == nil {
-		return nil
-	}
-	bytes, ok := value.([]byte)
-	if !ok {
-		return nil
-	}
-	return json.Unmarshal(bytes, o)
-}
-
-// Value implements driver.Valuer interface for JSONB
-func (o Owner) Value() (driver.Value, error) {
-	return json.Marshal(o)
-}
-
 // Scan implements sql.Scanner interface for JSONB
 func (l *LastRun) Scan(value interface{}) error {
 	if value == nil {
@@ -159,3 +148,31 @@ func (l LastRun) Value() (driver.Value, error) {
 func (Package) TableName() string {
 	return "packages"
 }
+
+// BeforeSave stores the scenario of the last run in its foreign key column
+func (p *Package) BeforeSave(tx *gorm.DB) error {
+	p.LastRunScenarioID = ""
+	if p.LastRun != nil {
+		p.LastRunScenarioID = store.NullID(p.LastRun.ScenarioID)
+	}
+	return nil
+}
+
+// AfterFind reads the scenario of the last run back from its foreign key
+// column, which is cleared when the scenario is purged
+func (p *Package) AfterFind(tx *gorm.DB) error {
+	if p.LastRun != nil {
+		p.LastRun.ScenarioID = string(p.LastRunScenarioID)
+	}
+	return nil
+}
+
+// OwnerRef returns the reference to the package owner
+func (p *Package) OwnerRef() auth.OwnerRef {
+	return auth.OwnerRef{Type: p.OwnerType, ID: p.OwnerID}
+}
+
+// SetOwner sets the resolved owner details
+func (p *Package) SetOwner(owner *identity.Owner) {
+	p.Owner = owner
+}
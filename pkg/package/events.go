@@ -0,0 +1,56 @@
+package pkg
+
+import (
+	"robohub-inventory/pkg/event"
+)
+
+// runEventData describes a scenario run of a package
+type runEventData struct {
+	PackageID   string `json:"packageId"`
+	PackageName string `json:"packageName"`
+	*LastRun
+}
+
+// changeEvents returns the events of saving pkg over current, or of creating
+// pkg when current is nil. A run is reported when it is queued and once it
+// passed or failed.
+func changeEvents(current, pkg *Package) ([]event.Event, error) {
+	typ := event.PackageUpdated
+	if current == nil {
+		typ = event.PackageCreated
+	}
+	e, err := event.New(typ, "package", pkg.ID, pkg)
+	if err != nil {
+		return nil, err
+	}
+	events := []event.Event{e}
+
+	if run := pkg.LastRun; run != nil && run.ScenarioID != "" && !sameRun(current, run) {
+		var runType string
+		switch run.Status {
+		case "pending":
+			runType = event.ScenarioRunStarted
+		case "pass":
+			runType = event.ScenarioRunCompleted
+		case "fail":
+			runType = event.ScenarioRunFailed
+		}
+		if runType != "" {
+			e, err := event.New(runType, "scenario", run.ScenarioID, runEventData{PackageID: pkg.ID, PackageName: pkg.Name, LastRun: run})
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+// sameRun reports whether run is the last run of current already
+func sameRun(current *Package, run *LastRun) bool {
+	if current == nil || current.LastRun == nil {
+		return false
+	}
+	prev := current.LastRun
+	return prev.Status == run.Status && prev.ScenarioID == run.ScenarioID && prev.RunAt.Equal(run.RunAt)
+}
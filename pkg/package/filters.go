@@ -0,0 +1,31 @@
+package pkg
+
+import "robohub-inventory/pkg/query"
+
+// ListSchema whitelists the filters and sort orders of the package list (API_CONTRACT.md §2.1)
+var ListSchema = query.Schema{
+	Fields: []query.Field{
+		{
+			Param:  "type",
+			Column: "types",
+			Kind:   query.ArrayOverlaps,
+			Values: []string{"planner", "perception", "control", "sensors", "simulation", "infrastructure", "other"},
+		},
+		{Param: "repoId", Column: "repo_id", Kind: query.ID},
+		{
+			Param:  "status",
+			Column: "validation_status->>'status'",
+			Kind:   query.Equals,
+			Values: []string{"pass", "fail", "pending"},
+		},
+		{Param: "tag", Column: "tags", Kind: query.ArrayContains},
+		{Param: "license", Column: "license", Kind: query.Equals},
+	},
+	Search: []string{"name", "display_name", "description"},
+	Sorts: map[string]string{
+		"updated":     "updated_at DESC",
+		"name":        "name ASC",
+		"validations": "(validation_status->>'passRate')::numeric DESC NULLS LAST",
+		"popular":     "used_in_collections_count DESC, linked_scenarios_count DESC",
+	},
+}
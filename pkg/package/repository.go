@@ -2,6 +2,9 @@ package pkg
 
 import (
 	"context"
+	"time"
+
+	"robohub-inventory/pkg/query"
 )
 
 // Repository defines the interface for package persistence
@@ -9,7 +12,18 @@ type Repository interface {
 	Create(ctx context.Context, pkg *Package) error
 	GetByID(ctx context.Context, id string) (*Package, error)
 	GetByName(ctx context.Context, name string) (*Package, error)
-	List(ctx context.Context, limit, offset int) ([]*Package, error)
+	ListByIDs(ctx context.Context, ids []string) ([]*Package, error)
+	ListByIDsWithDeleted(ctx context.Context, ids []string) ([]*Package, error)
+	List(ctx context.Context, spec query.Spec) ([]*Package, error)
+	Count(ctx context.Context, spec query.Spec) (int64, error)
 	Update(ctx context.Context, pkg *Package) error
-	Delete(ctx context.Context, id string) error
+	Delete(ctx context.Context, id string, revision int64) error
+	GetDeleted(ctx context.Context, id string) (*Package, error)
+	ListDeleted(ctx context.Context, spec query.Spec) ([]*Package, error)
+	CountDeleted(ctx context.Context, spec query.Spec) (int64, error)
+	Restore(ctx context.Context, pkg *Package) error
+	Purge(ctx context.Context, before time.Time) (int64, error)
+	CountQueuedRuns(ctx context.Context) (int64, error)
+	RepositoryName(ctx context.Context, repoID string) (string, error)
+	MissingScenarios(ctx context.Context, ids []string) ([]string, error)
 }
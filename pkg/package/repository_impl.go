@@ -2,7 +2,13 @@ package pkg
 
 import (
 	"context"
+	"time"
+
 	"gorm.io/gorm"
+
+	"robohub-inventory/pkg/counter"
+	"robohub-inventory/pkg/query"
+	"robohub-inventory/pkg/store"
 )
 
 // gormRepository implements the Repository interface using GORM
@@ -15,13 +21,28 @@ func NewRepository(db *gorm.DB) Repository {
 	return &gormRepository{db: db}
 }
 
+// visible starts a query limited to the packages the caller may read
+func (r *gormRepository) visible(ctx context.Context) *gorm.DB {
+	v := query.VisibilityFor(ctx)
+	return store.Conn(ctx, r.db).Scopes(v.Scope(v.RepoCondition("packages")))
+}
+
 func (r *gormRepository) Create(ctx context.Context, pkg *Package) error {
-	return r.db.WithContext(ctx).Create(pkg).Error
+	err := store.Conn(ctx, r.db).Transaction(func(db *gorm.DB) error {
+		if err := db.Create(pkg).Error; err != nil {
+			return err
+		}
+		return refreshCounters(db, pkg.ID, refsOf(pkg))
+	})
+	if err != nil {
+		return err
+	}
+	return store.Conn(ctx, r.db).Where("id = ?", pkg.ID).First(pkg).Error
 }
 
 func (r *gormRepository) GetByID(ctx context.Context, id string) (*Package, error) {
 	var pkg Package
-	err := r.db.WithContext(ctx).Where("id = ?", id).First(&pkg).Error
+	err := r.visible(ctx).Where("id = ?", id).First(&pkg).Error
 	if err != nil {
 		return nil, err
 	}
@@ -30,30 +51,216 @@ func (r *gormRepository) GetByID(ctx context.Context, id string) (*Package, erro
 
 func (r *gormRepository) GetByName(ctx context.Context, name string) (*Package, error) {
 	var pkg Package
-	err := r.db.WithContext(ctx).Where("name = ?", name).First(&pkg).Error
+	err := r.visible(ctx).Where("name = ?", name).First(&pkg).Error
 	if err != nil {
 		return nil, err
 	}
 	return &pkg, nil
 }
 
-func (r *gormRepository) List(ctx context.Context, limit, offset int) ([]*Package, error) {
+func (r *gormRepository) ListByIDs(ctx context.Context, ids []string) ([]*Package, error) {
 	var packages []*Package
-	query := r.db.WithContext(ctx)
-	if limit > 0 {
-		query = query.Limit(limit)
+	err := r.visible(ctx).Where("id IN ?", ids).Find(&packages).Error
+	return packages, err
+}
+
+// ListByIDsWithDeleted loads the packages with the given IDs, including
+// those in the trash, whatever the caller may read
+func (r *gormRepository) ListByIDsWithDeleted(ctx context.Context, ids []string) ([]*Package, error) {
+	var packages []*Package
+	err := store.Conn(ctx, r.db).Unscoped().Where("id IN ?", ids).Order("name").Find(&packages).Error
+	return packages, err
+}
+
+func (r *gormRepository) List(ctx context.Context, spec query.Spec) ([]*Package, error) {
+	var packages []*Package
+	err := spec.Apply(r.visible(ctx)).Find(&packages).Error
+	return packages, err
+}
+
+func (r *gormRepository) Count(ctx context.Context, spec query.Spec) (int64, error) {
+	var count int64
+	err := spec.Where(r.visible(ctx).Model(&Package{})).Count(&count).Error
+	return count, err
+}
+
+// Update saves pkg if its stored revision still equals pkg.Revision and bumps the revision
+func (r *gormRepository) Update(ctx context.Context, pkg *Package) error {
+	revision := pkg.Revision
+	err := store.Conn(ctx, r.db).Transaction(func(db *gorm.DB) error {
+		before, err := loadRefs(db, pkg.ID)
+		if err != nil {
+			return err
+		}
+		pkg.Revision = revision + 1
+		result := db.Model(pkg).Where("id = ? AND revision = ?", pkg.ID, revision).
+			Select("*").Omit(append([]string{"id", "created_at", "deleted_at"}, counter.PackageColumns...)...).Updates(pkg)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return store.ErrRevisionMismatch
+		}
+		return refreshCounters(db, pkg.ID, before, refsOf(pkg))
+	})
+	if err != nil {
+		pkg.Revision = revision
+		return err
 	}
-	if offset > 0 {
-		query = query.Offset(offset)
+	return store.Conn(ctx, r.db).Where("id = ?", pkg.ID).First(pkg).Error
+}
+
+// Delete moves the package to the trash, bumping its revision; a non-zero
+// revision must match the stored one
+func (r *gormRepository) Delete(ctx context.Context, id string, revision int64) error {
+	return store.Conn(ctx, r.db).Transaction(func(db *gorm.DB) error {
+		stmt := db.Where("id = ?", id)
+		if revision != 0 {
+			stmt = stmt.Where("revision = ?", revision)
+		}
+		result := stmt.Model(&Package{}).UpdateColumns(store.Trashed())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			if revision != 0 {
+				return store.ErrRevisionMismatch
+			}
+			return gorm.ErrRecordNotFound
+		}
+		refs, err := loadRefs(db, id)
+		if err != nil {
+			return err
+		}
+		return refreshCounters(db, id, refs)
+	})
+}
+
+// trash starts a query limited to the deleted packages the caller may restore
+func (r *gormRepository) trash(ctx context.Context) *gorm.DB {
+	v := query.VisibilityFor(ctx)
+	return store.Conn(ctx, r.db).Unscoped().Where("packages.deleted_at IS NOT NULL").
+		Scopes(v.Scope(v.ManageCondition("packages")))
+}
+
+func (r *gormRepository) GetDeleted(ctx context.Context, id string) (*Package, error) {
+	var pkg Package
+	err := r.visible(ctx).Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&pkg).Error
+	if err != nil {
+		return nil, err
 	}
-	err := query.Order("created_at DESC").Find(&packages).Error
+	return &pkg, nil
+}
+
+func (r *gormRepository) ListDeleted(ctx context.Context, spec query.Spec) ([]*Package, error) {
+	var packages []*Package
+	err := spec.Apply(r.trash(ctx)).Find(&packages).Error
 	return packages, err
 }
 
-func (r *gormRepository) Update(ctx context.Context, pkg *Package) error {
-	return r.db.WithContext(ctx).Save(pkg).Error
+func (r *gormRepository) CountDeleted(ctx context.Context, spec query.Spec) (int64, error) {
+	var count int64
+	err := spec.Where(r.trash(ctx).Model(&Package{})).Count(&count).Error
+	return count, err
+}
+
+// Restore takes the package out of the trash, bumping its revision. Packages of a repository
+// in the trash cannot be restored on their own.
+func (r *gormRepository) Restore(ctx context.Context, pkg *Package) error {
+	err := store.Conn(ctx, r.db).Transaction(func(db *gorm.DB) error {
+		result := db.Unscoped().Model(&Package{}).Where("id = ? AND deleted_at IS NOT NULL", pkg.ID).
+			Where("NOT EXISTS (SELECT 1 FROM repositories WHERE repositories.id = packages.repo_id AND repositories.deleted_at IS NOT NULL)").
+			Updates(map[string]interface{}{"deleted_at": nil, "revision": gorm.Expr("revision + 1")})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRepositoryDeleted
+		}
+		refs, err := loadRefs(db, pkg.ID)
+		if err != nil {
+			return err
+		}
+		return refreshCounters(db, pkg.ID, refs)
+	})
+	if err != nil {
+		return err
+	}
+	return store.Conn(ctx, r.db).Where("id = ?", pkg.ID).First(pkg).Error
+}
+
+// Purge permanently deletes the packages moved to the trash before the given time
+func (r *gormRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	result := store.Conn(ctx, r.db).Unscoped().Where("deleted_at < ?", before).Delete(&Package{})
+	return result.RowsAffected, result.Error
+}
+
+// CountQueuedRuns counts the live packages whose last run is still pending,
+// regardless of the caller's visibility
+func (r *gormRepository) CountQueuedRuns(ctx context.Context) (int64, error) {
+	var count int64
+	err := store.Conn(ctx, r.db).Model(&Package{}).
+		Where("last_run->>'status' = ?", "pending").
+		Count(&count).Error
+	return count, err
+}
+
+// RepositoryName returns the name of a live repository the caller may read
+func (r *gormRepository) RepositoryName(ctx context.Context, repoID string) (string, error) {
+	v := query.VisibilityFor(ctx)
+	var names []string
+	err := store.Conn(ctx, r.db).Table("repositories").
+		Where("id = ? AND deleted_at IS NULL", repoID).
+		Scopes(v.Scope(v.Condition("repositories"))).
+		Limit(1).Pluck("name", &names).Error
+	if err != nil {
+		return "", err
+	}
+	if len(names) == 0 {
+		return "", gorm.ErrRecordNotFound
+	}
+	return names[0], nil
+}
+
+// MissingScenarios returns the ids that match no live scenario
+func (r *gormRepository) MissingScenarios(ctx context.Context, ids []string) ([]string, error) {
+	return store.MissingIDs(store.Conn(ctx, r.db).Table("scenarios").Where("deleted_at IS NULL"), ids)
+}
+
+// packageRefs are the entities a package counts towards
+type packageRefs struct {
+	RepoID            store.NullID
+	LastRunScenarioID store.NullID
 }
 
-func (r *gormRepository) Delete(ctx context.Context, id string) error {
-	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&Package{}).Error
+func refsOf(pkg *Package) packageRefs {
+	refs := packageRefs{RepoID: pkg.RepoID}
+	if pkg.LastRun != nil {
+		refs.LastRunScenarioID = store.NullID(pkg.LastRun.ScenarioID)
+	}
+	return refs
+}
+
+// loadRefs reads the stored references of a package, including one in the trash
+func loadRefs(db *gorm.DB, id string) (packageRefs, error) {
+	var refs packageRefs
+	err := db.Table("packages").Select("repo_id, last_run_scenario_id").Where("id = ?", id).Limit(1).Scan(&refs).Error
+	return refs, err
+}
+
+// refreshCounters recomputes the counters of the package and of the
+// repositories and scenarios it referenced before and after a change
+func refreshCounters(db *gorm.DB, id string, refs ...packageRefs) error {
+	var repos, scenarios []string
+	for _, ref := range refs {
+		repos = append(repos, string(ref.RepoID))
+		scenarios = append(scenarios, string(ref.LastRunScenarioID))
+	}
+	if err := counter.Packages(db, id); err != nil {
+		return err
+	}
+	if err := counter.Repositories(db, repos...); err != nil {
+		return err
+	}
+	return counter.Scenarios(db, scenarios...)
 }
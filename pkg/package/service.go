@@ -3,56 +3,393 @@ package pkg
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"gorm.io/gorm"
+
+	"robohub-inventory/pkg/auth"
+	"robohub-inventory/pkg/event"
+	"robohub-inventory/pkg/identity"
+	"robohub-inventory/pkg/patch"
+	"robohub-inventory/pkg/query"
+	"robohub-inventory/pkg/store"
 )
 
 var (
-	ErrPackageNotFound = errors.New("package not found")
-	ErrInvalidPackage  = errors.New("invalid package data")
+	ErrPackageNotFound      = errors.New("package not found")
+	ErrInvalidPackage       = errors.New("invalid package data")
+	ErrPackageAlreadyExists = errors.New("package already exists")
+	ErrPackageNameTaken     = errors.New("package name is taken by another package; rename one of them before restoring")
+	ErrRepositoryDeleted    = errors.New("package repository is in the trash")
 )
 
+var tracer = otel.Tracer("robohub-inventory/pkg/package")
+
 // Service handles business logic for packages
 type Service struct {
-	repo Repository
+	repo   Repository
+	owners identity.OwnerResolver
+	tx     *store.Transactor
+	events event.Publisher
 }
 
-func NewService(repo Repository) *Service {
-	return &Service{repo: repo}
+func NewService(repo Repository, owners identity.OwnerResolver, tx *store.Transactor, events event.Publisher) *Service {
+	return &Service{repo: repo, owners: owners, tx: tx, events: events}
 }
 
+// CreatePackage stores a new package owned by the requested owner, defaulting to the caller
 func (s *Service) CreatePackage(ctx context.Context, pkg *Package) error {
-	if pkg.Name == "" {
-		return ErrInvalidPackage
+	ctx, span := tracer.Start(ctx, "package.Service.CreatePackage")
+	defer span.End()
+	if err := validatePackage(pkg); err != nil {
+		return err
+	}
+	owner, err := auth.AssignOwner(ctx, pkg.OwnerRef())
+	if err != nil {
+		return err
 	}
-	return s.repo.Create(ctx, pkg)
+	pkg.OwnerType, pkg.OwnerID = owner.Type, owner.ID
+	if err := s.resolveReferences(ctx, pkg, nil); err != nil {
+		return err
+	}
+	pkg.Revision, pkg.DeletedAt = 1, gorm.DeletedAt{}
+	pkg.LinkedScenariosCount, pkg.LinkedDatasetsCount, pkg.UsedInCollectionsCount = 0, 0, 0
+	return s.save(ctx, nil, pkg)
 }
 
 func (s *Service) GetPackage(ctx context.Context, id string) (*Package, error) {
+	ctx, span := tracer.Start(ctx, "package.Service.GetPackage")
+	defer span.End()
 	pkg, err := s.repo.GetByID(ctx, id)
 	if err != nil {
-		return nil, ErrPackageNotFound
+		return nil, translateError(err)
+	}
+	if err := identity.AttachOwners(ctx, s.owners, pkg); err != nil {
+		return nil, err
 	}
 	return pkg, nil
 }
 
 func (s *Service) GetPackageByName(ctx context.Context, name string) (*Package, error) {
+	ctx, span := tracer.Start(ctx, "package.Service.GetPackageByName")
+	defer span.End()
 	pkg, err := s.repo.GetByName(ctx, name)
 	if err != nil {
-		return nil, ErrPackageNotFound
+		return nil, translateError(err)
+	}
+	if err := identity.AttachOwners(ctx, s.owners, pkg); err != nil {
+		return nil, err
 	}
 	return pkg, nil
 }
 
-func (s *Service) ListPackages(ctx context.Context, limit, offset int) ([]*Package, error) {
-	return s.repo.List(ctx, limit, offset)
+// GetPackagesBatch loads the packages with the given IDs in one query. It returns them
+// in request order together with the IDs that were not found.
+func (s *Service) GetPackagesBatch(ctx context.Context, ids []string) ([]*Package, []string, error) {
+	ctx, span := tracer.Start(ctx, "package.Service.GetPackagesBatch")
+	defer span.End()
+	valid, err := query.BatchIDs(ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	var packages []*Package
+	if len(valid) > 0 {
+		if packages, err = s.repo.ListByIDs(ctx, valid); err != nil {
+			return nil, nil, err
+		}
+	}
+	found, notFound := query.OrderBatch(ids, packages, func(x *Package) string { return x.ID })
+	if err := identity.AttachOwners(ctx, s.owners, found...); err != nil {
+		return nil, nil, err
+	}
+	return found, notFound, nil
+}
+
+// ListPackages returns one page of packages matching spec together with the total count
+func (s *Service) ListPackages(ctx context.Context, spec query.Spec) ([]*Package, int64, error) {
+	ctx, span := tracer.Start(ctx, "package.Service.ListPackages")
+	defer span.End()
+	packages, err := s.repo.List(ctx, spec)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := s.repo.Count(ctx, spec)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := identity.AttachOwners(ctx, s.owners, packages...); err != nil {
+		return nil, 0, err
+	}
+	return packages, total, nil
 }
 
+// UpdatePackage replaces a package; only its owner or the owning org's maintainers may
+// update it. An empty owner in the update keeps the current one, and a
+// non-zero revision must match the stored revision.
 func (s *Service) UpdatePackage(ctx context.Context, pkg *Package) error {
+	ctx, span := tracer.Start(ctx, "package.Service.UpdatePackage")
+	defer span.End()
+	if err := validatePackage(pkg); err != nil {
+		return err
+	}
+	current, err := s.repo.GetByID(ctx, pkg.ID)
+	if err != nil {
+		return translateError(err)
+	}
+	owner, err := auth.Reassign(ctx, current.OwnerRef(), pkg.OwnerRef())
+	if err != nil {
+		return err
+	}
+	if err := store.CheckRevision(pkg.Revision, current.Revision); err != nil {
+		return err
+	}
+	if err := s.resolveReferences(ctx, pkg, current); err != nil {
+		return err
+	}
+	pkg.OwnerType, pkg.OwnerID, pkg.Revision = owner.Type, owner.ID, current.Revision
+	return s.save(ctx, current, pkg)
+}
+
+// save creates pkg, or updates current to pkg, and publishes the events of
+// the change in the same transaction
+func (s *Service) save(ctx context.Context, current, pkg *Package) error {
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if current == nil {
+			err = s.repo.Create(ctx, pkg)
+		} else {
+			err = s.repo.Update(ctx, pkg)
+		}
+		if err != nil {
+			return translateError(err)
+		}
+		if err := identity.AttachOwners(ctx, s.owners, pkg); err != nil {
+			return err
+		}
+		events, err := changeEvents(current, pkg)
+		if err != nil {
+			return err
+		}
+		return s.events.Publish(ctx, events...)
+	})
+}
+
+// UpsertPackage creates the package or, if one with the same name exists, updates it
+// in place. It reports whether the package was created.
+func (s *Service) UpsertPackage(ctx context.Context, pkg *Package) (bool, error) {
+	ctx, span := tracer.Start(ctx, "package.Service.UpsertPackage")
+	defer span.End()
+	existing, err := s.repo.GetByName(ctx, pkg.Name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return true, s.CreatePackage(ctx, pkg)
+	}
+	if err != nil {
+		return false, err
+	}
+	pkg.ID, pkg.Revision = existing.ID, 0
+	return false, s.UpdatePackage(ctx, pkg)
+}
+
+// PatchPackage applies a merge patch or JSON Patch to the stored package and saves the result,
+// validating the merged package as a whole.
+func (s *Service) PatchPackage(ctx context.Context, id string, revision int64, p *patch.Patch) (*Package, error) {
+	ctx, span := tracer.Start(ctx, "package.Service.PatchPackage")
+	defer span.End()
+	current, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	if err := store.CheckRevision(revision, current.Revision); err != nil {
+		return nil, err
+	}
+	var pkg Package
+	if err := p.Apply(current, &pkg); err != nil {
+		return nil, err
+	}
+	// Saving against the revision the patch was applied to keeps concurrent writes from being lost
+	pkg.ID, pkg.CreatedAt, pkg.Revision = current.ID, current.CreatedAt, current.Revision
+	if err := s.UpdatePackage(ctx, &pkg); err != nil {
+		return nil, err
+	}
+	return &pkg, nil
+}
+
+// DeletePackage moves a package to the trash; only its owner or the owning org's
+// maintainers may delete it. A non-zero revision must match the stored revision.
+func (s *Service) DeletePackage(ctx context.Context, id string, revision int64) error {
+	ctx, span := tracer.Start(ctx, "package.Service.DeletePackage")
+	defer span.End()
+	current, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return translateError(err)
+	}
+	if err := auth.CanModify(ctx, current.OwnerRef()); err != nil {
+		return err
+	}
+	if err := store.CheckRevision(revision, current.Revision); err != nil {
+		return err
+	}
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.repo.Delete(ctx, id, current.Revision); err != nil {
+			return translateError(err)
+		}
+		pkg, err := s.repo.GetDeleted(ctx, id)
+		if err != nil {
+			return err
+		}
+		return s.publishTrashEvent(ctx, event.PackageDeleted, pkg)
+	})
+}
+
+// ListDeletedPackages returns one page of the deleted packages the caller may restore, together with the total count
+func (s *Service) ListDeletedPackages(ctx context.Context, spec query.Spec) ([]*Package, int64, error) {
+	ctx, span := tracer.Start(ctx, "package.Service.ListDeletedPackages")
+	defer span.End()
+	packages, err := s.repo.ListDeleted(ctx, spec)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := s.repo.CountDeleted(ctx, spec)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := identity.AttachOwners(ctx, s.owners, packages...); err != nil {
+		return nil, 0, err
+	}
+	return packages, total, nil
+}
+
+// RestorePackage takes a package out of the trash; only its owner or the owning org's maintainers may restore it. A package deleted with
+// its repository comes back when the repository is restored.
+func (s *Service) RestorePackage(ctx context.Context, id string) (*Package, error) {
+	ctx, span := tracer.Start(ctx, "package.Service.RestorePackage")
+	defer span.End()
+	current, err := s.repo.GetDeleted(ctx, id)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	if err := auth.CanModify(ctx, current.OwnerRef()); err != nil {
+		return nil, err
+	}
+	err = s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.repo.Restore(ctx, current); err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return ErrPackageNameTaken
+			}
+			return translateError(err)
+		}
+		return s.publishTrashEvent(ctx, event.PackageRestored, current)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return current, nil
+}
+
+// publishTrashEvent publishes an event of type typ about pkg, which was moved
+// to or out of the trash
+func (s *Service) publishTrashEvent(ctx context.Context, typ string, pkg *Package) error {
+	if err := identity.AttachOwners(ctx, s.owners, pkg); err != nil {
+		return err
+	}
+	e, err := event.New(typ, "package", pkg.ID, pkg)
+	if err != nil {
+		return err
+	}
+	return s.events.Publish(ctx, e)
+}
+
+// PackageEvents returns an event of type typ for each of the packages with
+// the given IDs, in or out of the trash. The repository service publishes
+// them for the packages it changes.
+func (s *Service) PackageEvents(ctx context.Context, typ string, ids []string) ([]event.Event, error) {
+	ctx, span := tracer.Start(ctx, "package.Service.PackageEvents")
+	defer span.End()
+	packages, err := s.repo.ListByIDsWithDeleted(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	if err := identity.AttachOwners(ctx, s.owners, packages...); err != nil {
+		return nil, err
+	}
+	events := make([]event.Event, 0, len(packages))
+	for _, pkg := range packages {
+		e, err := event.New(typ, "package", pkg.ID, pkg)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+// PurgeDeletedPackages permanently deletes the packages moved to the trash before the given time
+func (s *Service) PurgeDeletedPackages(ctx context.Context, before time.Time) (int64, error) {
+	ctx, span := tracer.Start(ctx, "package.Service.PurgeDeletedPackages")
+	defer span.End()
+	return s.repo.Purge(ctx, before)
+}
+
+// CountQueuedRuns counts the packages whose last scenario run is still pending
+func (s *Service) CountQueuedRuns(ctx context.Context) (int64, error) {
+	ctx, span := tracer.Start(ctx, "package.Service.CountQueuedRuns")
+	defer span.End()
+	return s.repo.CountQueuedRuns(ctx)
+}
+
+// resolveReferences checks that the repository and the last-run scenario of pkg
+// exist and copies the repository name. A last-run scenario unchanged from
+// current is not checked again, so that packages stay editable after the
+// scenario went to the trash.
+func (s *Service) resolveReferences(ctx context.Context, pkg, current *Package) error {
+	pkg.RepoName = ""
+	if pkg.RepoID != "" {
+		id := string(pkg.RepoID)
+		if !query.ValidID(id) {
+			return fmt.Errorf("%w: repoId %q does not match a repository", store.ErrInvalidReference, id)
+		}
+		name, err := s.repo.RepositoryName(ctx, id)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: repoId %q does not match a repository", store.ErrInvalidReference, id)
+		}
+		if err != nil {
+			return err
+		}
+		pkg.RepoName = name
+	}
+
+	if pkg.LastRun == nil || pkg.LastRun.ScenarioID == "" {
+		return nil
+	}
+	if current != nil && current.LastRun != nil && current.LastRun.ScenarioID == pkg.LastRun.ScenarioID {
+		return nil
+	}
+	missing, err := s.repo.MissingScenarios(ctx, []string{pkg.LastRun.ScenarioID})
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: lastRun.scenarioId %q does not match a scenario", store.ErrInvalidReference, missing[0])
+	}
+	return nil
+}
+
+func validatePackage(pkg *Package) error {
 	if pkg.Name == "" {
-		return ErrInvalidPackage
+		return fmt.Errorf("%w: name is required", ErrInvalidPackage)
 	}
-	return s.repo.Update(ctx, pkg)
+	return nil
 }
 
-func (s *Service) DeletePackage(ctx context.Context, id string) error {
-	return s.repo.Delete(ctx, id)
+// translateError maps persistence errors onto the package service errors
+func translateError(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrPackageNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return ErrPackageAlreadyExists
+	}
+	return err
 }
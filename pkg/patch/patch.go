@@ -0,0 +1,181 @@
+// Package patch applies RFC 7396 JSON Merge Patch and RFC 6902 JSON Patch
+// documents to stored entities.
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"sort"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+)
+
+// Media types accepted by PATCH endpoints. Plain application/json is read as a merge patch.
+const (
+	MediaTypeMergePatch = "application/merge-patch+json"
+	MediaTypeJSONPatch  = "application/json-patch+json"
+)
+
+var (
+	ErrInvalidPatch         = errors.New("invalid patch")
+	ErrUnsupportedMediaType = errors.New("unsupported patch media type")
+	ErrTestFailed           = errors.New("patch test operation failed")
+)
+
+// readOnly lists fields that no patch may change
+var readOnly = []string{
+	"id", "owner", "revision", "createdAt", "updatedAt", "deletedAt",
+	// Relationship counters maintained by the store
+	"packageCount", "linkedScenariosCount", "linkedDatasetsCount", "usedInCollectionsCount", "usedByPackagesCount",
+}
+
+// Patch is a parsed merge patch or JSON Patch document
+type Patch struct {
+	merge  []byte
+	ops    jsonpatch.Patch
+	fields []string
+}
+
+// Parse reads body as the patch format named by contentType
+func Parse(contentType string, body []byte) (*Patch, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedMediaType, contentType)
+	}
+
+	p := &Patch{}
+	switch mediaType {
+	case MediaTypeMergePatch, "application/json":
+		var doc map[string]json.RawMessage
+		if err := json.Unmarshal(body, &doc); err != nil || doc == nil {
+			return nil, fmt.Errorf("%w: merge patch must be a JSON object", ErrInvalidPatch)
+		}
+		p.merge = body
+		for field := range doc {
+			p.fields = append(p.fields, field)
+		}
+	case MediaTypeJSONPatch:
+		ops, err := jsonpatch.DecodePatch(body)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+		p.ops = ops
+		if p.fields, err = opFields(ops); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: use %s or %s", ErrUnsupportedMediaType, MediaTypeMergePatch, MediaTypeJSONPatch)
+	}
+
+	sort.Strings(p.fields)
+	for _, field := range readOnly {
+		if p.touches(field) {
+			return nil, fmt.Errorf("%w: %s is read-only", ErrInvalidPatch, field)
+		}
+	}
+	return p, nil
+}
+
+// Restrict rejects the patch if it touches any top-level field outside allowed
+func (p *Patch) Restrict(allowed ...string) error {
+	for _, field := range p.fields {
+		ok := false
+		for _, a := range allowed {
+			if field == a {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return fmt.Errorf("%w: %s cannot be patched; allowed fields are %s", ErrInvalidPatch, field, strings.Join(allowed, ", "))
+		}
+	}
+	return nil
+}
+
+// Apply patches the JSON form of current and decodes the result into dst.
+// Fields the patch removes or nulls take their zero value in dst.
+func (p *Patch) Apply(current, dst interface{}) error {
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+
+	if p.merge != nil {
+		doc, err = jsonpatch.MergePatch(doc, p.merge)
+	} else {
+		doc, err = p.ops.Apply(doc)
+	}
+	switch {
+	case errors.Is(err, jsonpatch.ErrTestFailed):
+		return fmt.Errorf("%w: %v", ErrTestFailed, err)
+	case err != nil:
+		return fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	if err := json.Unmarshal(doc, dst); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	return nil
+}
+
+func (p *Patch) touches(field string) bool {
+	i := sort.SearchStrings(p.fields, field)
+	return i < len(p.fields) && p.fields[i] == field
+}
+
+// opFields returns the top-level fields written by ops. Test operations
+// only read, while a move also writes by removing its source.
+func opFields(ops jsonpatch.Patch) ([]string, error) {
+	seen := make(map[string]bool)
+	var fields []string
+	add := func(path string) error {
+		field, err := topLevel(path)
+		if err != nil {
+			return err
+		}
+		if !seen[field] {
+			seen[field] = true
+			fields = append(fields, field)
+		}
+		return nil
+	}
+
+	for _, op := range ops {
+		path, err := op.Path()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+		switch op.Kind() {
+		case "test":
+			continue
+		case "move":
+			from, err := op.From()
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+			}
+			if err := add(from); err != nil {
+				return nil, err
+			}
+		}
+		if err := add(path); err != nil {
+			return nil, err
+		}
+	}
+	return fields, nil
+}
+
+// topLevel returns the first reference token of a JSON pointer
+func topLevel(pointer string) (string, error) {
+	if pointer == "" || pointer == "/" {
+		return "", fmt.Errorf("%w: operations must target a field, not the whole document", ErrInvalidPatch)
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return "", fmt.Errorf("%w: invalid JSON pointer %q", ErrInvalidPatch, pointer)
+	}
+	token := strings.SplitN(pointer[1:], "/", 2)[0]
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(token), nil
+}
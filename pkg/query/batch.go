@@ -0,0 +1,66 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MaxBatchIDs is the largest number of IDs a batch get accepts (API_CONTRACT.md §11)
+const MaxBatchIDs = 50
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// BatchIDs validates the IDs of a batch get and returns the distinct,
+// well-formed ones to look up. IDs differing in case only are the same ID.
+// Malformed IDs cannot match any row and end up reported as not found.
+func BatchIDs(ids []string) ([]string, error) {
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("%w: ids is required", ErrInvalidQuery)
+	}
+	if len(ids) > MaxBatchIDs {
+		return nil, fmt.Errorf("%w: at most %d ids are allowed, got %d", ErrInvalidQuery, MaxBatchIDs, len(ids))
+	}
+
+	seen := make(map[string]bool, len(ids))
+	var valid []string
+	for _, id := range ids {
+		key := strings.ToLower(id)
+		if !seen[key] && ValidID(id) {
+			valid = append(valid, id)
+		}
+		seen[key] = true
+	}
+	return valid, nil
+}
+
+// ValidID reports whether id is a well-formed entity ID
+func ValidID(id string) bool {
+	return uuidPattern.MatchString(id)
+}
+
+// OrderBatch arranges the items found by a batch get in the order of ids and
+// lists the IDs that matched nothing. IDs are compared ignoring case, since
+// PostgreSQL returns UUIDs in lower case.
+func OrderBatch[T any](ids []string, items []T, idOf func(T) string) (found []T, notFound []string) {
+	byID := make(map[string]T, len(items))
+	for _, item := range items {
+		byID[strings.ToLower(idOf(item))] = item
+	}
+	seen := make(map[string]bool, len(ids))
+	found = make([]T, 0, len(items))
+	notFound = []string{}
+	for _, id := range ids {
+		key := strings.ToLower(id)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if item, ok := byID[key]; ok {
+			found = append(found, item)
+		} else {
+			notFound = append(notFound, id)
+		}
+	}
+	return found, notFound
+}
@@ -0,0 +1,82 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	idA = "0b6f1c2e-8d4a-4f4e-9a51-2f7c3e9d1a01"
+	idB = "7c2d9e4f-1a3b-4c5d-8e6f-9a0b1c2d3e02"
+)
+
+func TestBatchIDs(t *testing.T) {
+	upperA := strings.ToUpper(idA)
+	tests := []struct {
+		name string
+		ids  []string
+		want []string
+	}{
+		{"distinct", []string{idA, idB}, []string{idA, idB}},
+		{"duplicate", []string{idA, idB, idA}, []string{idA, idB}},
+		{"uppercase", []string{upperA}, []string{upperA}},
+		{"mixed-case duplicate", []string{upperA, idB, idA}, []string{upperA, idB}},
+		{"malformed", []string{"not-a-uuid", idB}, []string{idB}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := BatchIDs(tt.ids)
+			if err != nil {
+				t.Fatalf("BatchIDs(%v): %v", tt.ids, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BatchIDs(%v) = %v, want %v", tt.ids, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBatchIDsLimits(t *testing.T) {
+	if _, err := BatchIDs(nil); !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("BatchIDs(nil) error = %v, want ErrInvalidQuery", err)
+	}
+	ids := make([]string, MaxBatchIDs+1)
+	for i := range ids {
+		ids[i] = idA
+	}
+	if _, err := BatchIDs(ids); !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("BatchIDs(%d ids) error = %v, want ErrInvalidQuery", len(ids), err)
+	}
+}
+
+func TestOrderBatch(t *testing.T) {
+	// Items come back from PostgreSQL in lower case and in any order
+	items := []string{idB, idA}
+	upperA := strings.ToUpper(idA)
+	missing := "3e4f5a6b-7c8d-4e9f-8a0b-1c2d3e4f5a03"
+
+	tests := []struct {
+		name         string
+		ids          []string
+		wantFound    []string
+		wantNotFound []string
+	}{
+		{"request order", []string{idA, idB}, []string{idA, idB}, []string{}},
+		{"uppercase", []string{upperA}, []string{idA}, []string{}},
+		{"mixed-case duplicate", []string{upperA, idA, idB}, []string{idA, idB}, []string{}},
+		{"missing", []string{missing, idB, "not-a-uuid"}, []string{idB}, []string{missing, "not-a-uuid"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, notFound := OrderBatch(tt.ids, items, func(id string) string { return id })
+			if !reflect.DeepEqual(found, tt.wantFound) {
+				t.Errorf("found = %v, want %v", found, tt.wantFound)
+			}
+			if !reflect.DeepEqual(notFound, tt.wantNotFound) {
+				t.Errorf("notFound = %v, want %v", notFound, tt.wantNotFound)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package query
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+var ErrInvalidQuery = errors.New("invalid query parameters")
+
+// Page selects a window of a list, either by offset or by keyset cursor
+type Page struct {
+	Limit  int
+	Offset int
+	After  *Cursor // Keyset pagination; takes precedence over Offset
+}
+
+// Cursor identifies a row in the default (created_at DESC, id DESC) ordering
+type Cursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// ParsePage reads limit, offset and cursor from query parameters
+func ParsePage(values url.Values) (Page, error) {
+	page := Page{Limit: DefaultLimit}
+
+	if v := values.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return page, fmt.Errorf("%w: limit must be a positive integer", ErrInvalidQuery)
+		}
+		page.Limit = limit
+	}
+	if page.Limit > MaxLimit {
+		page.Limit = MaxLimit
+	}
+
+	if v := values.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return page, fmt.Errorf("%w: offset must be a non-negative integer", ErrInvalidQuery)
+		}
+		page.Offset = offset
+	}
+
+	if v := values.Get("cursor"); v != "" {
+		cursor, err := DecodeCursor(v)
+		if err != nil {
+			return page, err
+		}
+		page.After = cursor
+		page.Offset = 0
+	}
+
+	return page, nil
+}
+
+// Apply adds the ordering, keyset predicate and window to a query
+func (p Page) Apply(db *gorm.DB) *gorm.DB {
+	db = db.Order("created_at DESC").Order("id DESC")
+	if p.After != nil {
+		db = db.Where("(created_at, id) < (?, ?)", p.After.CreatedAt, p.After.ID)
+	} else if p.Offset > 0 {
+		db = db.Offset(p.Offset)
+	}
+	if p.Limit > 0 {
+		db = db.Limit(p.Limit)
+	}
+	return db
+}
+
+// Encode returns the opaque string form of the cursor
+func (c Cursor) Encode() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor parses a cursor produced by Cursor.Encode
+func DecodeCursor(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	createdAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok || id == "" {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	ts, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	return &Cursor{CreatedAt: ts, ID: id}, nil
+}
@@ -0,0 +1,275 @@
+package query
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// FilterKind describes how a filter parameter is translated into SQL
+type FilterKind int
+
+const (
+	// Equals matches column = value, or column IN (...) for several values
+	Equals FilterKind = iota
+	// ArrayOverlaps matches text[] columns sharing at least one value
+	ArrayOverlaps
+	// ArrayContains matches text[] columns containing every value
+	ArrayContains
+	// Boolean matches column = true|false
+	Boolean
+	// Bucket matches the SQL predicate registered for the value in Buckets
+	Bucket
+	// ID matches a UUID column like Equals, rejecting malformed IDs
+	ID
+)
+
+// Field whitelists a filter parameter and the column it applies to
+type Field struct {
+	Param   string // Query parameter name
+	Column  string // Column or SQL expression; never taken from input
+	Kind    FilterKind
+	Values  []string          // Allowed values; empty allows any value
+	Buckets map[string]string // Value -> SQL predicate, for Bucket filters
+}
+
+// Schema describes the filters, search columns and sort orders of a list endpoint
+type Schema struct {
+	Fields []Field
+	Search []string          // Columns matched case-insensitively by ?search=
+	Sorts  map[string]string // Sort key -> ORDER BY expression
+}
+
+// Filter is a parsed filter parameter
+type Filter struct {
+	Field  Field
+	Values []string
+}
+
+// Spec is a parsed list request: filters, free-text search, sort and page
+type Spec struct {
+	Page    Page
+	Filters []Filter
+	Search  string
+
+	searchColumns []string
+	sort          string
+}
+
+// Reserved parameters that are not filters
+var reserved = map[string]bool{
+	"limit":  true,
+	"offset": true,
+	"cursor": true,
+	"sort":   true,
+	"search": true,
+}
+
+// Parse validates query parameters against schema and builds a Spec.
+// Multi-value parameters may be repeated (?tag=a&tag=b), use the
+// bracket form (?tag[]=a) or be comma separated (?tag=a,b).
+func Parse(values url.Values, schema Schema) (Spec, error) {
+	page, err := ParsePage(values)
+	if err != nil {
+		return Spec{}, err
+	}
+	spec := Spec{
+		Page:          page,
+		Search:        strings.TrimSpace(values.Get("search")),
+		searchColumns: schema.Search,
+	}
+
+	if key := values.Get("sort"); key != "" {
+		expr, ok := schema.Sorts[key]
+		if !ok {
+			return Spec{}, fmt.Errorf("%w: unsupported sort %q (allowed: %s)", ErrInvalidQuery, key, strings.Join(sortKeys(schema), ", "))
+		}
+		if page.After != nil {
+			return Spec{}, fmt.Errorf("%w: cursor cannot be combined with sort", ErrInvalidQuery)
+		}
+		spec.sort = expr
+	}
+
+	fields := make(map[string]Field, len(schema.Fields))
+	for _, f := range schema.Fields {
+		fields[f.Param] = f
+	}
+
+	// Merge ?tag=a and ?tag[]=b into a single filter
+	grouped := make(map[string][]string, len(values))
+	for param, vals := range values {
+		name := strings.TrimSuffix(param, "[]")
+		if reserved[name] {
+			continue
+		}
+		grouped[name] = append(grouped[name], vals...)
+	}
+	names := make([]string, 0, len(grouped))
+	for name := range grouped {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		field, ok := fields[name]
+		if !ok {
+			return Spec{}, fmt.Errorf("%w: unknown filter %q", ErrInvalidQuery, name)
+		}
+		filterValues := splitValues(grouped[name])
+		if len(filterValues) == 0 {
+			continue
+		}
+		if err := field.validate(filterValues); err != nil {
+			return Spec{}, err
+		}
+		spec.Filters = append(spec.Filters, Filter{Field: field, Values: filterValues})
+	}
+
+	return spec, nil
+}
+
+// Where applies the filters and search of the spec, for counting
+func (s Spec) Where(db *gorm.DB) *gorm.DB {
+	for _, f := range s.Filters {
+		db = f.apply(db)
+	}
+	if s.Search != "" && len(s.searchColumns) > 0 {
+		pattern := "%" + escapeLike(s.Search) + "%"
+		conds := make([]string, len(s.searchColumns))
+		args := make([]interface{}, len(s.searchColumns))
+		for i, col := range s.searchColumns {
+			conds[i] = col + " ILIKE ?"
+			args[i] = pattern
+		}
+		db = db.Where("("+strings.Join(conds, " OR ")+")", args...)
+	}
+	return db
+}
+
+// Apply applies filters, search, sort order and page window
+func (s Spec) Apply(db *gorm.DB) *gorm.DB {
+	db = s.Where(db)
+	if s.sort == "" {
+		return s.Page.Apply(db)
+	}
+	db = db.Order(s.sort).Order("id DESC")
+	if s.Page.Offset > 0 {
+		db = db.Offset(s.Page.Offset)
+	}
+	if s.Page.Limit > 0 {
+		db = db.Limit(s.Page.Limit)
+	}
+	return db
+}
+
+func (f Filter) apply(db *gorm.DB) *gorm.DB {
+	col := f.Field.Column
+	switch f.Field.Kind {
+	case ArrayOverlaps:
+		return db.Where(col+" && ?::text[]", TextArray(f.Values))
+	case ArrayContains:
+		return db.Where(col+" @> ?::text[]", TextArray(f.Values))
+	case Boolean:
+		b, _ := strconv.ParseBool(f.Values[0])
+		return db.Where(col+" = ?", b)
+	case Bucket:
+		conds := make([]string, len(f.Values))
+		for i, v := range f.Values {
+			conds[i] = f.Field.Buckets[v]
+		}
+		return db.Where("(" + strings.Join(conds, " OR ") + ")")
+	default:
+		if len(f.Values) == 1 {
+			return db.Where(col+" = ?", f.Values[0])
+		}
+		return db.Where(col+" IN ?", f.Values)
+	}
+}
+
+func (f Field) validate(values []string) error {
+	switch f.Kind {
+	case Boolean:
+		if len(values) > 1 {
+			return fmt.Errorf("%w: %s accepts a single value", ErrInvalidQuery, f.Param)
+		}
+		if _, err := strconv.ParseBool(values[0]); err != nil {
+			return fmt.Errorf("%w: %s must be true or false", ErrInvalidQuery, f.Param)
+		}
+		return nil
+	case Bucket:
+		for _, v := range values {
+			if _, ok := f.Buckets[v]; !ok {
+				return fmt.Errorf("%w: unsupported %s %q", ErrInvalidQuery, f.Param, v)
+			}
+		}
+		return nil
+	case ID:
+		for _, v := range values {
+			if !ValidID(v) {
+				return fmt.Errorf("%w: %s %q is not a valid id", ErrInvalidQuery, f.Param, v)
+			}
+		}
+		return nil
+	}
+	if len(f.Values) == 0 {
+		return nil
+	}
+	for _, v := range values {
+		if !slices.Contains(f.Values, v) {
+			return fmt.Errorf("%w: unsupported %s %q (allowed: %s)", ErrInvalidQuery, f.Param, v, strings.Join(f.Values, ", "))
+		}
+	}
+	return nil
+}
+
+// TextArray binds a string slice as a PostgreSQL text[] literal
+type TextArray []string
+
+// Value implements driver.Valuer interface
+func (a TextArray) Value() (driver.Value, error) {
+	quoted := make([]string, len(a))
+	for i, v := range a {
+		v = strings.ReplaceAll(v, `\`, `\\`)
+		v = strings.ReplaceAll(v, `"`, `\"`)
+		quoted[i] = `"` + v + `"`
+	}
+	return "{" + strings.Join(quoted, ",") + "}", nil
+}
+
+// Values returns every value of a multi-value parameter, accepting the
+// repeated, bracketed and comma-separated forms
+func Values(values url.Values, name string) []string {
+	raw := append(append([]string{}, values[name]...), values[name+"[]"]...)
+	return splitValues(raw)
+}
+
+func splitValues(raw []string) []string {
+	var out []string
+	for _, v := range raw {
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
+		}
+	}
+	return out
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func sortKeys(schema Schema) []string {
+	keys := make([]string, 0, len(schema.Sorts))
+	for k := range schema.Sorts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
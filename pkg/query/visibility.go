@@ -0,0 +1,118 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"robohub-inventory/pkg/auth"
+)
+
+// Visibility values of repositories and datasets
+const (
+	VisibilityPublic  = "public"
+	VisibilityPrivate = "private"
+)
+
+// Visibility decides which private rows a caller may read: those owned by
+// the calling user or by an organization they belong to. Platform admins see
+// everything and anonymous callers see public rows only.
+type Visibility struct {
+	unrestricted  bool
+	userID        string
+	orgIDs        []string
+	managedOrgIDs []string // Organizations where the caller is at least a maintainer
+}
+
+// VisibilityFor returns the visibility of the principal in ctx
+func VisibilityFor(ctx context.Context) Visibility {
+	p, ok := auth.FromContext(ctx)
+	if !ok {
+		return Visibility{}
+	}
+	if p.Admin {
+		return Visibility{unrestricted: true}
+	}
+	v := Visibility{userID: p.UserID}
+	for id, role := range p.Orgs {
+		v.orgIDs = append(v.orgIDs, id)
+		if role.AtLeast(auth.RoleMaintainer) {
+			v.managedOrgIDs = append(v.managedOrgIDs, id)
+		}
+	}
+	sort.Strings(v.orgIDs)
+	sort.Strings(v.managedOrgIDs)
+	return v
+}
+
+// Condition returns a predicate matching the rows of table, which must have
+// visibility, owner_type and owner_id columns, that the caller may read. It is
+// empty when nothing is hidden and binds the named parameters from Args.
+func (v Visibility) Condition(table string) string {
+	if v.unrestricted {
+		return ""
+	}
+	conds := []string{fmt.Sprintf("%s.visibility <> '%s'", table, VisibilityPrivate)}
+	if v.userID != "" {
+		conds = append(conds, fmt.Sprintf("(%[1]s.owner_type = '%[2]s' AND %[1]s.owner_id = @viewer_user)", table, auth.OwnerUser))
+	}
+	if len(v.orgIDs) > 0 {
+		conds = append(conds, fmt.Sprintf("(%[1]s.owner_type = '%[2]s' AND %[1]s.owner_id IN @viewer_orgs)", table, auth.OwnerOrganization))
+	}
+	return "(" + strings.Join(conds, " OR ") + ")"
+}
+
+// ManageCondition returns a predicate matching the rows of table that the
+// caller may modify, mirroring auth.CanModify: rows owned by the calling user
+// or by an organization they maintain. It is empty for platform admins and
+// matches nothing for anonymous callers.
+func (v Visibility) ManageCondition(table string) string {
+	if v.unrestricted {
+		return ""
+	}
+	var conds []string
+	if v.userID != "" {
+		conds = append(conds, fmt.Sprintf("(%[1]s.owner_type = '%[2]s' AND %[1]s.owner_id = @viewer_user)", table, auth.OwnerUser))
+	}
+	if len(v.managedOrgIDs) > 0 {
+		conds = append(conds, fmt.Sprintf("(%[1]s.owner_type = '%[2]s' AND %[1]s.owner_id IN @viewer_managed_orgs)", table, auth.OwnerOrganization))
+	}
+	if len(conds) == 0 {
+		return "FALSE"
+	}
+	return "(" + strings.Join(conds, " OR ") + ")"
+}
+
+// RepoCondition returns a predicate hiding rows of table whose repo_id refers
+// to a repository the caller may not read
+func (v Visibility) RepoCondition(table string) string {
+	cond := v.Condition("visible_repo")
+	if cond == "" {
+		return ""
+	}
+	return fmt.Sprintf(
+		"NOT EXISTS (SELECT 1 FROM repositories visible_repo WHERE visible_repo.id = %s.repo_id AND NOT %s)",
+		table, cond)
+}
+
+// Args returns the named parameters used by Condition, ManageCondition and RepoCondition
+func (v Visibility) Args() map[string]interface{} {
+	return map[string]interface{}{
+		"viewer_user":         v.userID,
+		"viewer_orgs":         v.orgIDs,
+		"viewer_managed_orgs": v.managedOrgIDs,
+	}
+}
+
+// Scope returns a GORM scope applying a condition built by v
+func (v Visibility) Scope(cond string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if cond == "" {
+			return db
+		}
+		return db.Where(cond, v.Args())
+	}
+}
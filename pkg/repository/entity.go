@@ -4,13 +4,18 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"time"
+
+	"gorm.io/gorm"
+
+	"robohub-inventory/pkg/auth"
+	"robohub-inventory/pkg/identity"
 )
 
 // Repository represents a code repository in the robotics platform
 // Matches API_CONTRACT.md Repository schema
 type Repository struct {
 	ID          string    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
-	Name        string    `gorm:"uniqueIndex;not null" json:"name"`                                // Format: "org/repo"
+	Name        string    `gorm:"uniqueIndex:idx_repositories_name_active,where:deleted_at IS NULL;not null" json:"name"`                                // Format: "org/repo"
 	Provider    string    `gorm:"not null" json:"provider"`                                        // "github" | "gitlab" | "bitbucket"
 	URL         string    `gorm:"not null" json:"url"`                                             // Full repository URL
 	Description string    `json:"description,omitempty"`
@@ -31,12 +36,18 @@ type Repository struct {
 	
 	// Metadata
 	Tags         []string `gorm:"type:text[]" json:"tags"`
-	PackageCount int      `gorm:"default:0" json:"packageCount"`
-	Owner        Owner    `gorm:"type:jsonb" json:"owner"`
+	PackageCount int      `gorm:"default:0" json:"packageCount"` // Maintained by pkg/counter; read-only
+	OwnerType string          `gorm:"index:idx_repositories_owner" json:"ownerType,omitempty"` // "user" | "organization"
+	OwnerID   string          `gorm:"index:idx_repositories_owner" json:"ownerId,omitempty"`
+	Owner     *identity.Owner `gorm:"-" json:"owner,omitempty"` // Resolved from OwnerType/OwnerID
+	
+	// Concurrency
+	Revision int64 `gorm:"not null;default:1" json:"revision"` // Incremented on every update; exposed as the ETag
 	
 	// Timestamps
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deletedAt,omitempty"` // Set while the repository is in the trash
 }
 
 // LatestCommit represents the latest commit information
@@ -48,13 +59,6 @@ type LatestCommit struct {
 	URL     string    `json:"url"`
 }
 
-// Owner represents the repository owner
-type Owner struct {
-	ID        string `json:"id"`
-	Name      string `json:"name"`
-	AvatarURL string `json:"avatarUrl,omitempty"`
-}
-
 // Scan implements sql.Scanner interface for JSONB
 func (c *LatestCommit) Scan(value interface{}) error {
 	if value == nil {
@@ -72,23 +76,16 @@ func (c LatestCommit) Value() (driver.Value, error) {
 	return json.Marshal(c)
 }
 
-// Scan implements sql.Scanner interface for JSONB
-func (o *Owner) Scan(value interface{}) error {
-	if value == nil {
-		return nil
-	}
-	bytes, ok := value.([]byte)
-	if !ok {
-		return nil
-	}
-	return json.Unmarshal(bytes, o)
+func (Repository) TableName() string {
+	return "repositories"
 }
 
-// Value implements driver.Valuer interface for JSONB
-func (o Owner) Value() (driver.Value, error) {
-	return json.Marshal(o)
+// OwnerRef returns the reference to the repository owner
+func (r *Repository) OwnerRef() auth.OwnerRef {
+	return auth.OwnerRef{Type: r.OwnerType, ID: r.OwnerID}
 }
 
-func (Repository) TableName() string {
-	return "repositories"
+// SetOwner sets the resolved owner details
+func (r *Repository) SetOwner(owner *identity.Owner) {
+	r.Owner = owner
 }
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"robohub-inventory/pkg/event"
+)
+
+// changeEvents returns the events of saving repo over current, or of
+// connecting repo when current is nil. A sync is reported when the sync
+// status becomes "syncing", "synced" or "error", or a new sync completes.
+func changeEvents(current, repo *Repository) ([]event.Event, error) {
+	var types []string
+	switch {
+	case current == nil:
+		types = append(types, event.RepositoryConnected)
+	case repo.SyncStatus == "syncing" && current.SyncStatus != "syncing":
+		types = append(types, event.RepositorySyncStarted)
+	case repo.SyncStatus == "synced" && (current.SyncStatus != "synced" || repo.LastSynced.After(current.LastSynced)):
+		types = append(types, event.RepositorySyncCompleted)
+	case repo.SyncStatus == "error" && current.SyncStatus != "error":
+		types = append(types, event.RepositorySyncFailed)
+	}
+
+	events := make([]event.Event, 0, len(types))
+	for _, typ := range types {
+		e, err := event.New(typ, "repository", repo.ID, repo)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
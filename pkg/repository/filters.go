@@ -0,0 +1,26 @@
+package repository
+
+import "robohub-inventory/pkg/query"
+
+// ListSchema whitelists the filters and sort orders of the repository list (API_CONTRACT.md §1.1)
+var ListSchema = query.Schema{
+	Fields: []query.Field{
+		{Param: "provider", Column: "provider", Kind: query.Equals, Values: []string{"github", "gitlab", "bitbucket"}},
+		{
+			Param:  "status",
+			Column: "sync_status",
+			Kind:   query.Equals,
+			Values: []string{"synced", "syncing", "needs_attention", "error"},
+		},
+		{Param: "autoSync", Column: "auto_sync", Kind: query.Boolean},
+		{Param: "visibility", Column: "visibility", Kind: query.Equals, Values: []string{"public", "private"}},
+		{Param: "tag", Column: "tags", Kind: query.ArrayContains},
+	},
+	Search: []string{"name", "description"},
+	Sorts: map[string]string{
+		"name":     "name ASC",
+		"updated":  "updated_at DESC",
+		"synced":   "last_synced DESC",
+		"packages": "package_count DESC",
+	},
+}
@@ -2,6 +2,9 @@ package repository
 
 import (
 	"context"
+	"time"
+
+	"robohub-inventory/pkg/query"
 )
 
 // Repository defines the interface for repository persistence
@@ -9,7 +12,14 @@ type RepoRepository interface {
 	Create(ctx context.Context, repo *Repository) error
 	GetByID(ctx context.Context, id string) (*Repository, error)
 	GetByName(ctx context.Context, name string) (*Repository, error)
-	List(ctx context.Context, limit, offset int) ([]*Repository, error)
-	Update(ctx context.Context, repo *Repository) error
-	Delete(ctx context.Context, id string) error
+	ListByIDs(ctx context.Context, ids []string) ([]*Repository, error)
+	List(ctx context.Context, spec query.Spec) ([]*Repository, error)
+	Count(ctx context.Context, spec query.Spec) (int64, error)
+	Update(ctx context.Context, repo *Repository) ([]string, error)
+	Delete(ctx context.Context, id string, revision int64) ([]string, error)
+	GetDeleted(ctx context.Context, id string) (*Repository, error)
+	ListDeleted(ctx context.Context, spec query.Spec) ([]*Repository, error)
+	CountDeleted(ctx context.Context, spec query.Spec) (int64, error)
+	Restore(ctx context.Context, repo *Repository) ([]string, error)
+	Purge(ctx context.Context, before time.Time) (int64, error)
 }
@@ -2,7 +2,14 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"time"
+
 	"gorm.io/gorm"
+
+	"robohub-inventory/pkg/counter"
+	"robohub-inventory/pkg/query"
+	"robohub-inventory/pkg/store"
 )
 
 // gormRepository implements the RepoRepository interface using GORM
@@ -15,13 +22,19 @@ func NewRepository(db *gorm.DB) RepoRepository {
 	return &gormRepository{db: db}
 }
 
+// visible starts a query limited to the repositories the caller may read
+func (r *gormRepository) visible(ctx context.Context) *gorm.DB {
+	v := query.VisibilityFor(ctx)
+	return store.Conn(ctx, r.db).Scopes(v.Scope(v.Condition("repositories")))
+}
+
 func (r *gormRepository) Create(ctx context.Context, repo *Repository) error {
-	return r.db.WithContext(ctx).Create(repo).Error
+	return store.Conn(ctx, r.db).Create(repo).Error
 }
 
 func (r *gormRepository) GetByID(ctx context.Context, id string) (*Repository, error) {
 	var repo Repository
-	err := r.db.WithContext(ctx).Where("id = ?", id).First(&repo).Error
+	err := r.visible(ctx).Where("id = ?", id).First(&repo).Error
 	if err != nil {
 		return nil, err
 	}
@@ -30,30 +43,189 @@ func (r *gormRepository) GetByID(ctx context.Context, id string) (*Repository, e
 
 func (r *gormRepository) GetByName(ctx context.Context, name string) (*Repository, error) {
 	var repo Repository
-	err := r.db.WithContext(ctx).Where("name = ?", name).First(&repo).Error
+	err := r.visible(ctx).Where("name = ?", name).First(&repo).Error
 	if err != nil {
 		return nil, err
 	}
 	return &repo, nil
 }
 
-func (r *gormRepository) List(ctx context.Context, limit, offset int) ([]*Repository, error) {
+func (r *gormRepository) ListByIDs(ctx context.Context, ids []string) ([]*Repository, error) {
+	var repositories []*Repository
+	err := r.visible(ctx).Where("id IN ?", ids).Find(&repositories).Error
+	return repositories, err
+}
+
+func (r *gormRepository) List(ctx context.Context, spec query.Spec) ([]*Repository, error) {
 	var repos []*Repository
-	query := r.db.WithContext(ctx)
-	if limit > 0 {
-		query = query.Limit(limit)
+	err := spec.Apply(r.visible(ctx)).Find(&repos).Error
+	return repos, err
+}
+
+func (r *gormRepository) Count(ctx context.Context, spec query.Spec) (int64, error) {
+	var count int64
+	err := spec.Where(r.visible(ctx).Model(&Repository{})).Count(&count).Error
+	return count, err
+}
+
+// Update saves repo if its stored revision still equals repo.Revision and bumps
+// the revision. A new name is copied to the packages of the repository,
+// including those in the trash; the IDs of the live ones are returned.
+func (r *gormRepository) Update(ctx context.Context, repo *Repository) ([]string, error) {
+	revision := repo.Revision
+	var renamed []string
+	err := store.Conn(ctx, r.db).Transaction(func(db *gorm.DB) error {
+		repo.Revision = revision + 1
+		result := db.Model(repo).Where("id = ? AND revision = ?", repo.ID, revision).
+			Select("*").Omit(append([]string{"id", "created_at", "deleted_at"}, counter.RepositoryColumns...)...).Updates(repo)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return store.ErrRevisionMismatch
+		}
+		var packages []struct {
+			ID   string
+			Live bool
+		}
+		err := db.Raw(`
+			UPDATE packages SET repo_name = ?, revision = revision + 1, updated_at = now()
+			WHERE repo_id = ? AND repo_name IS DISTINCT FROM ?
+			RETURNING id, deleted_at IS NULL AS live`, repo.Name, repo.ID, repo.Name).Scan(&packages).Error
+		if err != nil {
+			return err
+		}
+		for _, p := range packages {
+			if p.Live {
+				renamed = append(renamed, p.ID)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		repo.Revision = revision
+		return nil, err
 	}
-	if offset > 0 {
-		query = query.Offset(offset)
+	return renamed, store.Conn(ctx, r.db).Where("id = ?", repo.ID).First(repo).Error
+}
+
+// Delete moves the repository and its packages to the trash, bumping their
+// revisions; a non-zero revision must match the stored one. Both get the
+// transaction timestamp, so that Restore brings back exactly the packages
+// deleted with the repository. It returns the IDs of those packages.
+func (r *gormRepository) Delete(ctx context.Context, id string, revision int64) ([]string, error) {
+	var packages []string
+	err := store.Conn(ctx, r.db).Transaction(func(db *gorm.DB) error {
+		stmt := db.Model(&Repository{}).Where("id = ?", id)
+		if revision != 0 {
+			stmt = stmt.Where("revision = ?", revision)
+		}
+		result := stmt.UpdateColumns(store.Trashed())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			if revision != 0 {
+				return store.ErrRevisionMismatch
+			}
+			return gorm.ErrRecordNotFound
+		}
+		err := db.Raw(`
+			UPDATE packages SET deleted_at = now(), revision = revision + 1
+			WHERE repo_id = ? AND deleted_at IS NULL
+			RETURNING id`, id).Scan(&packages).Error
+		if err != nil {
+			return err
+		}
+		return refreshCounters(db, id)
+	})
+	if err != nil {
+		return nil, err
 	}
-	err := query.Order("created_at DESC").Find(&repos).Error
-	return repos, err
+	return packages, nil
 }
 
-func (r *gormRepository) Update(ctx context.Context, repo *Repository) error {
-	return r.db.WithContext(ctx).Save(repo).Error
+// trash starts a query limited to the deleted repositories the caller may restore
+func (r *gormRepository) trash(ctx context.Context) *gorm.DB {
+	v := query.VisibilityFor(ctx)
+	return store.Conn(ctx, r.db).Unscoped().Where("repositories.deleted_at IS NOT NULL").
+		Scopes(v.Scope(v.ManageCondition("repositories")))
 }
 
-func (r *gormRepository) Delete(ctx context.Context, id string) error {
-	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&Repository{}).Error
+func (r *gormRepository) GetDeleted(ctx context.Context, id string) (*Repository, error) {
+	var repo Repository
+	err := r.visible(ctx).Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&repo).Error
+	if err != nil {
+		return nil, err
+	}
+	return &repo, nil
+}
+
+func (r *gormRepository) ListDeleted(ctx context.Context, spec query.Spec) ([]*Repository, error) {
+	var repositories []*Repository
+	err := spec.Apply(r.trash(ctx)).Find(&repositories).Error
+	return repositories, err
+}
+
+func (r *gormRepository) CountDeleted(ctx context.Context, spec query.Spec) (int64, error) {
+	var count int64
+	err := spec.Where(r.trash(ctx).Model(&Repository{})).Count(&count).Error
+	return count, err
+}
+
+// Restore takes the repository out of the trash together with the packages
+// that were deleted with it, bumping their revisions, and returns the IDs of
+// those packages. It returns ErrPackageNameTaken if a live package has taken
+// the name of one of them.
+func (r *gormRepository) Restore(ctx context.Context, repo *Repository) ([]string, error) {
+	var packages []string
+	err := store.Conn(ctx, r.db).Transaction(func(db *gorm.DB) error {
+		err := db.Raw(`
+			UPDATE packages SET deleted_at = NULL, revision = revision + 1
+			WHERE repo_id = ? AND deleted_at = (SELECT deleted_at FROM repositories WHERE id = ?)
+			RETURNING id`, repo.ID, repo.ID).Scan(&packages).Error
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return ErrPackageNameTaken
+		}
+		if err != nil {
+			return err
+		}
+		result := db.Unscoped().Model(&Repository{}).Where("id = ? AND deleted_at IS NOT NULL", repo.ID).
+			Updates(map[string]interface{}{"deleted_at": nil, "revision": gorm.Expr("revision + 1")})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := refreshCounters(db, repo.ID); err != nil {
+			return err
+		}
+		return db.Where("id = ?", repo.ID).First(repo).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return packages, nil
+}
+
+// Purge permanently deletes the repositories moved to the trash before the given time
+func (r *gormRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	result := store.Conn(ctx, r.db).Unscoped().Where("deleted_at < ?", before).Delete(&Repository{})
+	return result.RowsAffected, result.Error
+}
+
+// refreshCounters recomputes the package count of the repository and the
+// counters of the scenarios last run by its packages
+func refreshCounters(db *gorm.DB, id string) error {
+	var scenarios []string
+	err := db.Table("packages").Where("repo_id = ? AND last_run_scenario_id IS NOT NULL", id).
+		Distinct().Pluck("last_run_scenario_id", &scenarios).Error
+	if err != nil {
+		return err
+	}
+	if err := counter.Repositories(db, id); err != nil {
+		return err
+	}
+	return counter.Scenarios(db, scenarios...)
 }
@@ -0,0 +1,139 @@
+package repository_test
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"robohub-inventory/internal/database/dbtest"
+	"robohub-inventory/pkg/counter"
+	"robohub-inventory/pkg/repository"
+)
+
+func insert(t *testing.T, db *gorm.DB, sql string, args ...any) string {
+	t.Helper()
+	var id string
+	if err := db.Raw(sql+" RETURNING id", args...).Scan(&id).Error; err != nil {
+		t.Fatalf("%s: %v", sql, err)
+	}
+	return id
+}
+
+// counters returns the package count of a repository and the package count
+// of a scenario
+func counters(t *testing.T, db *gorm.DB, repoID, scenarioID string) (repoCount, scenarioCount int64) {
+	t.Helper()
+	err := db.Raw(`SELECT
+		(SELECT package_count FROM repositories WHERE id = ?),
+		(SELECT used_by_packages_count FROM scenarios WHERE id = ?)`, repoID, scenarioID).
+		Row().Scan(&repoCount, &scenarioCount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repoCount, scenarioCount
+}
+
+func assertIDs(t *testing.T, what string, got, want []string) {
+	t.Helper()
+	got, want = append([]string(nil), got...), append([]string(nil), want...)
+	sort.Strings(got)
+	sort.Strings(want)
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("%s = %v, want %v", what, got, want)
+	}
+}
+
+// TestCascades runs the package statements of Update, Delete and Restore,
+// which report the packages they change with RETURNING
+func TestCascades(t *testing.T) {
+	db := dbtest.Migrated(t)
+	ctx := context.Background()
+	repos := repository.NewRepository(db)
+
+	repo := &repository.Repository{Name: "org/robot", Provider: "github", URL: "https://github.com/org/robot"}
+	other := &repository.Repository{Name: "org/other", Provider: "github", URL: "https://github.com/org/other"}
+	for _, r := range []*repository.Repository{repo, other} {
+		if err := repos.Create(ctx, r); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+	scenario := insert(t, db, `INSERT INTO scenarios (name, category, difficulty, maintained_by)
+		VALUES ('Warehouse', 'navigation', 'easy', 'RoboHub')`)
+	const pkgSQL = "INSERT INTO packages (name, repo_id, repo_name, last_run_scenario_id) VALUES (?, ?, ?, ?)"
+	live := []string{
+		insert(t, db, pkgSQL, "planner", repo.ID, repo.Name, scenario),
+		insert(t, db, pkgSQL, "controller", repo.ID, repo.Name, nil),
+	}
+	trashed := insert(t, db, pkgSQL, "retired", repo.ID, repo.Name, nil)
+	unrelated := insert(t, db, pkgSQL, "unrelated", other.ID, other.Name, scenario)
+	if err := db.Exec("UPDATE packages SET deleted_at = now() - interval '1 hour' WHERE id = ?", trashed).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := counter.Repositories(db, repo.ID, other.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := counter.Scenarios(db, scenario); err != nil {
+		t.Fatal(err)
+	}
+	if repoCount, scenarioCount := counters(t, db, repo.ID, scenario); repoCount != 2 || scenarioCount != 2 {
+		t.Fatalf("counters = %d, %d, want 2, 2", repoCount, scenarioCount)
+	}
+
+	t.Run("update", func(t *testing.T) {
+		repo.Name = "org/robot-renamed"
+		renamed, err := repos.Update(ctx, repo)
+		if err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		assertIDs(t, "renamed", renamed, live)
+
+		var names []string
+		db.Raw("SELECT DISTINCT repo_name FROM packages WHERE repo_id = ?", repo.ID).Scan(&names)
+		assertIDs(t, "repo names", names, []string{"org/robot-renamed"})
+
+		again, err := repos.Update(ctx, repo)
+		if err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		assertIDs(t, "renamed without a new name", again, nil)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		deleted, err := repos.Delete(ctx, repo.ID, repo.Revision)
+		if err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		assertIDs(t, "deleted", deleted, live)
+		if repoCount, scenarioCount := counters(t, db, repo.ID, scenario); repoCount != 0 || scenarioCount != 1 {
+			t.Errorf("counters = %d, %d, want 0, 1", repoCount, scenarioCount)
+		}
+	})
+
+	t.Run("restore", func(t *testing.T) {
+		restored := &repository.Repository{ID: repo.ID}
+		ids, err := repos.Restore(ctx, restored)
+		if err != nil {
+			t.Fatalf("Restore: %v", err)
+		}
+		assertIDs(t, "restored", ids, live)
+		if restored.DeletedAt.Valid || restored.Name != "org/robot-renamed" {
+			t.Errorf("restored = %+v", restored)
+		}
+		if repoCount, scenarioCount := counters(t, db, repo.ID, scenario); repoCount != 2 || scenarioCount != 2 {
+			t.Errorf("counters = %d, %d, want 2, 2", repoCount, scenarioCount)
+		}
+
+		var inTrash []string
+		db.Raw("SELECT id FROM packages WHERE deleted_at IS NOT NULL").Scan(&inTrash)
+		assertIDs(t, "packages in the trash", inTrash, []string{trashed})
+	})
+
+	var otherName string
+	db.Raw("SELECT repo_name FROM packages WHERE id = ?", unrelated).Scan(&otherName)
+	if otherName != other.Name {
+		t.Errorf("unrelated package repo name = %q, want %q", otherName, other.Name)
+	}
+}
@@ -3,56 +3,359 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"gorm.io/gorm"
+
+	"robohub-inventory/pkg/auth"
+	"robohub-inventory/pkg/event"
+	"robohub-inventory/pkg/identity"
+	"robohub-inventory/pkg/patch"
+	"robohub-inventory/pkg/query"
+	"robohub-inventory/pkg/store"
 )
 
 var (
-	ErrRepositoryNotFound = errors.New("repository not found")
-	ErrInvalidRepository  = errors.New("invalid repository data")
+	ErrRepositoryNotFound      = errors.New("repository not found")
+	ErrInvalidRepository       = errors.New("invalid repository data")
+	ErrRepositoryAlreadyExists = errors.New("repository already exists")
+	ErrRepositoryNameTaken     = errors.New("repository name is taken by another repository; rename one of them before restoring")
+	ErrPackageNameTaken        = errors.New("a package of the repository has a name taken by another package; rename one of them before restoring")
 )
 
+// PatchableFields are the repository settings a PATCH may change (API_CONTRACT.md §1.4)
+var PatchableFields = []string{"autoSync", "defaultBranch", "tags"}
+
+var tracer = otel.Tracer("robohub-inventory/pkg/repository")
+
+// PackageEvents builds the events of packages changed together with their
+// repository, which publishes them in its own transaction
+type PackageEvents interface {
+	PackageEvents(ctx context.Context, typ string, ids []string) ([]event.Event, error)
+}
+
 // Service handles business logic for repositories
 type Service struct {
-	repo RepoRepository
+	repo     RepoRepository
+	owners   identity.OwnerResolver
+	packages PackageEvents
+	tx       *store.Transactor
+	events   event.Publisher
 }
 
-func NewService(repo RepoRepository) *Service {
-	return &Service{repo: repo}
+func NewService(repo RepoRepository, owners identity.OwnerResolver, packages PackageEvents, tx *store.Transactor, events event.Publisher) *Service {
+	return &Service{repo: repo, owners: owners, packages: packages, tx: tx, events: events}
 }
 
+// CreateRepository stores a new repository owned by the requested owner, defaulting to the caller
 func (s *Service) CreateRepository(ctx context.Context, repo *Repository) error {
-	if repo.Name == "" || repo.URL == "" {
-		return ErrInvalidRepository
+	ctx, span := tracer.Start(ctx, "repository.Service.CreateRepository")
+	defer span.End()
+	if err := validateRepository(repo); err != nil {
+		return err
+	}
+	owner, err := auth.AssignOwner(ctx, repo.OwnerRef())
+	if err != nil {
+		return err
 	}
-	return s.repo.Create(ctx, repo)
+	repo.OwnerType, repo.OwnerID = owner.Type, owner.ID
+	repo.Revision, repo.DeletedAt, repo.PackageCount = 1, gorm.DeletedAt{}, 0
+	return s.save(ctx, nil, repo)
 }
 
 func (s *Service) GetRepository(ctx context.Context, id string) (*Repository, error) {
+	ctx, span := tracer.Start(ctx, "repository.Service.GetRepository")
+	defer span.End()
 	repo, err := s.repo.GetByID(ctx, id)
 	if err != nil {
-		return nil, ErrRepositoryNotFound
+		return nil, translateError(err)
+	}
+	if err := identity.AttachOwners(ctx, s.owners, repo); err != nil {
+		return nil, err
 	}
 	return repo, nil
 }
 
 func (s *Service) GetRepositoryByName(ctx context.Context, name string) (*Repository, error) {
+	ctx, span := tracer.Start(ctx, "repository.Service.GetRepositoryByName")
+	defer span.End()
 	repo, err := s.repo.GetByName(ctx, name)
 	if err != nil {
-		return nil, ErrRepositoryNotFound
+		return nil, translateError(err)
+	}
+	if err := identity.AttachOwners(ctx, s.owners, repo); err != nil {
+		return nil, err
 	}
 	return repo, nil
 }
 
-func (s *Service) ListRepositories(ctx context.Context, limit, offset int) ([]*Repository, error) {
-	return s.repo.List(ctx, limit, offset)
+// GetRepositoriesBatch loads the repositories with the given IDs in one query. It returns them
+// in request order together with the IDs that were not found.
+func (s *Service) GetRepositoriesBatch(ctx context.Context, ids []string) ([]*Repository, []string, error) {
+	ctx, span := tracer.Start(ctx, "repository.Service.GetRepositoriesBatch")
+	defer span.End()
+	valid, err := query.BatchIDs(ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	var repositories []*Repository
+	if len(valid) > 0 {
+		if repositories, err = s.repo.ListByIDs(ctx, valid); err != nil {
+			return nil, nil, err
+		}
+	}
+	found, notFound := query.OrderBatch(ids, repositories, func(x *Repository) string { return x.ID })
+	if err := identity.AttachOwners(ctx, s.owners, found...); err != nil {
+		return nil, nil, err
+	}
+	return found, notFound, nil
 }
 
+// ListRepositories returns one page of repositories matching spec together with the total count
+func (s *Service) ListRepositories(ctx context.Context, spec query.Spec) ([]*Repository, int64, error) {
+	ctx, span := tracer.Start(ctx, "repository.Service.ListRepositories")
+	defer span.End()
+	repositories, err := s.repo.List(ctx, spec)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := s.repo.Count(ctx, spec)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := identity.AttachOwners(ctx, s.owners, repositories...); err != nil {
+		return nil, 0, err
+	}
+	return repositories, total, nil
+}
+
+// UpdateRepository replaces a repository; only its owner or the owning org's maintainers may
+// update it. An empty owner in the update keeps the current one, and a
+// non-zero revision must match the stored revision.
 func (s *Service) UpdateRepository(ctx context.Context, repo *Repository) error {
-	if repo.Name == "" || repo.URL == "" {
-		return ErrInvalidRepository
+	ctx, span := tracer.Start(ctx, "repository.Service.UpdateRepository")
+	defer span.End()
+	if err := validateRepository(repo); err != nil {
+		return err
+	}
+	current, err := s.repo.GetByID(ctx, repo.ID)
+	if err != nil {
+		return translateError(err)
+	}
+	owner, err := auth.Reassign(ctx, current.OwnerRef(), repo.OwnerRef())
+	if err != nil {
+		return err
 	}
-	return s.repo.Update(ctx, repo)
+	if err := store.CheckRevision(repo.Revision, current.Revision); err != nil {
+		return err
+	}
+	repo.OwnerType, repo.OwnerID, repo.Revision = owner.Type, owner.ID, current.Revision
+	return s.save(ctx, current, repo)
+}
+
+// save creates repo, or updates current to repo, and publishes the events of
+// the change in the same transaction. Packages renamed with the repository
+// are reported updated.
+func (s *Service) save(ctx context.Context, current, repo *Repository) error {
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		var renamed []string
+		var err error
+		if current == nil {
+			err = s.repo.Create(ctx, repo)
+		} else {
+			renamed, err = s.repo.Update(ctx, repo)
+		}
+		if err != nil {
+			return translateError(err)
+		}
+		if err := identity.AttachOwners(ctx, s.owners, repo); err != nil {
+			return err
+		}
+		events, err := changeEvents(current, repo)
+		if err != nil {
+			return err
+		}
+		return s.publish(ctx, events, event.PackageUpdated, renamed)
+	})
 }
 
-func (s *Service) DeleteRepository(ctx context.Context, id string) error {
-	return s.repo.Delete(ctx, id)
+// publish publishes events followed by an event of type typ for each of the
+// given packages
+func (s *Service) publish(ctx context.Context, events []event.Event, typ string, packages []string) error {
+	if len(packages) > 0 {
+		cascaded, err := s.packages.PackageEvents(ctx, typ, packages)
+		if err != nil {
+			return err
+		}
+		events = append(events, cascaded...)
+	}
+	return s.events.Publish(ctx, events...)
+}
+
+// UpsertRepository creates the repository or, if one with the same name exists, updates it
+// in place. It reports whether the repository was created.
+func (s *Service) UpsertRepository(ctx context.Context, repo *Repository) (bool, error) {
+	ctx, span := tracer.Start(ctx, "repository.Service.UpsertRepository")
+	defer span.End()
+	existing, err := s.repo.GetByName(ctx, repo.Name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return true, s.CreateRepository(ctx, repo)
+	}
+	if err != nil {
+		return false, err
+	}
+	repo.ID, repo.Revision = existing.ID, 0
+	return false, s.UpdateRepository(ctx, repo)
+}
+
+// PatchRepository applies a merge patch or JSON Patch to the stored repository and saves the
+// result. Only the repository settings of API_CONTRACT.md §1.4 may be patched.
+func (s *Service) PatchRepository(ctx context.Context, id string, revision int64, p *patch.Patch) (*Repository, error) {
+	ctx, span := tracer.Start(ctx, "repository.Service.PatchRepository")
+	defer span.End()
+	if err := p.Restrict(PatchableFields...); err != nil {
+		return nil, err
+	}
+	current, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	if err := store.CheckRevision(revision, current.Revision); err != nil {
+		return nil, err
+	}
+	var repo Repository
+	if err := p.Apply(current, &repo); err != nil {
+		return nil, err
+	}
+	// Saving against the revision the patch was applied to keeps concurrent writes from being lost
+	repo.ID, repo.CreatedAt, repo.Revision = current.ID, current.CreatedAt, current.Revision
+	if err := s.UpdateRepository(ctx, &repo); err != nil {
+		return nil, err
+	}
+	return &repo, nil
+}
+
+// DeleteRepository moves a repository and its packages to the trash; only its owner or the
+// owning org's maintainers may delete it. A non-zero revision must match the stored revision.
+func (s *Service) DeleteRepository(ctx context.Context, id string, revision int64) error {
+	ctx, span := tracer.Start(ctx, "repository.Service.DeleteRepository")
+	defer span.End()
+	current, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return translateError(err)
+	}
+	if err := auth.CanModify(ctx, current.OwnerRef()); err != nil {
+		return err
+	}
+	if err := store.CheckRevision(revision, current.Revision); err != nil {
+		return err
+	}
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		packages, err := s.repo.Delete(ctx, id, current.Revision)
+		if err != nil {
+			return translateError(err)
+		}
+		repo, err := s.repo.GetDeleted(ctx, id)
+		if err != nil {
+			return err
+		}
+		return s.publishTrashEvent(ctx, event.RepositoryDeleted, repo, event.PackageDeleted, packages)
+	})
+}
+
+// publishTrashEvent publishes an event of type typ about repo, which was
+// moved to or out of the trash, and an event of type packageType for each of
+// the packages that went with it
+func (s *Service) publishTrashEvent(ctx context.Context, typ string, repo *Repository, packageType string, packages []string) error {
+	if err := identity.AttachOwners(ctx, s.owners, repo); err != nil {
+		return err
+	}
+	e, err := event.New(typ, "repository", repo.ID, repo)
+	if err != nil {
+		return err
+	}
+	return s.publish(ctx, []event.Event{e}, packageType, packages)
+}
+
+// ListDeletedRepositories returns one page of the deleted repositories the caller may restore, together with the total count
+func (s *Service) ListDeletedRepositories(ctx context.Context, spec query.Spec) ([]*Repository, int64, error) {
+	ctx, span := tracer.Start(ctx, "repository.Service.ListDeletedRepositories")
+	defer span.End()
+	repositories, err := s.repo.ListDeleted(ctx, spec)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := s.repo.CountDeleted(ctx, spec)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := identity.AttachOwners(ctx, s.owners, repositories...); err != nil {
+		return nil, 0, err
+	}
+	return repositories, total, nil
+}
+
+// RestoreRepository takes a repository out of the trash; only its owner or the owning org's maintainers may restore it. The packages deleted
+// with the repository are restored with it.
+func (s *Service) RestoreRepository(ctx context.Context, id string) (*Repository, error) {
+	ctx, span := tracer.Start(ctx, "repository.Service.RestoreRepository")
+	defer span.End()
+	current, err := s.repo.GetDeleted(ctx, id)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	if err := auth.CanModify(ctx, current.OwnerRef()); err != nil {
+		return nil, err
+	}
+	err = s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		packages, err := s.repo.Restore(ctx, current)
+		if err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return ErrRepositoryNameTaken
+			}
+			return translateError(err)
+		}
+		return s.publishTrashEvent(ctx, event.RepositoryRestored, current, event.PackageRestored, packages)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return current, nil
+}
+
+// PurgeDeletedRepositories permanently deletes the repositories moved to the trash before the given time
+func (s *Service) PurgeDeletedRepositories(ctx context.Context, before time.Time) (int64, error) {
+	ctx, span := tracer.Start(ctx, "repository.Service.PurgeDeletedRepositories")
+	defer span.End()
+	return s.repo.Purge(ctx, before)
+}
+
+func validateRepository(repo *Repository) error {
+	if repo.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidRepository)
+	}
+	if repo.URL == "" {
+		return fmt.Errorf("%w: url is required", ErrInvalidRepository)
+	}
+	switch repo.Visibility {
+	case "":
+		repo.Visibility = query.VisibilityPublic
+	case query.VisibilityPublic, query.VisibilityPrivate:
+	default:
+		return fmt.Errorf("%w: visibility must be %q or %q", ErrInvalidRepository, query.VisibilityPublic, query.VisibilityPrivate)
+	}
+	return nil
+}
+
+// translateError maps persistence errors onto the repository service errors
+func translateError(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrRepositoryNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return ErrRepositoryAlreadyExists
+	}
+	return err
 }
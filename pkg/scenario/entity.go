@@ -4,13 +4,18 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"time"
+
+	"gorm.io/gorm"
+
+	"robohub-inventory/pkg/auth"
+	"robohub-inventory/pkg/identity"
 )
 
 // Scenario represents a test scenario in the robotics platform
 // Matches API_CONTRACT.md Scenario schema
 type Scenario struct {
 	ID                  string    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
-	Name                string    `gorm:"uniqueIndex;not null" json:"name"`
+	Name                string    `gorm:"uniqueIndex:idx_scenarios_name_active,where:deleted_at IS NULL;not null" json:"name"`
 	Slug                string    `gorm:"index" json:"slug,omitempty"`                    // URL-friendly identifier
 	Description         string    `json:"description"`
 	DetailedDescription string    `gorm:"type:text" json:"detailedDescription,omitempty"` // Markdown
@@ -31,7 +36,7 @@ type Scenario struct {
 	SupportedSimulators []string `gorm:"type:text[]" json:"supportedSimulators"` // e.g., ["Gazebo", "CARLA", "AirSim"]
 	
 	// Related Data
-	RecommendedDatasets []string        `gorm:"type:text[]" json:"recommendedDatasets"` // Dataset IDs
+	RecommendedDatasets []string        `gorm:"-" json:"recommendedDatasets"` // Dataset IDs, stored in scenario_recommended_datasets
 	RequiredInputs      RequiredInputs  `gorm:"type:jsonb" json:"requiredInputs"`
 	
 	// Metrics
@@ -41,18 +46,24 @@ type Scenario struct {
 	// Statistics
 	WeeklyRunCount      int     `gorm:"default:0" json:"weeklyRunCount"`
 	MonthlyRunCount     int     `gorm:"default:0" json:"monthlyRunCount"`
-	UsedByPackagesCount int     `gorm:"default:0" json:"usedByPackagesCount"`
+	UsedByPackagesCount int     `gorm:"default:0" json:"usedByPackagesCount"` // Maintained by pkg/counter; read-only
 	UsedByStacksCount   int     `gorm:"default:0" json:"usedByStacksCount"`
 	AveragePassRate     float64 `gorm:"default:0" json:"averagePassRate"` // 0-100 percentage
 	
 	// Metadata
 	Tags    []string `gorm:"type:text[]" json:"tags"`
-	Owner   Owner    `gorm:"type:jsonb" json:"owner"`
+	OwnerType string          `gorm:"index:idx_scenarios_owner" json:"ownerType,omitempty"` // "user" | "organization"
+	OwnerID   string          `gorm:"index:idx_scenarios_owner" json:"ownerId,omitempty"`
+	Owner     *identity.Owner `gorm:"-" json:"owner,omitempty"` // Resolved from OwnerType/OwnerID
 	Version string   `json:"version"`
 	
+	// Concurrency
+	Revision int64 `gorm:"not null;default:1" json:"revision"` // Incremented on every update; exposed as the ETag
+	
 	// Timestamps
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deletedAt,omitempty"` // Set while the scenario is in the trash
 }
 
 // RequiredInput represents a required input for the scenario
@@ -118,30 +129,27 @@ func (s SuccessCriteria) Value() (driver.Value, error) {
 	return json.Marshal(s)
 }
 
-// Owner represents the scenario owner
-type Owner struct {
-	ID        string `json:"id"`
-	Name      string `json:"name"`
-	AvatarURL string `json:"avatarUrl,omitempty"`
+func (Scenario) TableName() string {
+	return "scenarios"
 }
 
-// Scan implements sql.Scanner interface for JSONB
-func (o *Owner) Scan(value interface{}) error {
-	if value == nil {
-		return nil
-	}
-	bytes, ok := value.([]byte)
-	if !ok {
-		return nil
-	}
-	return json.Unmarshal(bytes, o)
+// RecommendedDataset links a scenario to a dataset it recommends
+type RecommendedDataset struct {
+	ScenarioID string `gorm:"type:uuid;primaryKey"`
+	DatasetID  string `gorm:"type:uuid;primaryKey;index"`
+	Position   int    `gorm:"not null"` // Order of the dataset in RecommendedDatasets
 }
 
-// Value implements driver.Valuer interface for JSONB
-func (o Owner) Value() (driver.Value, error) {
-	return json.Marshal(o)
+func (RecommendedDataset) TableName() string {
+	return "scenario_recommended_datasets"
 }
 
-func (Scenario) TableName() string {
-	return "scenarios"
+// OwnerRef returns the reference to the scenario owner
+func (s *Scenario) OwnerRef() auth.OwnerRef {
+	return auth.OwnerRef{Type: s.OwnerType, ID: s.OwnerID}
+}
+
+// SetOwner sets the resolved owner details
+func (s *Scenario) SetOwner(owner *identity.Owner) {
+	s.Owner = owner
 }
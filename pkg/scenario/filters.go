@@ -0,0 +1,28 @@
+package scenario
+
+import "robohub-inventory/pkg/query"
+
+// ListSchema whitelists the filters and sort orders of the scenario list (API_CONTRACT.md §3.1)
+var ListSchema = query.Schema{
+	Fields: []query.Field{
+		{
+			Param:  "category",
+			Column: "category",
+			Kind:   query.Equals,
+			Values: []string{"navigation", "perception", "localization", "planning"},
+		},
+		{Param: "difficulty", Column: "difficulty", Kind: query.Equals, Values: []string{"easy", "medium", "hard"}},
+		{Param: "maintainedBy", Column: "maintained_by", Kind: query.Equals, Values: []string{"RoboHub", "Community", "Partner"}},
+		{Param: "verified", Column: "verified", Kind: query.Boolean},
+		{Param: "simulator", Column: "supported_simulators", Kind: query.ArrayContains},
+		{Param: "domain", Column: "domain", Kind: query.Equals},
+		{Param: "tag", Column: "tags", Kind: query.ArrayContains},
+	},
+	Search: []string{"name", "description"},
+	Sorts: map[string]string{
+		"newest":  "created_at DESC",
+		"popular": "monthly_run_count DESC, weekly_run_count DESC",
+		"updated": "updated_at DESC",
+		"name":    "name ASC",
+	},
+}
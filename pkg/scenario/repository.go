@@ -1,13 +1,26 @@
 package scenario
 
-import "context"
+import (
+	"context"
+	"time"
+
+	"robohub-inventory/pkg/query"
+)
 
 // Repository defines the interface for scenario persistence
 type Repository interface {
 	Create(ctx context.Context, scenario *Scenario) error
 	GetByID(ctx context.Context, id string) (*Scenario, error)
 	GetByName(ctx context.Context, name string) (*Scenario, error)
-	List(ctx context.Context, limit, offset int) ([]*Scenario, error)
+	ListByIDs(ctx context.Context, ids []string) ([]*Scenario, error)
+	List(ctx context.Context, spec query.Spec) ([]*Scenario, error)
+	Count(ctx context.Context, spec query.Spec) (int64, error)
 	Update(ctx context.Context, scenario *Scenario) error
-	Delete(ctx context.Context, id string) error
+	Delete(ctx context.Context, id string, revision int64) error
+	GetDeleted(ctx context.Context, id string) (*Scenario, error)
+	ListDeleted(ctx context.Context, spec query.Spec) ([]*Scenario, error)
+	CountDeleted(ctx context.Context, spec query.Spec) (int64, error)
+	Restore(ctx context.Context, scenario *Scenario) error
+	Purge(ctx context.Context, before time.Time) (int64, error)
+	MissingDatasets(ctx context.Context, ids []string) ([]string, error)
 }
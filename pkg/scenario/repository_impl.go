@@ -2,7 +2,13 @@ package scenario
 
 import (
 	"context"
+	"time"
+
 	"gorm.io/gorm"
+
+	"robohub-inventory/pkg/counter"
+	"robohub-inventory/pkg/query"
+	"robohub-inventory/pkg/store"
 )
 
 // gormRepository implements the Repository interface using GORM
@@ -16,44 +22,221 @@ func NewRepository(db *gorm.DB) Repository {
 }
 
 func (r *gormRepository) Create(ctx context.Context, scenario *Scenario) error {
-	return r.db.WithContext(ctx).Create(scenario).Error
+	return store.Conn(ctx, r.db).Transaction(func(db *gorm.DB) error {
+		if err := db.Create(scenario).Error; err != nil {
+			return err
+		}
+		return replaceDatasets(ctx, db, scenario)
+	})
 }
 
 func (r *gormRepository) GetByID(ctx context.Context, id string) (*Scenario, error) {
 	var scenario Scenario
-	err := r.db.WithContext(ctx).Where("id = ?", id).First(&scenario).Error
+	err := store.Conn(ctx, r.db).Where("id = ?", id).First(&scenario).Error
 	if err != nil {
 		return nil, err
 	}
-	return &scenario, nil
+	return &scenario, LoadDatasets(ctx, r.db, &scenario)
 }
 
 func (r *gormRepository) GetByName(ctx context.Context, name string) (*Scenario, error) {
 	var scenario Scenario
-	err := r.db.WithContext(ctx).Where("name = ?", name).First(&scenario).Error
+	err := store.Conn(ctx, r.db).Where("name = ?", name).First(&scenario).Error
 	if err != nil {
 		return nil, err
 	}
-	return &scenario, nil
+	return &scenario, LoadDatasets(ctx, r.db, &scenario)
 }
 
-func (r *gormRepository) List(ctx context.Context, limit, offset int) ([]*Scenario, error) {
+func (r *gormRepository) ListByIDs(ctx context.Context, ids []string) ([]*Scenario, error) {
 	var scenarios []*Scenario
-	query := r.db.WithContext(ctx)
-	if limit > 0 {
-		query = query.Limit(limit)
+	if err := store.Conn(ctx, r.db).Where("id IN ?", ids).Find(&scenarios).Error; err != nil {
+		return nil, err
 	}
-	if offset > 0 {
-		query = query.Offset(offset)
+	return scenarios, LoadDatasets(ctx, r.db, scenarios...)
+}
+
+func (r *gormRepository) List(ctx context.Context, spec query.Spec) ([]*Scenario, error) {
+	var scenarios []*Scenario
+	if err := spec.Apply(store.Conn(ctx, r.db)).Find(&scenarios).Error; err != nil {
+		return nil, err
 	}
-	err := query.Order("created_at DESC").Find(&scenarios).Error
-	return scenarios, err
+	return scenarios, LoadDatasets(ctx, r.db, scenarios...)
 }
 
+func (r *gormRepository) Count(ctx context.Context, spec query.Spec) (int64, error) {
+	var count int64
+	err := spec.Where(store.Conn(ctx, r.db).Model(&Scenario{})).Count(&count).Error
+	return count, err
+}
+
+// Update saves scenario if its stored revision still equals scenario.Revision and bumps the revision
 func (r *gormRepository) Update(ctx context.Context, scenario *Scenario) error {
-	return r.db.WithContext(ctx).Save(scenario).Error
+	revision := scenario.Revision
+	err := store.Conn(ctx, r.db).Transaction(func(db *gorm.DB) error {
+		scenario.Revision = revision + 1
+		result := db.Model(scenario).Where("id = ? AND revision = ?", scenario.ID, revision).
+			Select("*").Omit(append([]string{"id", "created_at", "deleted_at"}, counter.ScenarioColumns...)...).Updates(scenario)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return store.ErrRevisionMismatch
+		}
+		if err := replaceDatasets(ctx, db, scenario); err != nil {
+			return err
+		}
+		return counter.PackagesOfScenarios(db, scenario.ID)
+	})
+	if err != nil {
+		scenario.Revision = revision
+		return err
+	}
+	if err := store.Conn(ctx, r.db).Where("id = ?", scenario.ID).First(scenario).Error; err != nil {
+		return err
+	}
+	return LoadDatasets(ctx, r.db, scenario)
+}
+
+// Delete moves the scenario to the trash, bumping its revision; a non-zero
+// revision must match the stored one
+func (r *gormRepository) Delete(ctx context.Context, id string, revision int64) error {
+	return store.Conn(ctx, r.db).Transaction(func(db *gorm.DB) error {
+		stmt := db.Where("id = ?", id)
+		if revision != 0 {
+			stmt = stmt.Where("revision = ?", revision)
+		}
+		result := stmt.Model(&Scenario{}).UpdateColumns(store.Trashed())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			if revision != 0 {
+				return store.ErrRevisionMismatch
+			}
+			return gorm.ErrRecordNotFound
+		}
+		return counter.PackagesOfScenarios(db, id)
+	})
+}
+
+// trash starts a query limited to the deleted scenarios the caller may restore
+func (r *gormRepository) trash(ctx context.Context) *gorm.DB {
+	v := query.VisibilityFor(ctx)
+	return store.Conn(ctx, r.db).Unscoped().Where("scenarios.deleted_at IS NOT NULL").
+		Scopes(v.Scope(v.ManageCondition("scenarios")))
 }
 
-func (r *gormRepository) Delete(ctx context.Context, id string) error {
-	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&Scenario{}).Error
+func (r *gormRepository) GetDeleted(ctx context.Context, id string) (*Scenario, error) {
+	var scenario Scenario
+	err := store.Conn(ctx, r.db).Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&scenario).Error
+	if err != nil {
+		return nil, err
+	}
+	return &scenario, LoadDatasets(ctx, r.db, &scenario)
+}
+
+func (r *gormRepository) ListDeleted(ctx context.Context, spec query.Spec) ([]*Scenario, error) {
+	var scenarios []*Scenario
+	if err := spec.Apply(r.trash(ctx)).Find(&scenarios).Error; err != nil {
+		return nil, err
+	}
+	return scenarios, LoadDatasets(ctx, r.db, scenarios...)
+}
+
+func (r *gormRepository) CountDeleted(ctx context.Context, spec query.Spec) (int64, error) {
+	var count int64
+	err := spec.Where(r.trash(ctx).Model(&Scenario{})).Count(&count).Error
+	return count, err
+}
+
+// Restore takes the scenario out of the trash, bumping its revision.
+func (r *gormRepository) Restore(ctx context.Context, scenario *Scenario) error {
+	err := store.Conn(ctx, r.db).Transaction(func(db *gorm.DB) error {
+		result := db.Unscoped().Model(&Scenario{}).Where("id = ? AND deleted_at IS NOT NULL", scenario.ID).
+			Updates(map[string]interface{}{"deleted_at": nil, "revision": gorm.Expr("revision + 1")})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return counter.PackagesOfScenarios(db, scenario.ID)
+	})
+	if err != nil {
+		return err
+	}
+	if err := store.Conn(ctx, r.db).Where("id = ?", scenario.ID).First(scenario).Error; err != nil {
+		return err
+	}
+	return LoadDatasets(ctx, r.db, scenario)
+}
+
+// Purge permanently deletes the scenarios moved to the trash before the given time
+func (r *gormRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	result := store.Conn(ctx, r.db).Unscoped().Where("deleted_at < ?", before).Delete(&Scenario{})
+	return result.RowsAffected, result.Error
+}
+
+// MissingDatasets returns the ids that match no live dataset the caller may read
+func (r *gormRepository) MissingDatasets(ctx context.Context, ids []string) ([]string, error) {
+	v := query.VisibilityFor(ctx)
+	db := store.Conn(ctx, r.db).Table("datasets").Where("deleted_at IS NULL").Scopes(v.Scope(v.Condition("datasets")))
+	return store.MissingIDs(db, ids)
+}
+
+// LoadDatasets fills in the recommended datasets of scenarios with a single
+// query, skipping datasets in the trash or hidden from the caller
+func LoadDatasets(ctx context.Context, db *gorm.DB, scenarios ...*Scenario) error {
+	byID := make(map[string]*Scenario, len(scenarios))
+	for _, scenario := range scenarios {
+		scenario.RecommendedDatasets = []string{}
+		byID[scenario.ID] = scenario
+	}
+	if len(byID) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(byID))
+	for id := range byID {
+		ids = append(ids, id)
+	}
+
+	v := query.VisibilityFor(ctx)
+	var links []RecommendedDataset
+	err := store.Conn(ctx, db).
+		Joins("JOIN datasets ON datasets.id = scenario_recommended_datasets.dataset_id").
+		Where("scenario_recommended_datasets.scenario_id IN ? AND datasets.deleted_at IS NULL", ids).
+		Scopes(v.Scope(v.Condition("datasets"))).
+		Order("scenario_recommended_datasets.position").
+		Find(&links).Error
+	if err != nil {
+		return err
+	}
+	for _, link := range links {
+		scenario := byID[link.ScenarioID]
+		scenario.RecommendedDatasets = append(scenario.RecommendedDatasets, link.DatasetID)
+	}
+	return nil
+}
+
+// replaceDatasets stores the recommended datasets of scenario in the given order. Links to datasets
+// in the trash or hidden from the caller are kept, as the caller could not
+// have listed them.
+func replaceDatasets(ctx context.Context, db *gorm.DB, scenario *Scenario) error {
+	v := query.VisibilityFor(ctx)
+	replaceable := db.Session(&gorm.Session{NewDB: true}).Table("datasets").Select("id").
+		Where("deleted_at IS NULL").Scopes(v.Scope(v.Condition("datasets")))
+	if err := db.Where("scenario_id = ? AND dataset_id IN (?)", scenario.ID, replaceable).Delete(&RecommendedDataset{}).Error; err != nil {
+		return err
+	}
+
+	scenario.RecommendedDatasets = store.DistinctIDs(scenario.RecommendedDatasets)
+	if len(scenario.RecommendedDatasets) == 0 {
+		return nil
+	}
+	links := make([]RecommendedDataset, len(scenario.RecommendedDatasets))
+	for i, id := range scenario.RecommendedDatasets {
+		links[i] = RecommendedDataset{ScenarioID: scenario.ID, DatasetID: id, Position: i}
+	}
+	return db.Create(&links).Error
 }
@@ -3,56 +3,316 @@ package scenario
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"gorm.io/gorm"
+
+	"robohub-inventory/pkg/auth"
+	"robohub-inventory/pkg/event"
+	"robohub-inventory/pkg/identity"
+	"robohub-inventory/pkg/patch"
+	"robohub-inventory/pkg/query"
+	"robohub-inventory/pkg/store"
 )
 
 var (
-	ErrScenarioNotFound = errors.New("scenario not found")
-	ErrInvalidScenario  = errors.New("invalid scenario data")
+	ErrScenarioNotFound      = errors.New("scenario not found")
+	ErrInvalidScenario       = errors.New("invalid scenario data")
+	ErrScenarioAlreadyExists = errors.New("scenario already exists")
+	ErrScenarioNameTaken     = errors.New("scenario name is taken by another scenario; rename one of them before restoring")
 )
 
+var tracer = otel.Tracer("robohub-inventory/pkg/scenario")
+
 // Service handles business logic for scenarios
 type Service struct {
-	repo Repository
+	repo   Repository
+	owners identity.OwnerResolver
+	tx     *store.Transactor
+	events event.Publisher
 }
 
-func NewService(repo Repository) *Service {
-	return &Service{repo: repo}
+func NewService(repo Repository, owners identity.OwnerResolver, tx *store.Transactor, events event.Publisher) *Service {
+	return &Service{repo: repo, owners: owners, tx: tx, events: events}
 }
 
+// CreateScenario stores a new scenario owned by the requested owner, defaulting to the caller
 func (s *Service) CreateScenario(ctx context.Context, scenario *Scenario) error {
-	if scenario.Name == "" {
-		return ErrInvalidScenario
+	ctx, span := tracer.Start(ctx, "scenario.Service.CreateScenario")
+	defer span.End()
+	if err := validateScenario(scenario); err != nil {
+		return err
+	}
+	owner, err := auth.AssignOwner(ctx, scenario.OwnerRef())
+	if err != nil {
+		return err
+	}
+	scenario.OwnerType, scenario.OwnerID = owner.Type, owner.ID
+	if err := s.checkReferences(ctx, scenario); err != nil {
+		return err
+	}
+	scenario.Revision, scenario.DeletedAt, scenario.UsedByPackagesCount = 1, gorm.DeletedAt{}, 0
+	if err := s.repo.Create(ctx, scenario); err != nil {
+		return translateError(err)
 	}
-	return s.repo.Create(ctx, scenario)
+	return identity.AttachOwners(ctx, s.owners, scenario)
 }
 
 func (s *Service) GetScenario(ctx context.Context, id string) (*Scenario, error) {
+	ctx, span := tracer.Start(ctx, "scenario.Service.GetScenario")
+	defer span.End()
 	scenario, err := s.repo.GetByID(ctx, id)
 	if err != nil {
-		return nil, ErrScenarioNotFound
+		return nil, translateError(err)
+	}
+	if err := identity.AttachOwners(ctx, s.owners, scenario); err != nil {
+		return nil, err
 	}
 	return scenario, nil
 }
 
 func (s *Service) GetScenarioByName(ctx context.Context, name string) (*Scenario, error) {
+	ctx, span := tracer.Start(ctx, "scenario.Service.GetScenarioByName")
+	defer span.End()
 	scenario, err := s.repo.GetByName(ctx, name)
 	if err != nil {
-		return nil, ErrScenarioNotFound
+		return nil, translateError(err)
+	}
+	if err := identity.AttachOwners(ctx, s.owners, scenario); err != nil {
+		return nil, err
 	}
 	return scenario, nil
 }
 
-func (s *Service) ListScenarios(ctx context.Context, limit, offset int) ([]*Scenario, error) {
-	return s.repo.List(ctx, limit, offset)
+// GetScenariosBatch loads the scenarios with the given IDs in one query. It returns them
+// in request order together with the IDs that were not found.
+func (s *Service) GetScenariosBatch(ctx context.Context, ids []string) ([]*Scenario, []string, error) {
+	ctx, span := tracer.Start(ctx, "scenario.Service.GetScenariosBatch")
+	defer span.End()
+	valid, err := query.BatchIDs(ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	var scenarios []*Scenario
+	if len(valid) > 0 {
+		if scenarios, err = s.repo.ListByIDs(ctx, valid); err != nil {
+			return nil, nil, err
+		}
+	}
+	found, notFound := query.OrderBatch(ids, scenarios, func(x *Scenario) string { return x.ID })
+	if err := identity.AttachOwners(ctx, s.owners, found...); err != nil {
+		return nil, nil, err
+	}
+	return found, notFound, nil
+}
+
+// ListScenarios returns one page of scenarios matching spec together with the total count
+func (s *Service) ListScenarios(ctx context.Context, spec query.Spec) ([]*Scenario, int64, error) {
+	ctx, span := tracer.Start(ctx, "scenario.Service.ListScenarios")
+	defer span.End()
+	scenarios, err := s.repo.List(ctx, spec)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := s.repo.Count(ctx, spec)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := identity.AttachOwners(ctx, s.owners, scenarios...); err != nil {
+		return nil, 0, err
+	}
+	return scenarios, total, nil
 }
 
+// UpdateScenario replaces a scenario; only its owner or the owning org's maintainers may
+// update it. An empty owner in the update keeps the current one, and a
+// non-zero revision must match the stored revision.
 func (s *Service) UpdateScenario(ctx context.Context, scenario *Scenario) error {
+	ctx, span := tracer.Start(ctx, "scenario.Service.UpdateScenario")
+	defer span.End()
+	if err := validateScenario(scenario); err != nil {
+		return err
+	}
+	current, err := s.repo.GetByID(ctx, scenario.ID)
+	if err != nil {
+		return translateError(err)
+	}
+	owner, err := auth.Reassign(ctx, current.OwnerRef(), scenario.OwnerRef())
+	if err != nil {
+		return err
+	}
+	if err := store.CheckRevision(scenario.Revision, current.Revision); err != nil {
+		return err
+	}
+	if err := s.checkReferences(ctx, scenario); err != nil {
+		return err
+	}
+	scenario.OwnerType, scenario.OwnerID, scenario.Revision = owner.Type, owner.ID, current.Revision
+	if err := s.repo.Update(ctx, scenario); err != nil {
+		return translateError(err)
+	}
+	return identity.AttachOwners(ctx, s.owners, scenario)
+}
+
+// UpsertScenario creates the scenario or, if one with the same name exists, updates it
+// in place. It reports whether the scenario was created.
+func (s *Service) UpsertScenario(ctx context.Context, scenario *Scenario) (bool, error) {
+	ctx, span := tracer.Start(ctx, "scenario.Service.UpsertScenario")
+	defer span.End()
+	existing, err := s.repo.GetByName(ctx, scenario.Name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return true, s.CreateScenario(ctx, scenario)
+	}
+	if err != nil {
+		return false, err
+	}
+	scenario.ID, scenario.Revision = existing.ID, 0
+	return false, s.UpdateScenario(ctx, scenario)
+}
+
+// PatchScenario applies a merge patch or JSON Patch to the stored scenario and saves the result,
+// validating the merged scenario as a whole.
+func (s *Service) PatchScenario(ctx context.Context, id string, revision int64, p *patch.Patch) (*Scenario, error) {
+	ctx, span := tracer.Start(ctx, "scenario.Service.PatchScenario")
+	defer span.End()
+	current, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	if err := store.CheckRevision(revision, current.Revision); err != nil {
+		return nil, err
+	}
+	var scenario Scenario
+	if err := p.Apply(current, &scenario); err != nil {
+		return nil, err
+	}
+	// Saving against the revision the patch was applied to keeps concurrent writes from being lost
+	scenario.ID, scenario.CreatedAt, scenario.Revision = current.ID, current.CreatedAt, current.Revision
+	if err := s.UpdateScenario(ctx, &scenario); err != nil {
+		return nil, err
+	}
+	return &scenario, nil
+}
+
+// DeleteScenario moves a scenario to the trash; only its owner or the owning org's
+// maintainers may delete it. A non-zero revision must match the stored revision.
+func (s *Service) DeleteScenario(ctx context.Context, id string, revision int64) error {
+	ctx, span := tracer.Start(ctx, "scenario.Service.DeleteScenario")
+	defer span.End()
+	current, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return translateError(err)
+	}
+	if err := auth.CanModify(ctx, current.OwnerRef()); err != nil {
+		return err
+	}
+	if err := store.CheckRevision(revision, current.Revision); err != nil {
+		return err
+	}
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.repo.Delete(ctx, id, current.Revision); err != nil {
+			return translateError(err)
+		}
+		scenario, err := s.repo.GetDeleted(ctx, id)
+		if err != nil {
+			return err
+		}
+		return s.publishTrashEvent(ctx, event.ScenarioDeleted, scenario)
+	})
+}
+
+// ListDeletedScenarios returns one page of the deleted scenarios the caller may restore, together with the total count
+func (s *Service) ListDeletedScenarios(ctx context.Context, spec query.Spec) ([]*Scenario, int64, error) {
+	ctx, span := tracer.Start(ctx, "scenario.Service.ListDeletedScenarios")
+	defer span.End()
+	scenarios, err := s.repo.ListDeleted(ctx, spec)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := s.repo.CountDeleted(ctx, spec)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := identity.AttachOwners(ctx, s.owners, scenarios...); err != nil {
+		return nil, 0, err
+	}
+	return scenarios, total, nil
+}
+
+// RestoreScenario takes a scenario out of the trash; only its owner or the owning org's maintainers may restore it.
+func (s *Service) RestoreScenario(ctx context.Context, id string) (*Scenario, error) {
+	ctx, span := tracer.Start(ctx, "scenario.Service.RestoreScenario")
+	defer span.End()
+	current, err := s.repo.GetDeleted(ctx, id)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	if err := auth.CanModify(ctx, current.OwnerRef()); err != nil {
+		return nil, err
+	}
+	err = s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.repo.Restore(ctx, current); err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return ErrScenarioNameTaken
+			}
+			return translateError(err)
+		}
+		return s.publishTrashEvent(ctx, event.ScenarioRestored, current)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return current, nil
+}
+
+// publishTrashEvent publishes an event of type typ about scenario, which was
+// moved to or out of the trash
+func (s *Service) publishTrashEvent(ctx context.Context, typ string, scenario *Scenario) error {
+	if err := identity.AttachOwners(ctx, s.owners, scenario); err != nil {
+		return err
+	}
+	e, err := event.New(typ, "scenario", scenario.ID, scenario)
+	if err != nil {
+		return err
+	}
+	return s.events.Publish(ctx, e)
+}
+
+// PurgeDeletedScenarios permanently deletes the scenarios moved to the trash before the given time
+func (s *Service) PurgeDeletedScenarios(ctx context.Context, before time.Time) (int64, error) {
+	ctx, span := tracer.Start(ctx, "scenario.Service.PurgeDeletedScenarios")
+	defer span.End()
+	return s.repo.Purge(ctx, before)
+}
+
+// checkReferences rejects recommendedDatasets entries that do not match a dataset
+func (s *Service) checkReferences(ctx context.Context, scenario *Scenario) error {
+	missing, err := s.repo.MissingDatasets(ctx, scenario.RecommendedDatasets)
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: recommendedDatasets %q do not match a dataset", store.ErrInvalidReference, missing)
+	}
+	return nil
+}
+
+func validateScenario(scenario *Scenario) error {
 	if scenario.Name == "" {
-		return ErrInvalidScenario
+		return fmt.Errorf("%w: name is required", ErrInvalidScenario)
 	}
-	return s.repo.Update(ctx, scenario)
+	return nil
 }
 
-func (s *Service) DeleteScenario(ctx context.Context, id string) error {
-	return s.repo.Delete(ctx, id)
+// translateError maps persistence errors onto the scenario service errors
+func translateError(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrScenarioNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return ErrScenarioAlreadyExists
+	}
+	return err
 }
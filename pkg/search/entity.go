@@ -0,0 +1,73 @@
+package search
+
+import (
+	pkg "robohub-inventory/pkg/package"
+)
+
+// Type is a searchable entity type
+type Type string
+
+const (
+	TypePackage    Type = "package"
+	TypeScenario   Type = "scenario"
+	TypeDataset    Type = "dataset"
+	TypeRepository Type = "repository"
+)
+
+// AllTypes lists every searchable entity type
+var AllTypes = []Type{TypePackage, TypeScenario, TypeDataset, TypeRepository}
+
+// Query is a global search request (API_CONTRACT.md §5.1)
+type Query struct {
+	Text   string
+	Types  []Type // Empty searches every type
+	Limit  int
+	Offset int
+}
+
+// Result is a single ranked search hit
+type Result struct {
+	Type      Type        `json:"type"`
+	Data      interface{} `json:"data"`
+	Rank      float64     `json:"rank"`
+	Highlight string      `json:"highlight,omitempty"` // Description excerpt with <mark> tags
+}
+
+// Facets holds the number of matches per entity type
+type Facets struct {
+	Packages     int64 `json:"packages"`
+	Scenarios    int64 `json:"scenarios"`
+	Datasets     int64 `json:"datasets"`
+	Repositories int64 `json:"repositories"`
+}
+
+// Results is the response of a global search
+type Results struct {
+	Results []Result `json:"results"`
+	Total   int64    `json:"total"`
+	Facets  Facets   `json:"facets"`
+}
+
+// PackageQuery is a package search request (API_CONTRACT.md §2.5)
+type PackageQuery struct {
+	Text              string
+	Types             []string
+	MinValidationRate *float64 // Minimum validationStatus.passRate, 0-100
+	HasDocumentation  *bool
+	Limit             int
+	Offset            int
+}
+
+// PackageResults is the response of a package search
+type PackageResults struct {
+	Results    []*pkg.Package    `json:"results"`
+	Total      int64             `json:"total"`
+	Highlights map[string]string `json:"highlights,omitempty"` // Package ID -> description excerpt
+}
+
+// Hit identifies a ranked match before the entity is loaded
+type Hit struct {
+	Type Type
+	ID   string
+	Rank float64
+}
@@ -0,0 +1,17 @@
+package search
+
+import "context"
+
+// Repository defines the interface for full-text search queries
+type Repository interface {
+	// Hits returns one page of ranked matches across the given types
+	Hits(ctx context.Context, text string, types []Type, limit, offset int) ([]Hit, error)
+	// Facets counts matches of every type
+	Facets(ctx context.Context, text string) (Facets, error)
+	// Load fetches the entities of one type, keyed by ID
+	Load(ctx context.Context, t Type, ids []string) (map[string]interface{}, error)
+	// Highlights returns description excerpts with matches marked, keyed by ID
+	Highlights(ctx context.Context, t Type, text string, ids []string) (map[string]string, error)
+	// SearchPackages runs a filtered package search
+	SearchPackages(ctx context.Context, q PackageQuery) ([]Hit, int64, error)
+}
@@ -0,0 +1,212 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"robohub-inventory/pkg/dataset"
+	pkg "robohub-inventory/pkg/package"
+	"robohub-inventory/pkg/query"
+	"robohub-inventory/pkg/repository"
+	"robohub-inventory/pkg/scenario"
+	"robohub-inventory/pkg/store"
+)
+
+// tsQuery parses the search text with web-search syntax (quotes, OR, -exclusion)
+const tsQuery = "websearch_to_tsquery('english', @text)"
+
+// ts_headline marks matches with control characters, which are removed from
+// the description beforehand, so that the excerpt can be HTML-escaped before
+// the marks become <mark> tags
+const (
+	startSel = "\x02"
+	stopSel  = "\x03"
+)
+
+// headlineOptions controls the excerpts returned by ts_headline
+const headlineOptions = "StartSel=" + startSel + ", StopSel=" + stopSel + ", MaxWords=25, MinWords=8, MaxFragments=2"
+
+// headlineMarks turns the selection markers of an escaped excerpt into <mark> tags
+var headlineMarks = strings.NewReplacer(startSel, "<mark>", stopSel, "</mark>")
+
+var tables = map[Type]string{
+	TypePackage:    "packages",
+	TypeScenario:   "scenarios",
+	TypeDataset:    "datasets",
+	TypeRepository: "repositories",
+}
+
+// gormRepository implements the Repository interface using PostgreSQL full-text search
+type gormRepository struct {
+	db *gorm.DB
+}
+
+// NewRepository creates a new GORM-based search repository
+func NewRepository(db *gorm.DB) Repository {
+	return &gormRepository{db: db}
+}
+
+func (r *gormRepository) Hits(ctx context.Context, text string, types []Type, limit, offset int) ([]Hit, error) {
+	v := query.VisibilityFor(ctx)
+	parts := make([]string, 0, len(types))
+	for _, t := range types {
+		parts = append(parts, fmt.Sprintf(
+			"SELECT '%s' AS type, id, ts_rank_cd(search_vector, %s) AS rank FROM %s WHERE %s",
+			t, tsQuery, tables[t], matchCondition(v, t)))
+	}
+	sql := strings.Join(parts, " UNION ALL ") + " ORDER BY rank DESC, id LIMIT @limit OFFSET @offset"
+
+	args := v.Args()
+	args["text"] = text
+	args["limit"] = limit
+	args["offset"] = offset
+
+	var hits []Hit
+	err := store.Conn(ctx, r.db).Raw(sql, args).Scan(&hits).Error
+	return hits, err
+}
+
+func (r *gormRepository) Facets(ctx context.Context, text string) (Facets, error) {
+	v := query.VisibilityFor(ctx)
+	counts := make([]string, 0, len(AllTypes))
+	for _, t := range AllTypes {
+		counts = append(counts, fmt.Sprintf(
+			"(SELECT count(*) FROM %s WHERE %s) AS %s",
+			tables[t], matchCondition(v, t), tables[t]))
+	}
+
+	args := v.Args()
+	args["text"] = text
+
+	var facets Facets
+	err := store.Conn(ctx, r.db).Raw("SELECT "+strings.Join(counts, ", "), args).Scan(&facets).Error
+	return facets, err
+}
+
+// matchCondition matches rows of t against the search text, hiding entities in
+// the trash and private entities the caller may not read so they cannot leak
+// through hits or facets
+func matchCondition(v query.Visibility, t Type) string {
+	cond := "search_vector @@ " + tsQuery + " AND deleted_at IS NULL"
+	var visible string
+	switch t {
+	case TypeDataset, TypeRepository:
+		visible = v.Condition(tables[t])
+	case TypePackage:
+		visible = v.RepoCondition(tables[t])
+	}
+	if visible != "" {
+		cond += " AND " + visible
+	}
+	return cond
+}
+
+func (r *gormRepository) Load(ctx context.Context, t Type, ids []string) (map[string]interface{}, error) {
+	db := store.Conn(ctx, r.db).Where("id IN ?", ids)
+	found := make(map[string]interface{}, len(ids))
+
+	switch t {
+	case TypePackage:
+		var items []*pkg.Package
+		if err := db.Find(&items).Error; err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			found[item.ID] = item
+		}
+	case TypeScenario:
+		var items []*scenario.Scenario
+		if err := db.Find(&items).Error; err != nil {
+			return nil, err
+		}
+		if err := scenario.LoadDatasets(ctx, r.db, items...); err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			found[item.ID] = item
+		}
+	case TypeDataset:
+		var items []*dataset.Dataset
+		if err := db.Find(&items).Error; err != nil {
+			return nil, err
+		}
+		if err := dataset.LoadScenarios(ctx, r.db, items...); err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			found[item.ID] = item
+		}
+	case TypeRepository:
+		var items []*repository.Repository
+		if err := db.Find(&items).Error; err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			found[item.ID] = item
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidSearch, t)
+	}
+
+	return found, nil
+}
+
+func (r *gormRepository) Highlights(ctx context.Context, t Type, text string, ids []string) (map[string]string, error) {
+	var rows []struct {
+		ID        string
+		Highlight string
+	}
+	sql := fmt.Sprintf(
+		"SELECT id, ts_headline('english', translate(coalesce(description, ''), @markers, ''), %s, @options) AS highlight FROM %s WHERE id IN @ids",
+		tsQuery, tables[t])
+	err := store.Conn(ctx, r.db).Raw(sql, map[string]interface{}{
+		"text":    text,
+		"markers": startSel + stopSel,
+		"options": headlineOptions,
+		"ids":     ids,
+	}).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	highlights := make(map[string]string, len(rows))
+	for _, row := range rows {
+		highlights[row.ID] = headlineMarks.Replace(html.EscapeString(row.Highlight))
+	}
+	return highlights, nil
+}
+
+func (r *gormRepository) SearchPackages(ctx context.Context, q PackageQuery) ([]Hit, int64, error) {
+	v := query.VisibilityFor(ctx)
+	db := store.Conn(ctx, r.db).Table("packages").
+		Where("search_vector @@ websearch_to_tsquery('english', ?) AND deleted_at IS NULL", q.Text).
+		Scopes(v.Scope(v.RepoCondition("packages")))
+	if len(q.Types) > 0 {
+		db = db.Where("types && ?::text[]", query.TextArray(q.Types))
+	}
+	if q.MinValidationRate != nil {
+		db = db.Where("(validation_status->>'passRate')::numeric >= ?", *q.MinValidationRate)
+	}
+	if q.HasDocumentation != nil {
+		if *q.HasDocumentation {
+			db = db.Where("coalesce(documentation, '') <> ''")
+		} else {
+			db = db.Where("coalesce(documentation, '') = ''")
+		}
+	}
+	db = db.Session(&gorm.Session{})
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var hits []Hit
+	err := db.Select("'package' AS type, id, ts_rank_cd(search_vector, websearch_to_tsquery('english', ?)) AS rank", q.Text).
+		Order("rank DESC, id").Limit(q.Limit).Offset(q.Offset).Scan(&hits).Error
+	return hits, total, err
+}
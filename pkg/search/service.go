@@ -0,0 +1,162 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+
+	"robohub-inventory/pkg/identity"
+	pkg "robohub-inventory/pkg/package"
+)
+
+var ErrInvalidSearch = errors.New("invalid search query")
+
+var tracer = otel.Tracer("robohub-inventory/pkg/search")
+
+// Service handles full-text search across catalog entities
+type Service struct {
+	repo   Repository
+	owners identity.OwnerResolver
+}
+
+func NewService(repo Repository, owners identity.OwnerResolver) *Service {
+	return &Service{repo: repo, owners: owners}
+}
+
+// Search ranks matches across entity types and counts matches per type
+func (s *Service) Search(ctx context.Context, q Query) (*Results, error) {
+	ctx, span := tracer.Start(ctx, "search.Service.Search")
+	defer span.End()
+	q.Text = strings.TrimSpace(q.Text)
+	if q.Text == "" {
+		return nil, fmt.Errorf("%w: q is required", ErrInvalidSearch)
+	}
+	if len(q.Types) == 0 {
+		q.Types = AllTypes
+	}
+	for _, t := range q.Types {
+		if _, ok := tables[t]; !ok {
+			return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidSearch, t)
+		}
+	}
+
+	facets, err := s.repo.Facets(ctx, q.Text)
+	if err != nil {
+		return nil, err
+	}
+	hits, err := s.repo.Hits(ctx, q.Text, q.Types, q.Limit, q.Offset)
+	if err != nil {
+		return nil, err
+	}
+	results, err := s.hydrate(ctx, q.Text, hits)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	for _, t := range q.Types {
+		total += facets.count(t)
+	}
+	return &Results{Results: results, Total: total, Facets: facets}, nil
+}
+
+// SearchPackages runs a filtered, ranked package search
+func (s *Service) SearchPackages(ctx context.Context, q PackageQuery) (*PackageResults, error) {
+	ctx, span := tracer.Start(ctx, "search.Service.SearchPackages")
+	defer span.End()
+	q.Text = strings.TrimSpace(q.Text)
+	if q.Text == "" {
+		return nil, fmt.Errorf("%w: q is required", ErrInvalidSearch)
+	}
+	if q.MinValidationRate != nil && (*q.MinValidationRate < 0 || *q.MinValidationRate > 100) {
+		return nil, fmt.Errorf("%w: minValidationRate must be between 0 and 100", ErrInvalidSearch)
+	}
+
+	hits, total, err := s.repo.SearchPackages(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	results, err := s.hydrate(ctx, q.Text, hits)
+	if err != nil {
+		return nil, err
+	}
+
+	packages := make([]*pkg.Package, 0, len(results))
+	highlights := make(map[string]string, len(results))
+	for _, res := range results {
+		p := res.Data.(*pkg.Package)
+		packages = append(packages, p)
+		if res.Highlight != "" {
+			highlights[p.ID] = res.Highlight
+		}
+	}
+	return &PackageResults{Results: packages, Total: total, Highlights: highlights}, nil
+}
+
+// hydrate loads the entities and excerpts of hits, preserving rank order
+func (s *Service) hydrate(ctx context.Context, text string, hits []Hit) ([]Result, error) {
+	idsByType := make(map[Type][]string)
+	for _, h := range hits {
+		idsByType[h.Type] = append(idsByType[h.Type], h.ID)
+	}
+
+	entities := make(map[Type]map[string]interface{}, len(idsByType))
+	highlights := make(map[Type]map[string]string, len(idsByType))
+	for t, ids := range idsByType {
+		found, err := s.repo.Load(ctx, t, ids)
+		if err != nil {
+			return nil, err
+		}
+		marks, err := s.repo.Highlights(ctx, t, text, ids)
+		if err != nil {
+			return nil, err
+		}
+		entities[t] = found
+		highlights[t] = marks
+	}
+
+	var owned []identity.Owned
+	for _, found := range entities {
+		for _, e := range found {
+			if o, ok := e.(identity.Owned); ok {
+				owned = append(owned, o)
+			}
+		}
+	}
+	if err := identity.AttachOwners(ctx, s.owners, owned...); err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, 0, len(hits))
+	for _, h := range hits {
+		data, ok := entities[h.Type][h.ID]
+		if !ok {
+			// Deleted between ranking and loading
+			continue
+		}
+		results = append(results, Result{
+			Type:      h.Type,
+			Data:      data,
+			Rank:      h.Rank,
+			Highlight: highlights[h.Type][h.ID],
+		})
+	}
+	return results, nil
+}
+
+func (f Facets) count(t Type) int64 {
+	switch t {
+	case TypePackage:
+		return f.Packages
+	case TypeScenario:
+		return f.Scenarios
+	case TypeDataset:
+		return f.Datasets
+	case TypeRepository:
+		return f.Repositories
+	}
+	return 0
+}
@@ -2,19 +2,23 @@ package simulator
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // Simulator represents a simulation environment in the robotics platform
 type Simulator struct {
-	ID          string    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
-	Name        string    `gorm:"uniqueIndex;not null" json:"name"`
-	Description string    `json:"description"`
-	Type        string    `gorm:"not null" json:"type"` // e.g., "gazebo", "unity", "custom"
-	Version     string    `json:"version"`
-	Config      string    `gorm:"type:text" json:"config"` // JSON configuration
-	Tags        []string  `gorm:"type:text[]" json:"tags"`
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
+	ID          string         `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	Name        string         `gorm:"uniqueIndex:idx_simulators_name_active,where:deleted_at IS NULL;not null" json:"name"`
+	Description string         `json:"description"`
+	Type        string         `gorm:"not null" json:"type"` // e.g., "gazebo", "unity", "custom"
+	Version     string         `json:"version"`
+	Config      string         `gorm:"type:text" json:"config"` // JSON configuration
+	Tags        []string       `gorm:"type:text[]" json:"tags"`
+	Revision    int64          `gorm:"not null;default:1" json:"revision"` // Incremented on every update; exposed as the ETag
+	CreatedAt   time.Time      `json:"createdAt"`
+	UpdatedAt   time.Time      `json:"updatedAt"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"deletedAt,omitempty"` // Set while the simulator is in the trash
 }
 
 func (Simulator) TableName() string {
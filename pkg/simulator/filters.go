@@ -0,0 +1,17 @@
+package simulator
+
+import "robohub-inventory/pkg/query"
+
+// ListSchema whitelists the filters and sort orders of the simulator list
+var ListSchema = query.Schema{
+	Fields: []query.Field{
+		{Param: "type", Column: "type", Kind: query.Equals},
+		{Param: "tag", Column: "tags", Kind: query.ArrayContains},
+	},
+	Search: []string{"name", "description"},
+	Sorts: map[string]string{
+		"name":    "name ASC",
+		"newest":  "created_at DESC",
+		"updated": "updated_at DESC",
+	},
+}
@@ -1,13 +1,25 @@
 package simulator
 
-import "context"
+import (
+	"context"
+	"time"
+
+	"robohub-inventory/pkg/query"
+)
 
 // Repository defines the interface for simulator persistence
 type Repository interface {
 	Create(ctx context.Context, simulator *Simulator) error
 	GetByID(ctx context.Context, id string) (*Simulator, error)
 	GetByName(ctx context.Context, name string) (*Simulator, error)
-	List(ctx context.Context, limit, offset int) ([]*Simulator, error)
+	ListByIDs(ctx context.Context, ids []string) ([]*Simulator, error)
+	List(ctx context.Context, spec query.Spec) ([]*Simulator, error)
+	Count(ctx context.Context, spec query.Spec) (int64, error)
 	Update(ctx context.Context, simulator *Simulator) error
-	Delete(ctx context.Context, id string) error
+	Delete(ctx context.Context, id string, revision int64) error
+	GetDeleted(ctx context.Context, id string) (*Simulator, error)
+	ListDeleted(ctx context.Context, spec query.Spec) ([]*Simulator, error)
+	CountDeleted(ctx context.Context, spec query.Spec) (int64, error)
+	Restore(ctx context.Context, simulator *Simulator) error
+	Purge(ctx context.Context, before time.Time) (int64, error)
 }
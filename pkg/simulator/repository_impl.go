@@ -2,7 +2,12 @@ package simulator
 
 import (
 	"context"
+	"time"
+
 	"gorm.io/gorm"
+
+	"robohub-inventory/pkg/query"
+	"robohub-inventory/pkg/store"
 )
 
 // gormRepository implements the Repository interface using GORM
@@ -16,12 +21,12 @@ func NewRepository(db *gorm.DB) Repository {
 }
 
 func (r *gormRepository) Create(ctx context.Context, simulator *Simulator) error {
-	return r.db.WithContext(ctx).Create(simulator).Error
+	return store.Conn(ctx, r.db).Create(simulator).Error
 }
 
 func (r *gormRepository) GetByID(ctx context.Context, id string) (*Simulator, error) {
 	var simulator Simulator
-	err := r.db.WithContext(ctx).Where("id = ?", id).First(&simulator).Error
+	err := store.Conn(ctx, r.db).Where("id = ?", id).First(&simulator).Error
 	if err != nil {
 		return nil, err
 	}
@@ -30,30 +35,110 @@ func (r *gormRepository) GetByID(ctx context.Context, id string) (*Simulator, er
 
 func (r *gormRepository) GetByName(ctx context.Context, name string) (*Simulator, error) {
 	var simulator Simulator
-	err := r.db.WithContext(ctx).Where("name = ?", name).First(&simulator).Error
+	err := store.Conn(ctx, r.db).Where("name = ?", name).First(&simulator).Error
 	if err != nil {
 		return nil, err
 	}
 	return &simulator, nil
 }
 
-func (r *gormRepository) List(ctx context.Context, limit, offset int) ([]*Simulator, error) {
+func (r *gormRepository) ListByIDs(ctx context.Context, ids []string) ([]*Simulator, error) {
 	var simulators []*Simulator
-	query := r.db.WithContext(ctx)
-	if limit > 0 {
-		query = query.Limit(limit)
+	err := store.Conn(ctx, r.db).Where("id IN ?", ids).Find(&simulators).Error
+	return simulators, err
+}
+
+func (r *gormRepository) List(ctx context.Context, spec query.Spec) ([]*Simulator, error) {
+	var simulators []*Simulator
+	err := spec.Apply(store.Conn(ctx, r.db)).Find(&simulators).Error
+	return simulators, err
+}
+
+func (r *gormRepository) Count(ctx context.Context, spec query.Spec) (int64, error) {
+	var count int64
+	err := spec.Where(store.Conn(ctx, r.db).Model(&Simulator{})).Count(&count).Error
+	return count, err
+}
+
+// Update saves simulator if its stored revision still equals simulator.Revision and bumps the revision
+func (r *gormRepository) Update(ctx context.Context, simulator *Simulator) error {
+	revision := simulator.Revision
+	simulator.Revision++
+	result := store.Conn(ctx, r.db).Model(simulator).Where("id = ? AND revision = ?", simulator.ID, revision).
+		Select("*").Omit("id", "created_at", "deleted_at").Updates(simulator)
+	if result.Error != nil {
+		simulator.Revision = revision
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		simulator.Revision = revision
+		return store.ErrRevisionMismatch
+	}
+	return store.Conn(ctx, r.db).Where("id = ?", simulator.ID).First(simulator).Error
+}
+
+// Delete moves the simulator to the trash, bumping its revision; a non-zero
+// revision must match the stored one
+func (r *gormRepository) Delete(ctx context.Context, id string, revision int64) error {
+	db := store.Conn(ctx, r.db).Where("id = ?", id)
+	if revision != 0 {
+		db = db.Where("revision = ?", revision)
+	}
+	result := db.Model(&Simulator{}).UpdateColumns(store.Trashed())
+	if result.Error != nil {
+		return result.Error
 	}
-	if offset > 0 {
-		query = query.Offset(offset)
+	if result.RowsAffected == 0 {
+		if revision != 0 {
+			return store.ErrRevisionMismatch
+		}
+		return gorm.ErrRecordNotFound
 	}
-	err := query.Order("created_at DESC").Find(&simulators).Error
+	return nil
+}
+
+// trash starts a query limited to deleted simulators
+func (r *gormRepository) trash(ctx context.Context) *gorm.DB {
+	return store.Conn(ctx, r.db).Unscoped().Where("simulators.deleted_at IS NOT NULL")
+}
+
+func (r *gormRepository) GetDeleted(ctx context.Context, id string) (*Simulator, error) {
+	var simulator Simulator
+	err := store.Conn(ctx, r.db).Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&simulator).Error
+	if err != nil {
+		return nil, err
+	}
+	return &simulator, nil
+}
+
+func (r *gormRepository) ListDeleted(ctx context.Context, spec query.Spec) ([]*Simulator, error) {
+	var simulators []*Simulator
+	err := spec.Apply(r.trash(ctx)).Find(&simulators).Error
 	return simulators, err
 }
 
-func (r *gormRepository) Update(ctx context.Context, simulator *Simulator) error {
-	return r.db.WithContext(ctx).Save(simulator).Error
+func (r *gormRepository) CountDeleted(ctx context.Context, spec query.Spec) (int64, error) {
+	var count int64
+	err := spec.Where(r.trash(ctx).Model(&Simulator{})).Count(&count).Error
+	return count, err
+}
+
+// Restore takes the simulator out of the trash, bumping its revision.
+func (r *gormRepository) Restore(ctx context.Context, simulator *Simulator) error {
+	db := store.Conn(ctx, r.db)
+	result := db.Unscoped().Model(&Simulator{}).Where("id = ? AND deleted_at IS NOT NULL", simulator.ID).
+		Updates(map[string]interface{}{"deleted_at": nil, "revision": gorm.Expr("revision + 1")})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return db.Where("id = ?", simulator.ID).First(simulator).Error
 }
 
-func (r *gormRepository) Delete(ctx context.Context, id string) error {
-	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&Simulator{}).Error
+// Purge permanently deletes the simulators moved to the trash before the given time
+func (r *gormRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	result := store.Conn(ctx, r.db).Unscoped().Where("deleted_at < ?", before).Delete(&Simulator{})
+	return result.RowsAffected, result.Error
 }
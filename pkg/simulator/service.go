@@ -3,56 +3,265 @@ package simulator
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"gorm.io/gorm"
+
+	"robohub-inventory/pkg/auth"
+	"robohub-inventory/pkg/event"
+	"robohub-inventory/pkg/patch"
+	"robohub-inventory/pkg/query"
+	"robohub-inventory/pkg/store"
 )
 
 var (
-	ErrSimulatorNotFound = errors.New("simulator not found")
-	ErrInvalidSimulator  = errors.New("invalid simulator data")
+	ErrSimulatorNotFound      = errors.New("simulator not found")
+	ErrInvalidSimulator       = errors.New("invalid simulator data")
+	ErrSimulatorAlreadyExists = errors.New("simulator already exists")
+	ErrSimulatorNameTaken     = errors.New("simulator name is taken by another simulator; rename one of them before restoring")
 )
 
-// Service handles business logic for simulators
+// Service handles business logic for simulators. Simulators are platform
+// resources without an owner, so only platform admins may change them.
+var tracer = otel.Tracer("robohub-inventory/pkg/simulator")
+
 type Service struct {
-	repo Repository
+	repo   Repository
+	tx     *store.Transactor
+	events event.Publisher
 }
 
-func NewService(repo Repository) *Service {
-	return &Service{repo: repo}
+func NewService(repo Repository, tx *store.Transactor, events event.Publisher) *Service {
+	return &Service{repo: repo, tx: tx, events: events}
 }
 
 func (s *Service) CreateSimulator(ctx context.Context, simulator *Simulator) error {
-	if simulator.Name == "" {
-		return ErrInvalidSimulator
+	ctx, span := tracer.Start(ctx, "simulator.Service.CreateSimulator")
+	defer span.End()
+	if err := auth.RequireAdmin(ctx); err != nil {
+		return err
 	}
-	return s.repo.Create(ctx, simulator)
+	if err := validateSimulator(simulator); err != nil {
+		return err
+	}
+	simulator.Revision, simulator.DeletedAt = 1, gorm.DeletedAt{}
+	return translateError(s.repo.Create(ctx, simulator))
 }
 
 func (s *Service) GetSimulator(ctx context.Context, id string) (*Simulator, error) {
+	ctx, span := tracer.Start(ctx, "simulator.Service.GetSimulator")
+	defer span.End()
 	simulator, err := s.repo.GetByID(ctx, id)
 	if err != nil {
-		return nil, ErrSimulatorNotFound
+		return nil, translateError(err)
 	}
 	return simulator, nil
 }
 
 func (s *Service) GetSimulatorByName(ctx context.Context, name string) (*Simulator, error) {
+	ctx, span := tracer.Start(ctx, "simulator.Service.GetSimulatorByName")
+	defer span.End()
 	simulator, err := s.repo.GetByName(ctx, name)
 	if err != nil {
-		return nil, ErrSimulatorNotFound
+		return nil, translateError(err)
 	}
 	return simulator, nil
 }
 
-func (s *Service) ListSimulators(ctx context.Context, limit, offset int) ([]*Simulator, error) {
-	return s.repo.List(ctx, limit, offset)
+// GetSimulatorsBatch loads the simulators with the given IDs in one query. It returns them
+// in request order together with the IDs that were not found.
+func (s *Service) GetSimulatorsBatch(ctx context.Context, ids []string) ([]*Simulator, []string, error) {
+	ctx, span := tracer.Start(ctx, "simulator.Service.GetSimulatorsBatch")
+	defer span.End()
+	valid, err := query.BatchIDs(ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	var simulators []*Simulator
+	if len(valid) > 0 {
+		if simulators, err = s.repo.ListByIDs(ctx, valid); err != nil {
+			return nil, nil, err
+		}
+	}
+	found, notFound := query.OrderBatch(ids, simulators, func(x *Simulator) string { return x.ID })
+	return found, notFound, nil
 }
 
+// ListSimulators returns one page of simulators matching spec together with the total count
+func (s *Service) ListSimulators(ctx context.Context, spec query.Spec) ([]*Simulator, int64, error) {
+	ctx, span := tracer.Start(ctx, "simulator.Service.ListSimulators")
+	defer span.End()
+	simulators, err := s.repo.List(ctx, spec)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := s.repo.Count(ctx, spec)
+	if err != nil {
+		return nil, 0, err
+	}
+	return simulators, total, nil
+}
+
+// UpdateSimulator replaces a simulator; a non-zero revision must match the stored revision
 func (s *Service) UpdateSimulator(ctx context.Context, simulator *Simulator) error {
+	ctx, span := tracer.Start(ctx, "simulator.Service.UpdateSimulator")
+	defer span.End()
+	if err := auth.RequireAdmin(ctx); err != nil {
+		return err
+	}
+	if err := validateSimulator(simulator); err != nil {
+		return err
+	}
+	current, err := s.repo.GetByID(ctx, simulator.ID)
+	if err != nil {
+		return translateError(err)
+	}
+	if err := store.CheckRevision(simulator.Revision, current.Revision); err != nil {
+		return err
+	}
+	simulator.Revision = current.Revision
+	return translateError(s.repo.Update(ctx, simulator))
+}
+
+// UpsertSimulator creates the simulator or, if one with the same name exists, updates it
+// in place. It reports whether the simulator was created.
+func (s *Service) UpsertSimulator(ctx context.Context, simulator *Simulator) (bool, error) {
+	ctx, span := tracer.Start(ctx, "simulator.Service.UpsertSimulator")
+	defer span.End()
+	existing, err := s.repo.GetByName(ctx, simulator.Name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return true, s.CreateSimulator(ctx, simulator)
+	}
+	if err != nil {
+		return false, err
+	}
+	simulator.ID, simulator.Revision = existing.ID, 0
+	return false, s.UpdateSimulator(ctx, simulator)
+}
+
+// PatchSimulator applies a merge patch or JSON Patch to the stored simulator and saves the result,
+// validating the merged simulator as a whole.
+func (s *Service) PatchSimulator(ctx context.Context, id string, revision int64, p *patch.Patch) (*Simulator, error) {
+	ctx, span := tracer.Start(ctx, "simulator.Service.PatchSimulator")
+	defer span.End()
+	if err := auth.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	current, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	if err := store.CheckRevision(revision, current.Revision); err != nil {
+		return nil, err
+	}
+	var simulator Simulator
+	if err := p.Apply(current, &simulator); err != nil {
+		return nil, err
+	}
+	// Saving against the revision the patch was applied to keeps concurrent writes from being lost
+	simulator.ID, simulator.CreatedAt, simulator.Revision = current.ID, current.CreatedAt, current.Revision
+	if err := s.UpdateSimulator(ctx, &simulator); err != nil {
+		return nil, err
+	}
+	return &simulator, nil
+}
+
+// DeleteSimulator moves a simulator to the trash; a non-zero revision must match the stored revision
+func (s *Service) DeleteSimulator(ctx context.Context, id string, revision int64) error {
+	ctx, span := tracer.Start(ctx, "simulator.Service.DeleteSimulator")
+	defer span.End()
+	if err := auth.RequireAdmin(ctx); err != nil {
+		return err
+	}
+	return s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.repo.Delete(ctx, id, revision); err != nil {
+			return translateError(err)
+		}
+		simulator, err := s.repo.GetDeleted(ctx, id)
+		if err != nil {
+			return err
+		}
+		return s.publishTrashEvent(ctx, event.SimulatorDeleted, simulator)
+	})
+}
+
+// ListDeletedSimulators returns one page of deleted simulators; only platform admins may list them, together with the total count
+func (s *Service) ListDeletedSimulators(ctx context.Context, spec query.Spec) ([]*Simulator, int64, error) {
+	ctx, span := tracer.Start(ctx, "simulator.Service.ListDeletedSimulators")
+	defer span.End()
+	if err := auth.RequireAdmin(ctx); err != nil {
+		return nil, 0, err
+	}
+	simulators, err := s.repo.ListDeleted(ctx, spec)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := s.repo.CountDeleted(ctx, spec)
+	if err != nil {
+		return nil, 0, err
+	}
+	return simulators, total, nil
+}
+
+// RestoreSimulator takes a simulator out of the trash; only platform admins may restore it.
+func (s *Service) RestoreSimulator(ctx context.Context, id string) (*Simulator, error) {
+	ctx, span := tracer.Start(ctx, "simulator.Service.RestoreSimulator")
+	defer span.End()
+	if err := auth.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	current, err := s.repo.GetDeleted(ctx, id)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	err = s.tx.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.repo.Restore(ctx, current); err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return ErrSimulatorNameTaken
+			}
+			return translateError(err)
+		}
+		return s.publishTrashEvent(ctx, event.SimulatorRestored, current)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return current, nil
+}
+
+// publishTrashEvent publishes an event of type typ about simulator, which was
+// moved to or out of the trash
+func (s *Service) publishTrashEvent(ctx context.Context, typ string, simulator *Simulator) error {
+	e, err := event.New(typ, "simulator", simulator.ID, simulator)
+	if err != nil {
+		return err
+	}
+	return s.events.Publish(ctx, e)
+}
+
+// PurgeDeletedSimulators permanently deletes the simulators moved to the trash before the given time
+func (s *Service) PurgeDeletedSimulators(ctx context.Context, before time.Time) (int64, error) {
+	ctx, span := tracer.Start(ctx, "simulator.Service.PurgeDeletedSimulators")
+	defer span.End()
+	return s.repo.Purge(ctx, before)
+}
+
+func validateSimulator(simulator *Simulator) error {
 	if simulator.Name == "" {
-		return ErrInvalidSimulator
+		return fmt.Errorf("%w: name is required", ErrInvalidSimulator)
 	}
-	return s.repo.Update(ctx, simulator)
+	return nil
 }
 
-func (s *Service) DeleteSimulator(ctx context.Context, id string) error {
-	return s.repo.Delete(ctx, id)
+// translateError maps persistence errors onto the simulator service errors
+func translateError(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrSimulatorNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return ErrSimulatorAlreadyExists
+	}
+	return err
 }
@@ -0,0 +1,92 @@
+package store
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"robohub-inventory/pkg/query"
+)
+
+// ErrInvalidReference reports a reference to an entity that does not exist,
+// is in the trash or is hidden from the caller
+var ErrInvalidReference = errors.New("invalid reference")
+
+// NullID is an entity reference stored in a nullable UUID column. The empty
+// string stands for NULL, so references stay plain strings in the API.
+type NullID string
+
+// Scan implements sql.Scanner
+func (id *NullID) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*id = ""
+	case string:
+		*id = NullID(v)
+	case []byte:
+		*id = NullID(v)
+	default:
+		return fmt.Errorf("cannot scan %T into NullID", value)
+	}
+	return nil
+}
+
+// Value implements driver.Valuer
+func (id NullID) Value() (driver.Value, error) {
+	if id == "" {
+		return nil, nil
+	}
+	return string(id), nil
+}
+
+// MissingIDs returns the distinct ids that match no row of db, in the order
+// given. db selects the rows that may be referenced, e.g. the live rows of a
+// table visible to the caller.
+func MissingIDs(db *gorm.DB, ids []string) ([]string, error) {
+	var candidates []string
+	for _, id := range ids {
+		if query.ValidID(id) {
+			candidates = append(candidates, id)
+		}
+	}
+
+	found := make(map[string]bool, len(candidates))
+	if len(candidates) > 0 {
+		var existing []string
+		if err := db.Where("id IN ?", candidates).Pluck("id", &existing).Error; err != nil {
+			return nil, err
+		}
+		for _, id := range existing {
+			found[id] = true
+		}
+	}
+
+	// PostgreSQL returns UUIDs in lower case
+	var missing []string
+	for _, id := range ids {
+		key := strings.ToLower(id)
+		if !found[key] {
+			found[key] = true
+			missing = append(missing, id)
+		}
+	}
+	return missing, nil
+}
+
+// DistinctIDs returns ids without repeats, keeping the first occurrence.
+// IDs are compared case-insensitively, as UUIDs are.
+func DistinctIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	distinct := make([]string, 0, len(ids))
+	for _, id := range ids {
+		key := strings.ToLower(id)
+		if !seen[key] {
+			seen[key] = true
+			distinct = append(distinct, id)
+		}
+	}
+	return distinct
+}
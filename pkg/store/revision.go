@@ -0,0 +1,28 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// ErrRevisionMismatch reports that an entity changed since the client, or
+// the service, last read it
+var ErrRevisionMismatch = errors.New("revision mismatch")
+
+// CheckRevision compares the revision a client expects with the stored one.
+// An expected revision of 0 means the client set no precondition.
+func CheckRevision(expected, actual int64) error {
+	if expected != 0 && expected != actual {
+		return fmt.Errorf("%w: expected revision %d, current revision is %d", ErrRevisionMismatch, expected, actual)
+	}
+	return nil
+}
+
+// Trashed returns the columns set when moving rows to the trash: the
+// transaction timestamp, shared by everything deleted together, and the next
+// revision
+func Trashed() map[string]interface{} {
+	return map[string]interface{}{"deleted_at": gorm.Expr("now()"), "revision": gorm.Expr("revision + 1")}
+}
@@ -0,0 +1,39 @@
+// Package store lets repositories share a database transaction carried in
+// the request context, so that services can group writes across domains.
+package store
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type txKey struct{}
+
+// Conn returns the transaction carried by ctx, or db outside a transaction,
+// bound to ctx. Repositories use it instead of db.WithContext.
+func Conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
+
+// Transactor runs functions inside database transactions
+type Transactor struct {
+	db *gorm.DB
+}
+
+// NewTransactor creates a transactor on db
+func NewTransactor(db *gorm.DB) *Transactor {
+	return &Transactor{db: db}
+}
+
+// WithinTransaction runs fn with a context carrying a transaction. The
+// transaction commits if fn returns nil and rolls back otherwise. Inside an
+// existing transaction it uses a savepoint, so that fn can fail on its own.
+func (t *Transactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return Conn(ctx, t.db).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
@@ -0,0 +1,239 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"robohub-inventory/pkg/event"
+)
+
+// Delivery request headers
+const (
+	HeaderEvent     = "X-RoboHub-Event"
+	HeaderDelivery  = "X-RoboHub-Delivery"
+	HeaderSignature = "X-RoboHub-Signature"
+)
+
+// maxErrorLength caps the response excerpt kept as the error of a failed attempt
+const maxErrorLength = 512
+
+// Payload is the body of a delivery (API_CONTRACT.md §9)
+type Payload struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	Timestamp  time.Time       `json:"timestamp"`
+	Data       json.RawMessage `json:"data"`
+	Attempt    int             `json:"attempt"`
+	MaxRetries int             `json:"maxRetries"`
+}
+
+// Sign returns the X-RoboHub-Signature header of a body sent at t: the Unix
+// time and the hex HMAC-SHA256 of "<time>.<body>" keyed with the secret.
+// Receivers recompute it and reject stale times to prevent replays.
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "."))
+	mac.Write(body)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatcher polls for due deliveries and sends them with a pool of workers
+type Dispatcher struct {
+	service  *Service
+	client   *http.Client
+	workers  int
+	interval time.Duration
+	log      *slog.Logger
+}
+
+func NewDispatcher(service *Service, workers int, interval time.Duration, log *slog.Logger) *Dispatcher {
+	client := &http.Client{
+		Timeout: service.opts.Timeout,
+		// A redirect is reported as a failure rather than followed to an unchecked URL
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	return &Dispatcher{service: service, client: client, workers: workers, interval: interval, log: log}
+}
+
+// Run dispatches due deliveries every interval until ctx is done. A full
+// batch is followed by the next one right away.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		if d.Dispatch(ctx) < d.batchSize() {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		} else if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) batchSize() int {
+	return 4 * d.workers
+}
+
+// Dispatch claims one batch of due deliveries, attempts them and returns how
+// many it claimed. Each batch starts a new trace.
+func (d *Dispatcher) Dispatch(ctx context.Context) int {
+	ctx, span := tracer.Start(ctx, "webhook.Dispatch", trace.WithNewRoot())
+	defer span.End()
+
+	s := d.service
+	now := s.now()
+	// A replica stopping mid-attempt leaves the delivery to be retried once the lease expires
+	deliveries, err := s.repo.ClaimDue(ctx, now, now.Add(s.opts.Timeout+time.Minute), d.batchSize())
+	if err != nil {
+		d.log.ErrorContext(ctx, "Failed to claim webhook deliveries", "error", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "failed to claim webhook deliveries")
+		return 0
+	}
+	span.SetAttributes(attribute.Int("webhook.deliveries", len(deliveries)))
+
+	jobs := make(chan *Delivery)
+	var wg sync.WaitGroup
+	for i := 0; i < min(d.workers, len(deliveries)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for delivery := range jobs {
+				d.attempt(ctx, delivery)
+			}
+		}()
+	}
+	for _, delivery := range deliveries {
+		jobs <- delivery
+	}
+	close(jobs)
+	wg.Wait()
+	return len(deliveries)
+}
+
+// attempt sends a claimed delivery and records the outcome
+func (d *Dispatcher) attempt(ctx context.Context, delivery *Delivery) {
+	ctx, span := tracer.Start(ctx, "webhook.Attempt", trace.WithAttributes(
+		attribute.String("webhook.delivery_id", delivery.ID),
+		attribute.String("webhook.event_type", delivery.EventType),
+		attribute.Int("webhook.attempt", delivery.Attempt),
+	))
+	defer span.End()
+
+	s := d.service
+	log := d.log.With("delivery", delivery.ID, "subscription", delivery.SubscriptionID, "attempt", delivery.Attempt)
+	sub, err := s.repo.GetByID(ctx, delivery.SubscriptionID)
+	if err != nil {
+		log.ErrorContext(ctx, "Failed to load webhook subscription", "error", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "failed to load webhook subscription")
+		return
+	}
+
+	status, sendErr := d.send(ctx, sub, delivery)
+	now := s.now()
+	delivery.LastAttemptAt, delivery.ResponseStatus, delivery.LastError = &now, status, ""
+	if sendErr == nil {
+		delivery.Status, delivery.DeliveredAt = StatusSucceeded, &now
+		if err := s.repo.SaveAttempt(ctx, delivery); err != nil {
+			log.ErrorContext(ctx, "Failed to record webhook delivery", "error", err)
+		}
+		if err := s.repo.RecordSuccess(ctx, sub.ID); err != nil {
+			log.ErrorContext(ctx, "Failed to record webhook delivery", "error", err)
+		}
+		return
+	}
+
+	span.RecordError(sendErr)
+	span.SetStatus(codes.Error, "webhook delivery failed")
+	delivery.LastError = sendErr.Error()
+	if delivery.Attempt > delivery.MaxRetries {
+		delivery.Status = StatusFailed
+		log.WarnContext(ctx, "Webhook delivery failed", "error", sendErr)
+	} else {
+		delivery.NextAttemptAt = now.Add(s.backoff(delivery.Attempt))
+		log.InfoContext(ctx, "Webhook delivery will be retried", "error", sendErr, "next_attempt_at", delivery.NextAttemptAt)
+	}
+	if err := s.repo.SaveAttempt(ctx, delivery); err != nil {
+		log.ErrorContext(ctx, "Failed to record webhook delivery", "error", err)
+	}
+
+	reason := fmt.Sprintf("deliveries failing for more than %s", s.opts.DisableAfter)
+	disabled, err := s.repo.RecordFailure(ctx, sub.ID, now, now.Add(-s.opts.DisableAfter), reason)
+	if err != nil {
+		log.ErrorContext(ctx, "Failed to record webhook delivery", "error", err)
+	}
+	if disabled {
+		log.WarnContext(ctx, "Disabled failing webhook subscription", "url", sub.URL)
+	}
+}
+
+// send posts the payload of a delivery and returns the response status. Any
+// status outside 2xx is an error.
+func (d *Dispatcher) send(ctx context.Context, sub *Subscription, delivery *Delivery) (int, error) {
+	body, err := json.Marshal(newPayload(delivery.Event, delivery.Attempt, delivery.MaxRetries))
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "RoboHub-Webhooks/1.0")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderSignature, Sign(sub.Secret, d.service.now(), body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	excerpt, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorLength))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(excerpt))
+	}
+	return resp.StatusCode, nil
+}
+
+func newPayload(e event.Event, attempt, maxRetries int) Payload {
+	return Payload{
+		ID:         e.ID,
+		Type:       e.Type,
+		Timestamp:  e.Timestamp,
+		Data:       e.Data,
+		Attempt:    attempt,
+		MaxRetries: maxRetries,
+	}
+}
+
+// backoff returns the delay before the retry following the given attempt:
+// the base delay doubled for each earlier retry, capped at the maximum
+func (s *Service) backoff(attempt int) time.Duration {
+	delay := s.opts.BackoffBase
+	for i := 1; i < attempt && delay < s.opts.BackoffMax; i++ {
+		delay *= 2
+	}
+	return min(delay, s.opts.BackoffMax)
+}
@@ -0,0 +1,66 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	at := time.Unix(1700000000, 0)
+	tests := []struct {
+		name   string
+		secret string
+		t      time.Time
+		body   string
+		want   string
+	}{
+		{
+			name:   "body",
+			secret: "whsec_0123456789abcdef",
+			t:      at,
+			body:   `{"type":"package.created"}`,
+			want:   "t=1700000000,v1=c9788b7a2d72336ec50afcce10875df45d971b14eb93394f3c11ecfb42281896",
+		},
+		{
+			name:   "empty body",
+			secret: "whsec_0123456789abcdef",
+			t:      at,
+			want:   "t=1700000000,v1=0e1fd9afd303fb6f213cc511093ec9783e6286d777cfee89f917aebd54d9a963",
+		},
+		{
+			name:   "sub-second time is truncated",
+			secret: "whsec_0123456789abcdef",
+			t:      at.Add(999 * time.Millisecond),
+			body:   `{"type":"package.created"}`,
+			want:   "t=1700000000,v1=c9788b7a2d72336ec50afcce10875df45d971b14eb93394f3c11ecfb42281896",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sign(tt.secret, tt.t, []byte(tt.body)); got != tt.want {
+				t.Errorf("Sign() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	s := &Service{opts: Options{BackoffBase: 30 * time.Second, BackoffMax: 5 * time.Minute}}
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 1, want: 30 * time.Second},
+		{attempt: 2, want: time.Minute},
+		{attempt: 3, want: 2 * time.Minute},
+		{attempt: 4, want: 4 * time.Minute},
+		{attempt: 5, want: 5 * time.Minute},
+		{attempt: 6, want: 5 * time.Minute},
+		{attempt: 100, want: 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := s.backoff(tt.attempt); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package webhook
+
+import (
+	"time"
+
+	"robohub-inventory/pkg/event"
+)
+
+// Delivery statuses
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Subscription posts the events it selects to a URL. The secret signs every
+// delivery and is only returned when the subscription is created.
+type Subscription struct {
+	ID             string     `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	URL            string     `gorm:"not null" json:"url"`
+	Description    string     `json:"description,omitempty"`
+	Secret         string     `gorm:"not null" json:"-"`
+	Events         []string   `gorm:"type:text[]" json:"events"` // Event types delivered; empty selects all
+	Active         bool       `gorm:"not null" json:"active"`
+	FailingSince   *time.Time `json:"failingSince,omitempty"` // First failed attempt since the last success
+	DisabledAt     *time.Time `json:"disabledAt,omitempty"`
+	DisabledReason string     `json:"disabledReason,omitempty"`
+	CreatedBy      string     `gorm:"not null" json:"createdBy"`
+
+	// Timestamps
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func (Subscription) TableName() string {
+	return "webhook_subscriptions"
+}
+
+// Selects reports whether the subscription receives events of type typ
+func (s *Subscription) Selects(typ string) bool {
+	if len(s.Events) == 0 {
+		return true
+	}
+	for _, e := range s.Events {
+		if e == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// Delivery is one event sent, or to be sent, to a subscription
+type Delivery struct {
+	ID             string      `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	SubscriptionID string      `gorm:"type:uuid;not null;index" json:"subscriptionId"`
+	EventID        string      `gorm:"type:uuid;not null" json:"eventId"`
+	EventType      string      `gorm:"not null" json:"eventType"`
+	Event          event.Event `gorm:"type:jsonb;not null" json:"event"`
+	Status         string      `gorm:"not null" json:"status"`  // "pending" | "succeeded" | "failed"
+	Attempt        int         `gorm:"not null" json:"attempt"` // Attempts made so far
+	MaxRetries     int         `gorm:"not null" json:"maxRetries"`
+	NextAttemptAt  time.Time   `gorm:"not null" json:"nextAttemptAt"`
+	LastAttemptAt  *time.Time  `json:"lastAttemptAt,omitempty"`
+	ResponseStatus int         `json:"responseStatus,omitempty"` // HTTP status of the last attempt
+	LastError      string      `json:"lastError,omitempty"`
+	DeliveredAt    *time.Time  `json:"deliveredAt,omitempty"`
+	RedeliveryOf   *string     `gorm:"type:uuid" json:"redeliveryOf,omitempty"` // Delivery this one repeats
+
+	// Timestamps
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func (Delivery) TableName() string {
+	return "webhook_deliveries"
+}
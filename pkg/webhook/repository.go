@@ -0,0 +1,33 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	"robohub-inventory/pkg/query"
+)
+
+// Repository defines the interface for webhook persistence
+type Repository interface {
+	Create(ctx context.Context, sub *Subscription) error
+	GetByID(ctx context.Context, id string) (*Subscription, error)
+	List(ctx context.Context) ([]*Subscription, error)
+	ListActive(ctx context.Context) ([]*Subscription, error)
+	Update(ctx context.Context, sub *Subscription) error
+	Delete(ctx context.Context, id string) error
+
+	CreateDeliveries(ctx context.Context, deliveries []*Delivery) error
+	GetDelivery(ctx context.Context, subscriptionID, id string) (*Delivery, error)
+	ListDeliveries(ctx context.Context, subscriptionID string, page query.Page) ([]*Delivery, error)
+	CountDeliveries(ctx context.Context, subscriptionID string) (int64, error)
+	// ClaimDue takes up to limit pending deliveries of active subscriptions
+	// due at now, counting an attempt and leasing them until leaseUntil
+	ClaimDue(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*Delivery, error)
+	SaveAttempt(ctx context.Context, d *Delivery) error
+	// RecordSuccess clears the failure streak of a subscription
+	RecordSuccess(ctx context.Context, subscriptionID string) error
+	// RecordFailure starts the failure streak of a subscription if none is
+	// running, and disables it if the streak began at or before disableBefore.
+	// It reports whether the subscription was disabled.
+	RecordFailure(ctx context.Context, subscriptionID string, now, disableBefore time.Time, reason string) (bool, error)
+}
@@ -0,0 +1,142 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"robohub-inventory/pkg/query"
+	"robohub-inventory/pkg/store"
+)
+
+// gormRepository implements the Repository interface using GORM
+type gormRepository struct {
+	db *gorm.DB
+}
+
+// NewRepository creates a new GORM-based webhook repository
+func NewRepository(db *gorm.DB) Repository {
+	return &gormRepository{db: db}
+}
+
+func (r *gormRepository) Create(ctx context.Context, sub *Subscription) error {
+	return store.Conn(ctx, r.db).Create(sub).Error
+}
+
+func (r *gormRepository) GetByID(ctx context.Context, id string) (*Subscription, error) {
+	var sub Subscription
+	if err := store.Conn(ctx, r.db).Where("id = ?", id).First(&sub).Error; err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+func (r *gormRepository) List(ctx context.Context) ([]*Subscription, error) {
+	var subs []*Subscription
+	err := store.Conn(ctx, r.db).Order("created_at DESC").Order("id DESC").Find(&subs).Error
+	return subs, err
+}
+
+func (r *gormRepository) ListActive(ctx context.Context) ([]*Subscription, error) {
+	var subs []*Subscription
+	err := store.Conn(ctx, r.db).Where("active").Find(&subs).Error
+	return subs, err
+}
+
+func (r *gormRepository) Update(ctx context.Context, sub *Subscription) error {
+	result := store.Conn(ctx, r.db).Model(sub).Where("id = ?", sub.ID).
+		Select("*").Omit("id", "created_at", "created_by").Updates(sub)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return store.Conn(ctx, r.db).Where("id = ?", sub.ID).First(sub).Error
+}
+
+// Delete removes a subscription together with its delivery log
+func (r *gormRepository) Delete(ctx context.Context, id string) error {
+	result := store.Conn(ctx, r.db).Where("id = ?", id).Delete(&Subscription{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *gormRepository) CreateDeliveries(ctx context.Context, deliveries []*Delivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return store.Conn(ctx, r.db).Create(deliveries).Error
+}
+
+func (r *gormRepository) GetDelivery(ctx context.Context, subscriptionID, id string) (*Delivery, error) {
+	var d Delivery
+	err := store.Conn(ctx, r.db).Where("id = ? AND subscription_id = ?", id, subscriptionID).First(&d).Error
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r *gormRepository) ListDeliveries(ctx context.Context, subscriptionID string, page query.Page) ([]*Delivery, error) {
+	var deliveries []*Delivery
+	db := store.Conn(ctx, r.db).Where("subscription_id = ?", subscriptionID)
+	err := page.Apply(db).Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *gormRepository) CountDeliveries(ctx context.Context, subscriptionID string) (int64, error) {
+	var total int64
+	err := store.Conn(ctx, r.db).Model(&Delivery{}).Where("subscription_id = ?", subscriptionID).Count(&total).Error
+	return total, err
+}
+
+// ClaimDue skips the rows other replicas are claiming, so each delivery is
+// attempted by one replica at a time
+func (r *gormRepository) ClaimDue(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*Delivery, error) {
+	var deliveries []*Delivery
+	err := store.Conn(ctx, r.db).Raw(`
+		UPDATE webhook_deliveries
+		SET attempt = attempt + 1, next_attempt_at = ?, updated_at = ?
+		WHERE id IN (
+			SELECT d.id FROM webhook_deliveries d
+			JOIN webhook_subscriptions s ON s.id = d.subscription_id
+			WHERE d.status = ? AND d.next_attempt_at <= ? AND s.active
+			ORDER BY d.next_attempt_at
+			LIMIT ?
+			FOR UPDATE OF d SKIP LOCKED
+		)
+		RETURNING *`, leaseUntil, now, StatusPending, now, limit).Scan(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *gormRepository) SaveAttempt(ctx context.Context, d *Delivery) error {
+	return store.Conn(ctx, r.db).Model(d).Select(
+		"status", "next_attempt_at", "last_attempt_at", "response_status", "last_error", "delivered_at", "updated_at",
+	).Updates(d).Error
+}
+
+func (r *gormRepository) RecordSuccess(ctx context.Context, subscriptionID string) error {
+	return store.Conn(ctx, r.db).Model(&Subscription{}).
+		Where("id = ? AND failing_since IS NOT NULL", subscriptionID).
+		UpdateColumn("failing_since", nil).Error
+}
+
+func (r *gormRepository) RecordFailure(ctx context.Context, subscriptionID string, now, disableBefore time.Time, reason string) (bool, error) {
+	err := store.Conn(ctx, r.db).Model(&Subscription{}).
+		Where("id = ? AND failing_since IS NULL", subscriptionID).
+		UpdateColumn("failing_since", now).Error
+	if err != nil {
+		return false, err
+	}
+	result := store.Conn(ctx, r.db).Model(&Subscription{}).
+		Where("id = ? AND active AND failing_since <= ?", subscriptionID, disableBefore).
+		UpdateColumns(map[string]any{"active": false, "disabled_at": now, "disabled_reason": reason})
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,247 @@
+// Package webhook delivers the domain events of API_CONTRACT.md §9 to
+// subscribed URLs. Deliveries are queued from the event log, signed with the
+// subscription secret and retried with exponential backoff; subscriptions
+// failing for too long are disabled.
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"gorm.io/gorm"
+
+	"robohub-inventory/pkg/auth"
+	"robohub-inventory/pkg/event"
+	"robohub-inventory/pkg/query"
+)
+
+var (
+	ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+	ErrDeliveryNotFound     = errors.New("webhook delivery not found")
+	ErrInvalidSubscription  = errors.New("invalid webhook subscription data")
+)
+
+const (
+	secretPrefix    = "whsec_"
+	minSecretLength = 16
+)
+
+var tracer = otel.Tracer("robohub-inventory/pkg/webhook")
+
+// Options configure the delivery of events
+type Options struct {
+	MaxRetries   int           // Attempts after the first before a delivery fails
+	BackoffBase  time.Duration // Delay before the first retry, doubled for each further retry
+	BackoffMax   time.Duration // Upper bound of the retry delay
+	Timeout      time.Duration // Timeout of each attempt
+	DisableAfter time.Duration // Failure streak after which a subscription is disabled
+}
+
+// Service handles business logic for webhooks
+type Service struct {
+	repo Repository
+	opts Options
+	now  func() time.Time
+}
+
+func NewService(repo Repository, opts Options) *Service {
+	return &Service{repo: repo, opts: opts, now: time.Now}
+}
+
+// CreateSubscription stores a new subscription; only platform admins may create one.
+// Without a secret one is generated. The secret is returned and cannot be read afterwards.
+func (s *Service) CreateSubscription(ctx context.Context, sub *Subscription) (string, error) {
+	ctx, span := tracer.Start(ctx, "webhook.Service.CreateSubscription")
+	defer span.End()
+	if err := auth.RequireAdmin(ctx); err != nil {
+		return "", err
+	}
+	if err := validateSubscription(sub); err != nil {
+		return "", err
+	}
+	if sub.Secret == "" {
+		secret, err := newSecret()
+		if err != nil {
+			return "", err
+		}
+		sub.Secret = secret
+	}
+	principal, _ := auth.FromContext(ctx)
+	sub.CreatedBy, sub.Active = principal.Subject, true
+	sub.FailingSince, sub.DisabledAt, sub.DisabledReason = nil, nil, ""
+	if err := s.repo.Create(ctx, sub); err != nil {
+		return "", err
+	}
+	return sub.Secret, nil
+}
+
+// GetSubscription returns a subscription; only platform admins may read it
+func (s *Service) GetSubscription(ctx context.Context, id string) (*Subscription, error) {
+	ctx, span := tracer.Start(ctx, "webhook.Service.GetSubscription")
+	defer span.End()
+	if err := auth.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	sub, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, translateError(err, ErrSubscriptionNotFound)
+	}
+	return sub, nil
+}
+
+// ListSubscriptions returns every subscription, newest first; only platform admins may list them
+func (s *Service) ListSubscriptions(ctx context.Context) ([]*Subscription, error) {
+	ctx, span := tracer.Start(ctx, "webhook.Service.ListSubscriptions")
+	defer span.End()
+	if err := auth.RequireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	return s.repo.List(ctx)
+}
+
+// UpdateSubscription replaces the URL, description, event filter and active
+// flag of a subscription, and rotates its secret if one is given. Activating a
+// disabled subscription clears its failure streak; pending deliveries resume.
+func (s *Service) UpdateSubscription(ctx context.Context, sub *Subscription) error {
+	ctx, span := tracer.Start(ctx, "webhook.Service.UpdateSubscription")
+	defer span.End()
+	if err := auth.RequireAdmin(ctx); err != nil {
+		return err
+	}
+	if err := validateSubscription(sub); err != nil {
+		return err
+	}
+	current, err := s.repo.GetByID(ctx, sub.ID)
+	if err != nil {
+		return translateError(err, ErrSubscriptionNotFound)
+	}
+	if sub.Secret == "" {
+		sub.Secret = current.Secret
+	}
+	sub.FailingSince, sub.DisabledAt, sub.DisabledReason = current.FailingSince, current.DisabledAt, current.DisabledReason
+	if sub.Active && !current.Active {
+		sub.FailingSince, sub.DisabledAt, sub.DisabledReason = nil, nil, ""
+	}
+	return translateError(s.repo.Update(ctx, sub), ErrSubscriptionNotFound)
+}
+
+// DeleteSubscription removes a subscription and its delivery log; only platform admins may delete it
+func (s *Service) DeleteSubscription(ctx context.Context, id string) error {
+	ctx, span := tracer.Start(ctx, "webhook.Service.DeleteSubscription")
+	defer span.End()
+	if err := auth.RequireAdmin(ctx); err != nil {
+		return err
+	}
+	return translateError(s.repo.Delete(ctx, id), ErrSubscriptionNotFound)
+}
+
+// ListDeliveries returns one page of the delivery log of a subscription, newest first, together with the total count
+func (s *Service) ListDeliveries(ctx context.Context, subscriptionID string, page query.Page) ([]*Delivery, int64, error) {
+	ctx, span := tracer.Start(ctx, "webhook.Service.ListDeliveries")
+	defer span.End()
+	if _, err := s.GetSubscription(ctx, subscriptionID); err != nil {
+		return nil, 0, err
+	}
+	deliveries, err := s.repo.ListDeliveries(ctx, subscriptionID, page)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := s.repo.CountDeliveries(ctx, subscriptionID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return deliveries, total, nil
+}
+
+// Redeliver queues the event of a delivery again as a new delivery with a fresh retry budget
+func (s *Service) Redeliver(ctx context.Context, subscriptionID, deliveryID string) (*Delivery, error) {
+	ctx, span := tracer.Start(ctx, "webhook.Service.Redeliver")
+	defer span.End()
+	if _, err := s.GetSubscription(ctx, subscriptionID); err != nil {
+		return nil, err
+	}
+	original, err := s.repo.GetDelivery(ctx, subscriptionID, deliveryID)
+	if err != nil {
+		return nil, translateError(err, ErrDeliveryNotFound)
+	}
+	d := s.newDelivery(subscriptionID, original.Event)
+	d.RedeliveryOf = &original.ID
+	if err := s.repo.CreateDeliveries(ctx, []*Delivery{d}); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// HandleEvent is the event bus subscriber of webhooks, queuing a delivery of
+// the event to every active subscription selecting it. The deliveries commit
+// together with the progress of the subscriber, so each event is queued once.
+func (s *Service) HandleEvent(ctx context.Context, e event.Event) error {
+	ctx, span := tracer.Start(ctx, "webhook.Service.HandleEvent")
+	defer span.End()
+	subs, err := s.repo.ListActive(ctx)
+	if err != nil {
+		return err
+	}
+	var deliveries []*Delivery
+	for _, sub := range subs {
+		if sub.Selects(e.Type) {
+			deliveries = append(deliveries, s.newDelivery(sub.ID, e))
+		}
+	}
+	return s.repo.CreateDeliveries(ctx, deliveries)
+}
+
+func (s *Service) newDelivery(subscriptionID string, e event.Event) *Delivery {
+	return &Delivery{
+		SubscriptionID: subscriptionID,
+		EventID:        e.ID,
+		EventType:      e.Type,
+		Event:          e,
+		Status:         StatusPending,
+		MaxRetries:     s.opts.MaxRetries,
+		NextAttemptAt:  s.now(),
+	}
+}
+
+func validateSubscription(sub *Subscription) error {
+	u, err := url.Parse(sub.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidSubscription)
+	}
+	if sub.Secret != "" && len(sub.Secret) < minSecretLength {
+		return fmt.Errorf("%w: secret must have at least %d characters", ErrInvalidSubscription, minSecretLength)
+	}
+	for _, typ := range sub.Events {
+		if !event.ValidType(typ) {
+			return fmt.Errorf("%w: unknown event type %q (allowed: %s)", ErrInvalidSubscription, typ, strings.Join(event.Types, ", "))
+		}
+	}
+	if sub.Events == nil {
+		sub.Events = []string{}
+	}
+	return nil
+}
+
+// newSecret returns a random signing secret
+func newSecret() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return secretPrefix + hex.EncodeToString(buf), nil
+}
+
+// translateError maps a missing row onto notFound
+func translateError(err, notFound error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return notFound
+	}
+	return err
+}
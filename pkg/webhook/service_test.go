@@ -0,0 +1,45 @@
+package webhook
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateSubscription(t *testing.T) {
+	const secret = "whsec_0123456789abcdef"
+	tests := []struct {
+		name    string
+		sub     Subscription
+		wantErr bool
+	}{
+		{name: "https", sub: Subscription{URL: "https://example.com/hook", Secret: secret}},
+		{name: "http", sub: Subscription{URL: "http://example.com:8080/hook"}},
+		{name: "generated secret", sub: Subscription{URL: "https://example.com/hook", Secret: ""}},
+		{name: "minimum secret", sub: Subscription{URL: "https://example.com/hook", Secret: "0123456789abcdef"}},
+		{name: "ftp scheme", sub: Subscription{URL: "ftp://example.com/hook", Secret: secret}, wantErr: true},
+		{name: "file scheme", sub: Subscription{URL: "file:///etc/passwd", Secret: secret}, wantErr: true},
+		{name: "javascript scheme", sub: Subscription{URL: "javascript:alert(1)", Secret: secret}, wantErr: true},
+		{name: "relative", sub: Subscription{URL: "/hook", Secret: secret}, wantErr: true},
+		{name: "missing host", sub: Subscription{URL: "https:///hook", Secret: secret}, wantErr: true},
+		{name: "unparsable", sub: Subscription{URL: "https://exa mple.com/%zz", Secret: secret}, wantErr: true},
+		{name: "short secret", sub: Subscription{URL: "https://example.com/hook", Secret: "0123456789abcde"}, wantErr: true},
+		{name: "unknown event", sub: Subscription{URL: "https://example.com/hook", Events: []string{"package.exploded"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSubscription(&tt.sub)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidSubscription) {
+					t.Errorf("err = %v, want %v", err, ErrInvalidSubscription)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validateSubscription: %v", err)
+			}
+			if tt.sub.Events == nil {
+				t.Error("Events is nil, want an empty list")
+			}
+		})
+	}
+}